@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show changes made through the web server",
+	Long: `Show the audit trail of changes made through 'todo serve': who made
+each request, what it changed and whether it succeeded. Newest first.
+
+Example:
+  todo audit
+  todo audit --user alice --limit 100
+  todo audit --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		user, _ := cmd.Flags().GetString("user")
+		limit, _ := cmd.Flags().GetInt("limit")
+		format, _ := cmd.Flags().GetString("format")
+
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		entries, err := db.GetAuditEntries(user, limit)
+		if err != nil {
+			return fmt.Errorf("failed to read audit trail: %w", err)
+		}
+
+		switch format {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		case "text":
+		default:
+			return fmt.Errorf("unknown format %q (valid: text, json)", format)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No changes recorded.")
+			return nil
+		}
+		for _, e := range entries {
+			fmt.Printf("%s  %-12s %-6s %s -> %d\n", e.CreatedAt.Format("2006-01-02 15:04:05"), e.User, e.Method, e.Path, e.Status)
+			if e.Details != "" {
+				fmt.Printf("    %s\n", e.Details)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	auditCmd.Flags().String("user", "", "Only show changes by this user")
+	auditCmd.Flags().IntP("limit", "n", 50, "Number of entries to show (0 for all)")
+	auditCmd.Flags().String("format", "text", "Output format (text, json)")
+	rootCmd.AddCommand(auditCmd)
+}
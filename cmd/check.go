@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/diffscan"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [path]",
+	Short: "Check TODOs against project policy",
+	Long: `Check TODOs against the rules in the [policy] section of the project
+config and exit with an error if any are broken, for use in pre-commit
+hooks and CI.
+
+Rules about single TODOs only look at TODOs added or changed relative to a
+base: the ref given with --diff, the index with --staged, or otherwise the
+git_branch_filter branch when set. Without a base every TODO is checked.
+Age, due date and staleness rules read the database, so run 'todo scan'
+first for them to be current.
+
+Example policy in .todo/config.toml:
+  [policy]
+  require_assignee = ["FIXME"]
+  require_issue = ["*"]
+  no_increase = true
+  max_priority = { P0 = 0 }
+  max_age_days = { HACK = 90 }
+
+Example:
+  todo check
+  todo check --staged
+  todo check --diff origin/main --format sarif --output todo.sarif`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rules := policyRules(cfg.Policy)
+		enabled := rules.Enabled()
+		if len(enabled) == 0 {
+			fmt.Println("No policy rules configured. Add a [policy] section to .todo/config.toml.")
+			return nil
+		}
+
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		root, err := resolvePath(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+
+		p := newParser(cfg, cfg.ExcludePatterns)
+		p.SetRoot(root)
+		todos, err := p.ParseDir(root)
+		if err != nil {
+			return fmt.Errorf("failed to scan: %w", err)
+		}
+		defaults := scanOptions(cfg).Markers
+		for i := range todos {
+			todos[i].FilePath = relPath(root, todos[i].FilePath)
+			if todos[i].Priority == "" {
+				todos[i].Priority = defaults[todos[i].Type].Priority
+			}
+			if todos[i].Priority == "" {
+				todos[i].Priority = "P3"
+			}
+		}
+
+		in := policy.Input{TODOs: todos, New: todos, Now: time.Now()}
+
+		// Compare with a base so only new work is held to the rules
+		base, _ := cmd.Flags().GetString("diff")
+		staged, _ := cmd.Flags().GetBool("staged")
+		if base == "" && !staged {
+			base = cfg.GitBranchFilter
+		}
+		if base != "" || staged {
+			result, err := diffscan.Diff(root, p, diffscan.Options{Base: base, Staged: staged})
+			if err != nil {
+				return fmt.Errorf("failed to diff: %w", err)
+			}
+			in.New = append([]parser.ParsedTODO{}, result.Added...)
+			for _, c := range result.Changed {
+				in.New = append(in.New, c.New)
+			}
+			for i := range in.New {
+				in.New[i].FilePath = relPath(root, filepath.Join(result.Root, filepath.FromSlash(in.New[i].FilePath)))
+			}
+			in.Delta = len(result.Added) - len(result.Removed)
+			in.HasBase = true
+		} else if rules.NoIncrease {
+			fmt.Fprintln(os.Stderr, "Warning: no base to compare with, skipping no_increase (use --diff or set git_branch_filter)")
+		}
+
+		// Database rules only apply once the project has been scanned
+		if _, err := os.Stat(filepath.Join(root, ".todo", "todos.db")); err == nil {
+			db, err := database.New(root)
+			if err != nil {
+				return fmt.Errorf("failed to open database: %w", err)
+			}
+			if err := loadTracked(db, cfg, rules, &in); err != nil {
+				return err
+			}
+			for _, list := range [][]database.TODO{in.Tracked, in.Overdue, in.Stale} {
+				for i := range list {
+					list[i].FilePath = relPath(root, list[i].FilePath)
+				}
+			}
+		}
+
+		violations := policy.Check(rules, in)
+
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		switch format {
+		case "sarif":
+			err = policy.WriteSARIF(w, enabled, violations, rootCmd.Version)
+		case "junit":
+			err = policy.WriteJUnit(w, enabled, violations)
+		case "text", "":
+			for _, v := range violations {
+				fmt.Fprintf(w, "%s [%s]\n", v, v.Rule)
+			}
+		default:
+			return fmt.Errorf("unsupported format: %s", format)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+
+		if len(violations) > 0 {
+			return fmt.Errorf("policy check failed: %d violation(s)", len(violations))
+		}
+		if format == "text" || output != "" {
+			fmt.Printf("Policy check passed (%s)\n", strings.Join(enabled, ", "))
+		}
+		return nil
+	},
+}
+
+// policyRules converts the configured policy for the checker
+func policyRules(c config.PolicyConfig) policy.Rules {
+	return policy.Rules{
+		RequireAssignee: c.RequireAssignee,
+		RequireIssue:    c.RequireIssue,
+		MaxPriority:     c.MaxPriority,
+		MaxAgeDays:      c.MaxAgeDays,
+		NoIncrease:      c.NoIncrease,
+		NoOverdue:       c.NoOverdue,
+		NoStale:         c.NoStale,
+	}
+}
+
+// loadTracked fills in the database-backed parts of the check input
+func loadTracked(db *database.DB, cfg *config.Config, rules policy.Rules, in *policy.Input) error {
+	todos, err := db.GetTODOs(nil)
+	if err != nil {
+		return fmt.Errorf("failed to get TODOs: %w", err)
+	}
+	// Non-nil even when empty, so priorities are counted from the database
+	in.Tracked = append([]database.TODO{}, openTODOs(todos)...)
+
+	if rules.NoOverdue {
+		overdue, err := db.GetOverdueTODOs()
+		if err != nil {
+			return fmt.Errorf("failed to get overdue TODOs: %w", err)
+		}
+		in.Overdue = openTODOs(overdue)
+	}
+	if rules.NoStale {
+		days := cfg.Stale.DaysSinceUpdate
+		if days <= 0 {
+			days = 30
+		}
+		stale, err := db.GetStaleTODOs(days)
+		if err != nil {
+			return fmt.Errorf("failed to get stale TODOs: %w", err)
+		}
+		in.Stale = openTODOs(stale)
+	}
+	return nil
+}
+
+// openTODOs drops TODOs whose comment is gone or whose work is finished
+func openTODOs(todos []database.TODO) []database.TODO {
+	var open []database.TODO
+	for _, t := range todos {
+		if t.RemovedAt == nil && !database.IsDone(t.Status) {
+			open = append(open, t)
+		}
+	}
+	return open
+}
+
+// relPath returns path relative to root with forward slashes, as CI
+// reports expect; paths outside root are returned unchanged
+func relPath(root, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func init() {
+	checkCmd.Flags().String("diff", "", "Only hold TODOs changed since the merge base with this ref to the rules")
+	checkCmd.Flags().Bool("staged", false, "Only hold TODOs in staged changes to the rules")
+	checkCmd.Flags().String("format", "text", "Output format (text, sarif, junit)")
+	checkCmd.Flags().StringP("output", "o", "", "Write the report to a file instead of stdout")
+	checkCmd.MarkFlagsMutuallyExclusive("diff", "staged")
+	rootCmd.AddCommand(checkCmd)
+}
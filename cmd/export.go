@@ -22,6 +22,8 @@ Examples:
   todo export --format csv      # Export as CSV
   todo export --format markdown # Export as Markdown table
   todo export --format github   # Export as GitHub Issues JSON
+  todo export --format sarif    # Export as SARIF 2.1.0 for code scanning
+  todo export --format codeclimate # Export as Code Climate JSON for GitLab
   todo export --status open     # Export only open TODOs`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get project path
@@ -63,6 +65,10 @@ Examples:
 			return exportGitHub(todos, db)
 		case "jira":
 			return exportJira(todos)
+		case "sarif":
+			return exportSARIF(todos, projectPath)
+		case "codeclimate":
+			return exportCodeClimate(todos, projectPath)
 		default:
 			return exportJSON(todos)
 		}
@@ -246,7 +252,7 @@ func exportJira(todos []database.TODO) error {
 }
 
 func init() {
-	exportCmd.Flags().StringP("format", "f", "json", "Export format (json, csv, markdown, github, jira)")
+	exportCmd.Flags().StringP("format", "f", "json", "Export format (json, csv, markdown, github, jira, sarif, codeclimate)")
 	exportCmd.Flags().StringP("status", "s", "", "Filter by status")
 	rootCmd.AddCommand(exportCmd)
 }
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/sarif"
+)
+
+// sarifLevel maps a TODO to a SARIF result level, following its Code
+// Climate severity
+func sarifLevel(t database.TODO) string {
+	switch codeClimateSeverity(t) {
+	case "blocker", "critical":
+		return "error"
+	case "major":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// codeClimateSeverity maps a TODO to a Code Climate severity. An explicit
+// severity wins, otherwise it comes from the priority; NOTEs are always
+// informational.
+func codeClimateSeverity(t database.TODO) string {
+	switch strings.ToLower(t.Severity) {
+	case "critical":
+		return "blocker"
+	case "high":
+		return "critical"
+	case "medium":
+		return "major"
+	case "low":
+		return "minor"
+	case "info":
+		return "info"
+	}
+	if strings.EqualFold(t.Type, "NOTE") {
+		return "info"
+	}
+	switch strings.ToUpper(t.Priority) {
+	case "P0":
+		return "blocker"
+	case "P1":
+		return "critical"
+	case "P2":
+		return "major"
+	case "P4":
+		return "info"
+	default:
+		return "minor"
+	}
+}
+
+// codeClimateCategories are the categories Code Climate accepts
+var codeClimateCategories = []string{
+	"Bug Risk", "Clarity", "Compatibility", "Complexity",
+	"Duplication", "Performance", "Security", "Style",
+}
+
+// codeClimateCategory uses the TODO's category when Code Climate knows it,
+// otherwise one that fits the marker
+func codeClimateCategory(t database.TODO) string {
+	for _, c := range codeClimateCategories {
+		if strings.EqualFold(c, t.Category) {
+			return c
+		}
+	}
+	switch strings.ToUpper(t.Type) {
+	case "BUG", "FIXME":
+		return "Bug Risk"
+	case "HACK", "XXX":
+		return "Complexity"
+	default:
+		return "Clarity"
+	}
+}
+
+// CodeClimateIssue is an issue in the Code Climate report format, as read
+// by GitLab code quality widgets
+type CodeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Severity    string              `json:"severity"`
+	Fingerprint string              `json:"fingerprint"`
+	Location    CodeClimateLocation `json:"location"`
+}
+
+// CodeClimateLocation is where an issue is in the code
+type CodeClimateLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// exportCodeClimate writes a Code Climate report. Reports show TODOs where
+// they are in the code, so TODOs whose comment was removed are left out.
+func exportCodeClimate(todos []database.TODO, projectPath string) error {
+	issues := make([]CodeClimateIssue, 0, len(todos))
+	for _, t := range todos {
+		if t.RemovedAt != nil {
+			continue
+		}
+		issue := CodeClimateIssue{
+			Type:        "issue",
+			CheckName:   t.Type,
+			Description: fmt.Sprintf("%s: %s", t.Type, t.Content),
+			Categories:  []string{codeClimateCategory(t)},
+			Severity:    codeClimateSeverity(t),
+			Fingerprint: t.ID,
+		}
+		issue.Location.Path = relPath(projectPath, t.FilePath)
+		issue.Location.Lines.Begin = t.LineNumber
+		issues = append(issues, issue)
+	}
+
+	output, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+// exportSARIF writes a SARIF 2.1.0 log, leaving out removed TODOs like
+// exportCodeClimate
+func exportSARIF(todos []database.TODO, projectPath string) error {
+	driver := sarif.Driver{
+		Name:           "todo",
+		Version:        rootCmd.Version,
+		InformationURI: "https://github.com/duncan-2126/ProjectManagement",
+	}
+
+	var results []sarif.Result
+	types := make(map[string]bool)
+	for _, t := range todos {
+		if t.RemovedAt != nil {
+			continue
+		}
+		types[t.Type] = true
+
+		properties := map[string]interface{}{
+			"status":   t.Status,
+			"priority": t.Priority,
+		}
+		if t.Assignee != "" {
+			properties["assignee"] = t.Assignee
+		}
+		if t.Category != "" {
+			properties["category"] = t.Category
+		}
+		if t.DueDate != nil {
+			properties["dueDate"] = t.DueDate.Format("2006-01-02")
+		}
+		if len(t.IssueKeys) > 0 {
+			properties["issueKeys"] = t.IssueKeys
+		}
+
+		results = append(results, sarif.Result{
+			RuleID:              t.Type,
+			Level:               sarifLevel(t),
+			Message:             sarif.Message{Text: t.Content},
+			Locations:           []sarif.Location{sarif.FileLocation(relPath(projectPath, t.FilePath), t.LineNumber, t.Column)},
+			PartialFingerprints: map[string]string{"todoId/v1": t.ID},
+			Properties:          properties,
+		})
+	}
+
+	// One rule per marker found
+	var names []string
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		driver.Rules = append(driver.Rules, sarif.Rule{
+			ID:               name,
+			Name:             name,
+			ShortDescription: &sarif.Message{Text: fmt.Sprintf("%s comment", name)},
+		})
+	}
+
+	return sarif.NewLog(driver, results).Write(os.Stdout)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportSeverity(t *testing.T) {
+	tests := []struct {
+		todo        database.TODO
+		codeClimate string
+		sarif       string
+	}{
+		{database.TODO{Type: "TODO", Priority: "P0"}, "blocker", "error"},
+		{database.TODO{Type: "FIXME", Priority: "P2"}, "major", "warning"},
+		{database.TODO{Type: "TODO", Priority: "P3"}, "minor", "note"},
+		{database.TODO{Type: "NOTE", Priority: "P0"}, "info", "note"},
+		{database.TODO{Type: "TODO", Priority: "P4", Severity: "high"}, "critical", "error"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.codeClimate, codeClimateSeverity(tt.todo), "%+v", tt.todo)
+		assert.Equal(t, tt.sarif, sarifLevel(tt.todo), "%+v", tt.todo)
+	}
+
+	assert.Equal(t, "Bug Risk", codeClimateCategory(database.TODO{Type: "FIXME"}))
+	assert.Equal(t, "Performance", codeClimateCategory(database.TODO{Type: "TODO", Category: "performance"}))
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [id]",
+	Short: "Reconstruct TODO lifecycles from git history",
+	Long: `Walk the repository's history to find the commit that introduced each
+tracked TODO, every commit that changed it and the commit that removed it.
+The events are stored so TODO ages and cycle times reflect git history
+rather than the first scan. Run 'todo scan' first so TODOs are tracked.
+
+With an ID, show the recorded history of that TODO.
+
+Example:
+  todo history
+  todo history --max-commits 500
+  todo history abc123`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get project path
+		projectPath, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		// Open database
+		db, err := database.New(projectPath)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+
+		if len(args) == 1 {
+			return showHistory(db, args[0])
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		p := newParser(cfg, cfg.ExcludePatterns)
+		p.SetRoot(projectPath)
+
+		maxCommits, _ := cmd.Flags().GetInt("max-commits")
+
+		fmt.Println("Walking git history...")
+		result, err := history.Walk(projectPath, p, history.Options{MaxCommits: maxCommits})
+		if err != nil {
+			return fmt.Errorf("failed to walk history: %w", err)
+		}
+
+		summary, err := history.Record(db, result)
+		if err != nil {
+			return fmt.Errorf("failed to save history: %w", err)
+		}
+
+		fmt.Printf("History complete!\n")
+		fmt.Printf("  Commits: %d\n", result.Commits)
+		fmt.Printf("  TODOs in history: %d\n", len(result.Lifecycles))
+		fmt.Printf("  Tracked TODOs matched: %d\n", summary.Matched)
+		fmt.Printf("  Introduction found: %d\n", summary.Introduced)
+		fmt.Printf("  Removal found: %d\n", summary.Removed)
+		return nil
+	},
+}
+
+// showHistory prints the recorded events of one TODO
+func showHistory(db *database.DB, id string) error {
+	todo, err := db.GetTODOByID(id)
+	if err != nil {
+		return fmt.Errorf("TODO not found: %s", id)
+	}
+
+	events, err := db.GetTODOEvents(todo.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+	if len(events) == 0 {
+		fmt.Println("No history recorded. Run 'todo history' to walk git history.")
+		return nil
+	}
+
+	fmt.Printf("=== History of %s ===\n\n", todo.ID[:8])
+	for _, e := range events {
+		fmt.Printf("%s  %-10s %s  %s <%s>\n", e.CommittedAt.Format("2006-01-02 15:04"), e.Type, e.Commit[:8], e.Author, e.Email)
+		fmt.Printf("    %s:%d  %s\n", e.FilePath, e.LineNumber, e.Content)
+	}
+
+	first, last := events[0], events[len(events)-1]
+	if first.Type == database.EventIntroduced {
+		fmt.Println()
+		if last.Type == database.EventRemoved {
+			fmt.Printf("Cycle time: %s\n", formatAge(last.CommittedAt.Sub(first.CommittedAt)))
+		} else {
+			fmt.Printf("Age: %s\n", formatAge(time.Since(first.CommittedAt)))
+		}
+	}
+	return nil
+}
+
+// formatAge renders a duration in days, or hours when under a day
+func formatAge(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days", int(d.Hours()/24))
+}
+
+func init() {
+	historyCmd.Flags().Int("max-commits", 0, "Limit how many commits are walked (0 walks all)")
+	rootCmd.AddCommand(historyCmd)
+}
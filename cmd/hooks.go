@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/duncan-2126/ProjectManagement/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks that keep TODOs up to date",
+	Long: `Install git hooks that rescan the files changed by commits and merges,
+so line numbers and authors stay current, and optionally block commits that
+break the project policy (see 'todo check').
+
+Existing hooks are kept and run before ours; uninstalling restores them.
+
+Example:
+  todo hooks install
+  todo hooks install --block
+  todo hooks status
+  todo hooks uninstall`,
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install pre-commit, post-commit and post-merge hooks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		block, _ := cmd.Flags().GetBool("block")
+		return installHooks(block)
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the hooks and restore any hooks they replaced",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectPath, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		states, err := git.UninstallHooks(projectPath)
+		if err != nil {
+			return fmt.Errorf("failed to uninstall hooks: %w", err)
+		}
+		for _, s := range states {
+			switch {
+			case s.Foreign:
+				fmt.Printf("  %s: left another tool's hook in place\n", s.Name)
+			case s.Chained:
+				fmt.Printf("  %s: removed, previous hook restored\n", s.Name)
+			case s.Installed:
+				fmt.Printf("  %s: removed\n", s.Name)
+			default:
+				fmt.Printf("  %s: not installed\n", s.Name)
+			}
+		}
+		fmt.Println("✓ Hooks uninstalled")
+		return nil
+	},
+}
+
+var hooksStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which hooks are installed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectPath, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		states, err := git.HookStatus(projectPath)
+		if err != nil {
+			return fmt.Errorf("failed to read hooks: %w", err)
+		}
+		for _, s := range states {
+			status := "not installed"
+			switch {
+			case s.Installed && s.Chained:
+				status = "installed (runs existing hook first)"
+			case s.Installed:
+				status = "installed"
+			case s.Foreign:
+				status = "other hook in place"
+			}
+			fmt.Printf("  %-12s %s\n", s.Name, status)
+		}
+		return nil
+	},
+}
+
+// installHooks installs the hooks into the repository in the current directory
+func installHooks(block bool) error {
+	projectPath, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Prefer the running binary so hooks work when todo is not on PATH
+	binary, err := os.Executable()
+	if err == nil {
+		binary, _ = filepath.EvalSymlinks(binary)
+	}
+
+	states, err := git.InstallHooks(projectPath, git.HookOptions{Binary: binary, Block: block})
+	if err != nil {
+		return fmt.Errorf("failed to install hooks: %w", err)
+	}
+	for _, s := range states {
+		if s.Chained {
+			fmt.Printf("  %s: installed, runs existing hook first\n", s.Name)
+		} else {
+			fmt.Printf("  %s: installed\n", s.Name)
+		}
+	}
+	fmt.Println("✓ Hooks installed")
+	if block {
+		fmt.Println("  Commits breaking the policy are blocked (skip with git commit --no-verify)")
+	}
+	return nil
+}
+
+func init() {
+	hooksInstallCmd.Flags().Bool("block", false, "Block commits that break the policy in .todo/config.toml")
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	hooksCmd.AddCommand(hooksStatusCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
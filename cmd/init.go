@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/duncan-2126/ProjectManagement/internal/config"
 	"github.com/duncan-2126/ProjectManagement/internal/database"
 	"github.com/spf13/cobra"
 )
@@ -18,7 +21,8 @@ Creates a .todo directory with configuration and database.
 
 Example:
   todo init
-  todo init my-project`,
+  todo init my-project
+  todo init --hooks`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get project path
@@ -58,18 +62,31 @@ Example:
 # Project: %s
 # Created: %s
 
-[todo_types]
-  default = ["TODO", "FIXME", "HACK", "BUG", "NOTE", "XXX"]
+todo_types = ["TODO", "FIXME", "HACK", "BUG", "NOTE", "XXX"]
+# exclude replaces the default list below rather than adding to it
+# exclude = [%s]
+git_author = true
+color = "auto"
 
-[exclude]
-  default = [".git", "node_modules", "vendor", "dist", "build"]
+# Rules enforced by 'todo check'
+[policy]
+# require_assignee = ["FIXME"]
+# require_issue = ["*"]
+# no_increase = true
+# no_overdue = true
 
-[git]
-  author = true
+# [policy.max_priority]
+# P0 = 0
 
-[display]
-  color = "auto"
-`, projectName, time.Now().Format("2006-01-02"))
+# [policy.max_age_days]
+# HACK = 90
+
+# Settings for 'todo serve'
+# [server]
+# cors_origins = ["http://localhost:5173"]
+# anonymous_role = "viewer"
+# session_hours = 24
+`, projectName, time.Now().Format("2006-01-02"), quoteList(config.DefaultConfig().ExcludePatterns))
 
 		if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 			return fmt.Errorf("failed to create config: %w", err)
@@ -88,6 +105,16 @@ Example:
 		}
 
 		fmt.Printf("✓ Initialized TODO Tracker project: %s\n", projectName)
+		if project, err := registerProject("", projectPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: not registered: %v\n", err)
+		} else {
+			fmt.Printf("  Registered as: %s\n", project.Name)
+		}
+		if hooks, _ := cmd.Flags().GetBool("hooks"); hooks {
+			if err := installHooks(false); err != nil {
+				return err
+			}
+		}
 		fmt.Printf("  Database: %s\n", filepath.Join(todoDir, "todos.db"))
 		fmt.Printf("  Config: %s\n", configPath)
 		fmt.Println("\nNext steps:")
@@ -107,6 +134,16 @@ func contains(s, substr string) bool {
 	return false
 }
 
+// quoteList formats values as the items of a TOML array
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
 func init() {
+	initCmd.Flags().Bool("hooks", false, "Install git hooks that rescan on commit and merge")
 	rootCmd.AddCommand(initCmd)
 }
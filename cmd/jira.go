@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/duncan-2126/ProjectManagement/internal/config"
 	"github.com/duncan-2126/ProjectManagement/internal/database"
 	"github.com/spf13/cobra"
 )
@@ -48,11 +49,15 @@ Examples:
 		// Get output file
 		outputFile, _ := cmd.Flags().GetString("output")
 
-		return exportTODOsToJira(todos, outputFile)
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		return exportTODOsToJira(todos, cfg.Jira, outputFile)
 	},
 }
 
-func exportTODOsToJira(todos []database.TODO, outputFile string) error {
+func exportTODOsToJira(todos []database.TODO, cfg config.JiraConfig, outputFile string) error {
 	var writer *csv.Writer
 	if outputFile != "" {
 		file, err := os.Create(outputFile)
@@ -75,9 +80,9 @@ func exportTODOsToJira(todos []database.TODO, outputFile string) error {
 	// Write rows
 	for _, t := range todos {
 		// Map priority to Jira format
-		priority := t.Priority
+		priority := cfg.Priority(t.Priority)
 		if priority == "" {
-			priority = "Medium"
+			priority = cfg.Priority("P2")
 		}
 
 		// Format due date
@@ -87,10 +92,10 @@ func exportTODOsToJira(todos []database.TODO, outputFile string) error {
 		}
 
 		// Map status to Jira workflow
-		status := mapStatusToJira(t.Status)
+		status := jiraStatus(cfg, t.Status)
 
 		// Map type to Jira issue type
-		issueType := mapTypeToJira(t.Type)
+		issueType := cfg.IssueType(t.Type)
 
 		// Truncate summary (Jira limit is 255)
 		summary := t.Content
@@ -123,42 +128,16 @@ func exportTODOsToJira(todos []database.TODO, outputFile string) error {
 	return nil
 }
 
-func mapStatusToJira(status string) string {
-	switch status {
-	case "open":
-		return "Open"
-	case "in_progress":
-		return "In Progress"
-	case "blocked":
-		return "Blocked"
-	case "resolved":
-		return "Resolved"
-	case "closed":
-		return "Closed"
-	case "wontfix":
-		return "Won't Fix"
-	default:
-		return "Open"
+// jiraStatus returns the Jira status a TODO status is exported as. Statuses
+// without a mapping become that of open or resolved TODOs.
+func jiraStatus(cfg config.JiraConfig, status string) string {
+	if s := cfg.Transition(status); s != "" {
+		return s
 	}
-}
-
-func mapTypeToJira(todoType string) string {
-	switch todoType {
-	case "BUG":
-		return "Bug"
-	case "FIXME":
-		return "Bug"
-	case "TODO":
-		return "Task"
-	case "HACK":
-		return "Technical Task"
-	case "NOTE":
-		return "Task"
-	case "XXX":
-		return "Task"
-	default:
-		return "Task"
+	if database.IsDone(status) {
+		return cfg.Transition("resolved")
 	}
+	return cfg.Transition("open")
 }
 
 func init() {
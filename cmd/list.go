@@ -8,6 +8,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/projects"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -23,19 +24,10 @@ Examples:
   todo list --type FIXME       # List only FIXMEs
   todo list @my-filter         # Use saved filter
   todo list --stale            # List stale TODOs
-  todo list --format json      # Output as JSON`,
+  todo list --format json      # Output as JSON
+  todo list --all-projects -p P0 --assignee me   # Across registered projects`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get project path
-		projectPath, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
-
-		// Open database
-		db, err := database.New(projectPath)
-		if err != nil {
-			return fmt.Errorf("failed to open database: %w", err)
-		}
+		allProjects, _ := cmd.Flags().GetBool("all-projects")
 
 		// Build filters
 		filters := make(map[string]interface{})
@@ -54,6 +46,13 @@ Examples:
 
 		// If filter name provided, load the saved filter
 		if filterName != "" {
+			if allProjects {
+				return fmt.Errorf("saved filters belong to one project and cannot be used with --all-projects")
+			}
+			db, err := openProjectDB()
+			if err != nil {
+				return err
+			}
 			filter, err := db.GetSavedFilter(filterName)
 			if err != nil {
 				return fmt.Errorf("filter not found: %s", filterName)
@@ -95,21 +94,56 @@ Examples:
 		}
 
 		// Get TODOs
-		var todos []database.TODO
-		if staleFlag {
-			// Get stale days from config or use default
-			staleDays := viper.GetInt("stale.days_since_update")
-			if staleDays == 0 {
-				staleDays = 14
+		fetch := func(db *database.DB) ([]database.TODO, error) {
+			if staleFlag {
+				// Get stale days from config or use default
+				staleDays := viper.GetInt("stale.days_since_update")
+				if staleDays == 0 {
+					staleDays = 14
+				}
+				todos, err := db.GetStaleTODOs(staleDays)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get stale TODOs: %w", err)
+				}
+				return todos, nil
+			}
+			todos, err := db.GetTODOs(filters)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get TODOs: %w", err)
 			}
-			todos, err = db.GetStaleTODOs(staleDays)
+			return todos, nil
+		}
+
+		// names holds the project of each TODO when listing several
+		var todos []database.TODO
+		var names []string
+		if allProjects {
+			registry, err := projects.LoadDefault()
 			if err != nil {
-				return fmt.Errorf("failed to get stale TODOs: %w", err)
+				return err
+			}
+			for _, p := range registry.Projects {
+				db, err := p.Open()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: skipping %v\n", err)
+					continue
+				}
+				found, err := fetch(db)
+				if err != nil {
+					return fmt.Errorf("%s: %w", p.Name, err)
+				}
+				todos = append(todos, found...)
+				for range found {
+					names = append(names, p.Name)
+				}
 			}
 		} else {
-			todos, err = db.GetTODOs(filters)
+			db, err := openProjectDB()
 			if err != nil {
-				return fmt.Errorf("failed to get TODOs: %w", err)
+				return err
+			}
+			if todos, err = fetch(db); err != nil {
+				return err
 			}
 		}
 
@@ -118,16 +152,29 @@ Examples:
 
 		switch format {
 		case "json":
-			jsonBytes, err := json.MarshalIndent(todos, "", "  ")
+			var out interface{} = todos
+			if allProjects {
+				withProject := make([]projectTODO, len(todos))
+				for i, t := range todos {
+					withProject[i] = projectTODO{Project: names[i], TODO: t}
+				}
+				out = withProject
+			}
+			jsonBytes, err := json.MarshalIndent(out, "", "  ")
 			if err != nil {
 				return fmt.Errorf("failed to marshal JSON: %w", err)
 			}
 			fmt.Println(string(jsonBytes))
 
 		case "csv":
+			prefix := func(int) string { return "" }
+			if allProjects {
+				fmt.Print("Project,")
+				prefix = func(i int) string { return names[i] + "," }
+			}
 			fmt.Println("ID,FilePath,LineNumber,Type,Content,Status,Priority,Author,Assignee")
-			for _, t := range todos {
-				fmt.Printf("%s,%s,%d,%s,\"%s\",%s,%s,%s,%s\n",
+			for i, t := range todos {
+				fmt.Printf("%s%s,%s,%d,%s,\"%s\",%s,%s,%s,%s\n", prefix(i),
 					t.ID, t.FilePath, t.LineNumber, t.Type, t.Content, t.Status, t.Priority, t.Author, t.Assignee)
 			}
 
@@ -138,10 +185,16 @@ Examples:
 			}
 
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if allProjects {
+				fmt.Fprint(w, "Project\t")
+			}
 			fmt.Fprintln(w, "ID\tFile\tLine\tType\tStatus\tPriority\tAssignee\tContent")
+			if allProjects {
+				fmt.Fprint(w, "-------\t")
+			}
 			fmt.Fprintln(w, "---\t----\t----\t----\t------\t--------\t---------\t-------")
 
-			for _, t := range todos {
+			for i, t := range todos {
 				// Truncate content if too long
 				content := t.Content
 				if len(content) > 40 {
@@ -151,6 +204,9 @@ Examples:
 				if assigneeStr == "" {
 					assigneeStr = "-"
 				}
+				if allProjects {
+					fmt.Fprintf(w, "%s\t", names[i])
+				}
 				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
 					t.ID[:8], t.FilePath, t.LineNumber, t.Type, t.Status, t.Priority, assigneeStr, content)
 			}
@@ -163,6 +219,12 @@ Examples:
 	},
 }
 
+// projectTODO is a TODO listed with the project it belongs to
+type projectTODO struct {
+	Project string `json:"project"`
+	database.TODO
+}
+
 func init() {
 	listCmd.Flags().StringP("status", "s", "", "Filter by status (open, in_progress, resolved, wontfix)")
 	listCmd.Flags().StringP("type", "t", "", "Filter by type (TODO, FIXME, HACK, BUG, NOTE, XXX)")
@@ -172,6 +234,7 @@ func init() {
 	listCmd.Flags().StringP("assignee", "", "", "Filter by assignee")
 	listCmd.Flags().StringP("format", "o", "table", "Output format (table, json, csv)")
 	listCmd.Flags().BoolP("stale", "", false, "Show stale TODOs (no update in configured days)")
+	listCmd.Flags().Bool("all-projects", false, "List TODOs of every registered project")
 
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/duncan-2126/ProjectManagement/internal/projects"
+	"github.com/spf13/cobra"
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage the registry of projects",
+	Long: `Register projects so that other commands can reach them with
+--project <name> from any directory, 'todo serve' hosts them all and
+'todo list --all-projects' queries them together. 'todo init' registers new
+projects automatically.
+
+Example:
+  todo project add ~/src/api --name api
+  todo project list
+  todo --project api list --priority P0
+  todo project remove api`,
+}
+
+var projectAddCmd = &cobra.Command{
+	Use:   "add [path]",
+	Short: "Register a project (default: the current directory)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		name, _ := cmd.Flags().GetString("name")
+
+		project, err := registerProject(name, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Registered %s at %s\n", project.Name, project.Path)
+		return nil
+	},
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered projects",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registry, err := projects.LoadDefault()
+		if err != nil {
+			return err
+		}
+		if len(registry.Projects) == 0 {
+			fmt.Println("No projects registered. Add one with 'todo project add'.")
+			return nil
+		}
+		for _, p := range registry.Projects {
+			state := ""
+			if _, err := os.Stat(filepath.Join(p.Path, ".todo")); err != nil {
+				state = "  (missing)"
+			}
+			fmt.Printf("  %-20s %s%s\n", p.Name, p.Path, state)
+		}
+		return nil
+	},
+}
+
+var projectRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Unregister a project, leaving its TODOs in place",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registry, err := projects.LoadDefault()
+		if err != nil {
+			return err
+		}
+		if err := registry.Remove(args[0]); err != nil {
+			return err
+		}
+		if err := registry.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Unregistered %s\n", args[0])
+		return nil
+	},
+}
+
+// registerProject adds the project at path to the registry. Without a name
+// the one given at 'todo init' is used, or else the directory name.
+func registerProject(name, path string) (*projects.Project, error) {
+	if _, err := os.Stat(filepath.Join(path, ".todo")); err != nil {
+		return nil, fmt.Errorf("%s is not a TODO Tracker project; run 'todo init' there first", path)
+	}
+	if name == "" {
+		name = filepath.Base(path)
+		p := projects.Project{Name: name, Path: path}
+		if db, err := p.Open(); err == nil {
+			if row, err := db.GetProjectByPath(path); err == nil {
+				name = row.Name
+			}
+		}
+	}
+
+	registry, err := projects.LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	project, err := registry.Add(name, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := registry.Save(); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// useProject makes a registered project, given by name or path, the
+// current directory so every command works on it
+func useProject(nameOrPath string) error {
+	registry, err := projects.LoadDefault()
+	if err != nil {
+		return err
+	}
+	project, err := registry.Find(nameOrPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(project.Path); err != nil {
+		return fmt.Errorf("failed to open project %s: %w", project.Name, err)
+	}
+	return nil
+}
+
+func init() {
+	projectAddCmd.Flags().String("name", "", "Name of the project (default: its name from 'todo init')")
+	projectCmd.AddCommand(projectAddCmd)
+	projectCmd.AddCommand(projectListCmd)
+	projectCmd.AddCommand(projectRemoveCmd)
+	rootCmd.AddCommand(projectCmd)
+}
@@ -26,4 +26,14 @@ Usage:
 
 For more information, visit: https://github.com/duncan-2126/ProjectManagement`,
 	Version: "1.0.0",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if project, _ := cmd.Flags().GetString("project"); project != "" {
+			return useProject(project)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("project", "", "Work on this registered project (name or path) instead of the current directory")
 }
@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/duncan-2126/ProjectManagement/internal/config"
 	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/diffscan"
+	"github.com/duncan-2126/ProjectManagement/internal/git"
 	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/scanner"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -18,13 +26,30 @@ var scanCmd = &cobra.Command{
 	Long: `Scans the specified directory (or current directory) for TODO comments
 and stores them in the database for tracking.
 
+With --diff, --branch or --staged, only the TODOs added, changed or removed
+relative to a base commit are reported and the database is left untouched:
+--diff compares the working tree with its merge base with a ref, --branch
+does the same for the git_branch_filter setting, and --staged compares the
+index with HEAD. Use --format json for machine-readable output.
+
+With --files-from, only the listed files are re-parsed, and TODOs tracked
+in listed files that no longer exist are resolved. The list is read from a
+file, or from standard input with "-", one path per line or NUL-separated
+as printed by 'git diff --name-only -z'.
+
 Example:
   todo scan
   todo scan ./src
-  todo scan --exclude node_modules --exclude vendor`,
+  todo scan --exclude node_modules --exclude vendor
+  todo scan --diff origin/main --format json
+  todo scan --staged
+  git diff --name-only -z | todo scan --files-from -`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg := config.Load()
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
 
 		// Get path to scan
 		path := "."
@@ -38,12 +63,6 @@ Example:
 			return fmt.Errorf("failed to resolve path: %w", err)
 		}
 
-		// Open database
-		db, err := database.New(absPath)
-		if err != nil {
-			return fmt.Errorf("failed to open database: %w", err)
-		}
-
 		// Get exclude patterns from flags or config
 		exclude := viper.GetStringSlice("exclude")
 		if len(exclude) == 0 {
@@ -51,62 +70,262 @@ Example:
 		}
 
 		// Create parser
-		p := parser.New(nil, exclude, nil)
+		p := newParser(cfg, exclude)
 
-		fmt.Printf("Scanning %s...\n", absPath)
+		// Diff-scoped scans only report what changed
+		base, _ := cmd.Flags().GetString("diff")
+		branch, _ := cmd.Flags().GetBool("branch")
+		staged, _ := cmd.Flags().GetBool("staged")
+		format, _ := cmd.Flags().GetString("format")
+		if branch {
+			if cfg.GitBranchFilter == "" {
+				return fmt.Errorf("base branch not configured. Run: todo config set git_branch_filter <branch>")
+			}
+			base = cfg.GitBranchFilter
+		}
+		if base != "" || staged {
+			p.SetRoot(absPath)
+			result, err := diffscan.Diff(absPath, p, diffscan.Options{Base: base, Staged: staged})
+			if err != nil {
+				return fmt.Errorf("failed to diff: %w", err)
+			}
+			return printDiff(result, format)
+		}
 
-		// Parse directory
-		todos, err := p.ParseDir(absPath)
+		// Open database
+		db, err := database.New(absPath)
 		if err != nil {
-			return fmt.Errorf("failed to scan: %w", err)
+			return fmt.Errorf("failed to open database: %w", err)
 		}
 
-		// Save TODOs to database
-		newCount := 0
-		existingCount := 0
+		// Only re-parse files that changed since the last scan
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		var cache *parser.Cache
+		if !noCache {
+			cache = parser.LoadCache(scanCachePath(absPath), time.Duration(cfg.CacheTTL)*time.Minute)
+			p.SetCache(cache)
+		}
 
-		for _, t := range todos {
-			// Check if already exists
-			exists, err := db.TODOExists(t.Hash, t.FilePath, t.LineNumber)
-			if err != nil {
-				continue
+		var todos []parser.ParsedTODO
+		var failed, scope []string
+		if filesFrom, _ := cmd.Flags().GetString("files-from"); filesFrom != "" {
+			if scope, err = readPathList(filesFrom); err != nil {
+				return err
 			}
-
-			if exists {
-				existingCount++
-				continue
+			if len(scope) == 0 {
+				fmt.Println("No files to scan")
+				return nil
 			}
-
-			// Create new TODO
-			todo := database.TODO{
-				FilePath:   t.FilePath,
-				LineNumber: t.LineNumber,
-				Column:     t.Column,
-				Type:       t.Type,
-				Content:    t.Content,
-				Author:     t.Author,
-				Email:      t.Email,
-				CreatedAt:  t.CreatedAt,
-				UpdatedAt:  t.CreatedAt,
-				Status:     "open",
-				Priority:   "P3",
-				Hash:       t.Hash,
+			fmt.Printf("Scanning %d files in %s...\n", len(scope), absPath)
+			p.SetRoot(absPath)
+			todos, failed = parseFiles(p, absPath, scope)
+		} else {
+			fmt.Printf("Scanning %s...\n", absPath)
+			if todos, failed, err = parseDir(p, absPath); err != nil {
+				return fmt.Errorf("failed to scan: %w", err)
 			}
+		}
 
-			if err := db.CreateTODO(&todo); err == nil {
-				newCount++
+		if cache != nil {
+			if err := cache.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
 		}
 
+		// Reconcile with tracked TODOs so moved or edited comments keep their history
+		opts := scanOptions(cfg)
+		opts.Parser = p
+		opts.Failed = failed
+		opts.Scope = scope
+		opts.Blamer = newBlamer(cfg, absPath)
+		result, err := scanner.Sync(db, absPath, todos, opts)
+		if err != nil {
+			return fmt.Errorf("failed to save TODOs: %w", err)
+		}
+		reportBlame(opts.Blamer, result)
+
 		fmt.Printf("Scan complete!\n")
-		fmt.Printf("  Found: %d TODOs\n", len(todos))
-		fmt.Printf("  New: %d\n", newCount)
-		fmt.Printf("  Existing: %d\n", existingCount)
+		fmt.Printf("  Found: %d TODOs\n", result.Found)
+		fmt.Printf("  New: %d\n", len(result.New))
+		fmt.Printf("  Existing: %d\n", result.Existing)
+		fmt.Printf("  Moved: %d\n", len(result.Moved))
+		fmt.Printf("  Removed: %d\n", len(result.Removed))
+		for _, t := range result.Removed {
+			commit := "uncommitted"
+			if t.RemovedCommit != "" {
+				commit = t.RemovedCommit[:8]
+			}
+			fmt.Printf("    - %s %s:%d (%s)\n", t.ID[:8], t.FilePath, t.LineNumber, commit)
+		}
 
 		return nil
 	},
 }
 
+// parseDir parses a directory, warning about the files that could not be
+// read and returning them so the TODOs tracked in them are kept
+func parseDir(p *parser.Parser, root string) ([]parser.ParsedTODO, []string, error) {
+	todos, err := p.ParseDir(root)
+	var failed parser.FileErrors
+	if !errors.As(err, &failed) {
+		return todos, nil, err
+	}
+	for _, path := range failed.Paths() {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", failed[path])
+	}
+	return todos, failed.Paths(), nil
+}
+
+// parseFiles parses the given files below root, skipping those a full scan
+// would skip. Files that no longer exist yield no TODOs; files that cannot
+// be read are reported and returned so the TODOs tracked in them are kept.
+func parseFiles(p *parser.Parser, root string, paths []string) ([]parser.ParsedTODO, []string) {
+	var todos []parser.ParsedTODO
+	var failed []string
+	for _, path := range paths {
+		if inIgnoredDir(p, root, path) {
+			continue
+		}
+		found, err := p.ParseFile(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "Warning: failed to parse %s: %v\n", path, err)
+				failed = append(failed, path)
+			}
+			continue
+		}
+		todos = append(todos, found...)
+	}
+	return todos, failed
+}
+
+// inIgnoredDir reports whether path is inside a directory below root that
+// scans skip
+func inIgnoredDir(p *parser.Parser, root, path string) bool {
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if p.IgnoredDir(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPathList reads the paths listed in name, or on standard input for
+// "-", and makes them absolute. Paths are separated by NULs when there are
+// any and by newlines otherwise.
+func readPathList(name string) ([]string, error) {
+	var content []byte
+	var err error
+	if name == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+
+	sep := "\n"
+	if bytes.IndexByte(content, 0) >= 0 {
+		sep = "\x00"
+	}
+	var paths []string
+	for _, line := range strings.Split(string(content), sep) {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		path, err := filepath.Abs(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", line, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// newParser builds a parser from configuration
+func newParser(cfg *config.Config, exclude []string) *parser.Parser {
+	p := parser.New(nil, exclude, cfg.MarkerNames())
+	p.SetIgnoreCase(cfg.IgnoreCase)
+	p.SetWorkers(cfg.ParallelWorkers)
+	p.SetLanguages(languagesFromConfig(cfg.Languages))
+	return p
+}
+
+// scanOptions builds the persistence options for a scan from config
+func scanOptions(cfg *config.Config) scanner.Options {
+	markers := make(map[string]scanner.MarkerDefaults)
+	for _, name := range cfg.MarkerNames() {
+		if m, ok := cfg.Marker(name); ok {
+			markers[name] = scanner.MarkerDefaults{
+				Priority: strings.ToUpper(m.Priority),
+				Category: m.Category,
+				Severity: strings.ToLower(m.Severity),
+			}
+		}
+	}
+	return scanner.Options{
+		Precedence: cfg.AnnotationPrecedence,
+		Markers:    markers,
+	}
+}
+
+// newBlamer returns a blamer for root when git authors are enabled. Not
+// being in a repository just disables blame; other failures are reported.
+func newBlamer(cfg *config.Config, root string) *git.Blamer {
+	if !cfg.GitAuthor {
+		return nil
+	}
+	b, err := git.NewBlamer(root, filepath.Join(root, ".todo", "blame-cache.gob"))
+	if err != nil {
+		if !errors.Is(err, git.ErrNotRepository) {
+			fmt.Fprintf(os.Stderr, "Warning: git authors unavailable: %v\n", err)
+		}
+		return nil
+	}
+	return b
+}
+
+// reportBlame prints files that could not be blamed and saves the cache
+func reportBlame(b *git.Blamer, result *scanner.Result) {
+	for _, err := range result.BlameErrors {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if b != nil {
+		if err := b.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// languagesFromConfig converts configured language definitions for the parser
+func languagesFromConfig(configured []config.LanguageConfig) []parser.Language {
+	var languages []parser.Language
+	for _, l := range configured {
+		if l.Name == "" {
+			continue
+		}
+		languages = append(languages, parser.Language{
+			Name:       l.Name,
+			Extensions: l.Extensions,
+			Filenames:  l.Filenames,
+			SingleLine: l.LineComments,
+			MultiLine:  l.BlockComments,
+			Strings:    l.Strings,
+			RawStrings: l.RawStrings,
+			Nested:     l.Nested,
+			Shebangs:   l.Shebangs,
+		})
+	}
+	return languages
+}
+
+// scanCachePath returns where the incremental scan cache for root is kept
+func scanCachePath(root string) string {
+	return filepath.Join(root, ".todo", "scan-cache.gob")
+}
+
 func resolvePath(path string) (string, error) {
 	if path == "." {
 		return os.Getwd()
@@ -116,5 +335,12 @@ func resolvePath(path string) (string, error) {
 
 func init() {
 	scanCmd.Flags().StringSliceP("exclude", "e", nil, "Exclude patterns (can be repeated)")
+	scanCmd.Flags().Bool("no-cache", false, "Re-parse every file instead of using the scan cache")
+	scanCmd.Flags().String("diff", "", "Only report TODOs changed since the merge base with this ref")
+	scanCmd.Flags().Bool("branch", false, "Like --diff, against the git_branch_filter base branch")
+	scanCmd.Flags().Bool("staged", false, "Only report TODOs changed in staged files")
+	scanCmd.Flags().String("format", "text", "Output format for diff scans (text, json)")
+	scanCmd.Flags().String("files-from", "", "Only rescan the files listed in this file ('-' for standard input)")
+	scanCmd.MarkFlagsMutuallyExclusive("diff", "branch", "staged", "files-from")
 	rootCmd.AddCommand(scanCmd)
 }
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/duncan-2126/ProjectManagement/internal/diffscan"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+)
+
+// diffTODO is the JSON form of a TODO in a diff scan
+type diffTODO struct {
+	File      string   `json:"file"`
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	Type      string   `json:"type"`
+	Content   string   `json:"content"`
+	Assignee  string   `json:"assignee,omitempty"`
+	Priority  string   `json:"priority,omitempty"`
+	DueDate   string   `json:"due_date,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	IssueKeys []string `json:"issue_keys,omitempty"`
+}
+
+// diffChange is the JSON form of a changed TODO
+type diffChange struct {
+	Old diffTODO `json:"old"`
+	New diffTODO `json:"new"`
+}
+
+// diffReport is the JSON output of a diff scan
+type diffReport struct {
+	Base    string `json:"base"`
+	Summary struct {
+		Added   int `json:"added"`
+		Changed int `json:"changed"`
+		Removed int `json:"removed"`
+	} `json:"summary"`
+	Added   []diffTODO   `json:"added"`
+	Changed []diffChange `json:"changed"`
+	Removed []diffTODO   `json:"removed"`
+}
+
+func toDiffTODO(t parser.ParsedTODO) diffTODO {
+	d := diffTODO{
+		File:      t.FilePath,
+		Line:      t.LineNumber,
+		Column:    t.Column,
+		Type:      t.Type,
+		Content:   t.Content,
+		Assignee:  t.Assignee,
+		Priority:  t.Priority,
+		Tags:      t.Tags,
+		IssueKeys: t.IssueKeys,
+	}
+	if t.DueDate != nil {
+		d.DueDate = t.DueDate.Format("2006-01-02")
+	}
+	return d
+}
+
+// printDiff writes the result of a diff scan in the given format
+func printDiff(result *diffscan.Result, format string) error {
+	switch format {
+	case "json":
+		report := diffReport{
+			Base:    result.Base,
+			Added:   []diffTODO{},
+			Changed: []diffChange{},
+			Removed: []diffTODO{},
+		}
+		for _, t := range result.Added {
+			report.Added = append(report.Added, toDiffTODO(t))
+		}
+		for _, c := range result.Changed {
+			report.Changed = append(report.Changed, diffChange{Old: toDiffTODO(c.Old), New: toDiffTODO(c.New)})
+		}
+		for _, t := range result.Removed {
+			report.Removed = append(report.Removed, toDiffTODO(t))
+		}
+		report.Summary.Added = len(report.Added)
+		report.Summary.Changed = len(report.Changed)
+		report.Summary.Removed = len(report.Removed)
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+
+	case "text", "":
+		base := "empty tree"
+		if result.Base != "" {
+			base = result.Base[:8]
+		}
+		fmt.Printf("Compared with %s\n", base)
+		fmt.Printf("  Added: %d\n", len(result.Added))
+		for _, t := range result.Added {
+			fmt.Printf("    + %s:%d %s: %s\n", t.FilePath, t.LineNumber, t.Type, t.Content)
+		}
+		fmt.Printf("  Changed: %d\n", len(result.Changed))
+		for _, c := range result.Changed {
+			fmt.Printf("    ~ %s:%d %s: %s\n", c.New.FilePath, c.New.LineNumber, c.New.Type, c.New.Content)
+		}
+		fmt.Printf("  Removed: %d\n", len(result.Removed))
+		for _, t := range result.Removed {
+			fmt.Printf("    - %s:%d %s: %s\n", t.FilePath, t.LineNumber, t.Type, t.Content)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
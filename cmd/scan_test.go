@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParserUsesConfiguredRawStrings(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Languages = []config.LanguageConfig{{
+		Name:         "Raw",
+		Extensions:   []string{".raw"},
+		LineComments: []string{"#"},
+		Strings:      []string{"`"},
+		RawStrings:   []string{"`"},
+	}}
+
+	// The backslash does not escape the closing backtick
+	todos := newParser(cfg, nil).ParseContent("a.raw", []byte("x = `C:\\` # TODO: after a raw string\n"))
+	require.Len(t, todos, 1)
+	assert.Equal(t, "after a raw string", todos[0].Content)
+}
+
+func TestReadPathList(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "list")
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(list, []byte("a.go\r\nsub/b.go\n\n"), 0644))
+	paths, err := readPathList(list)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(cwd, "a.go"), filepath.Join(cwd, "sub", "b.go")}, paths)
+
+	// NUL-separated names may hold newlines
+	require.NoError(t, os.WriteFile(list, []byte("a\nb.go\x00c.go\x00"), 0644))
+	paths, err = readPathList(list)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(cwd, "a\nb.go"), filepath.Join(cwd, "c.go")}, paths)
+}
+
+func TestParseFilesSkipsWhatScansSkip(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":          "// TODO: scanned\n",
+		".hidden/b.go":  "// TODO: hidden\n",
+		"vendor/c/c.go": "// TODO: excluded\n",
+	}
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "deleted.go"))
+
+	p := newParser(config.DefaultConfig(), []string{"vendor"})
+	p.SetRoot(dir)
+	todos, failed := parseFiles(p, dir, paths)
+	assert.Empty(t, failed)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "scanned", todos[0].Content)
+}
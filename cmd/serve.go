@@ -1,32 +1,61 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/duncan-2126/ProjectManagement/internal/api"
+	"github.com/duncan-2126/ProjectManagement/internal/config"
 	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/projects"
+	"github.com/duncan-2126/ProjectManagement/internal/webui"
 	"github.com/spf13/cobra"
 )
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start web GUI server",
-	Long:  `Start the web-based GUI for browsing and managing TODOs.`,
+	Long: `Start the web-based GUI for browsing and managing TODOs.
+
+The REST API is served under /api/v1; its OpenAPI document is at
+/api/v1/openapi.json. Changes, including those made by other todo commands
+while the server runs, are streamed as server-sent events at /api/v1/events.
+
+Until a user is created with 'todo user add', anyone who can reach the
+server may change TODOs, so it only listens on loopback addresses unless
+--insecure is given. Once users exist, requests need an API token
+(Authorization: Bearer ...) or a browser session, and users' roles decide
+what they may do. Browser pages on other origins may only call the API when
+listed in server.cors_origins.
+
+Every project registered with 'todo project add' is served too, below
+/api/v1/projects/<name>, and /api/v1/projects/*/todos queries them all.
+
+The web UI is built into the binary, so the server works in any project.
+During UI development, --assets-dir serves it from a directory instead.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		port, _ := cmd.Flags().GetInt("port")
 		host, _ := cmd.Flags().GetString("host")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		assetsDir, _ := cmd.Flags().GetString("assets-dir")
 
 		addr := fmt.Sprintf("%s:%d", host, port)
 		fmt.Printf("Starting TODO Tracker web server on http://%s\n", addr)
 
 		// Create server
 		server := &Server{
-			Port: port,
-			Host: host,
+			Port:      port,
+			Host:      host,
+			Insecure:  insecure,
+			AssetsDir: assetsDir,
 		}
 
 		// Start server
@@ -38,6 +67,11 @@ type Server struct {
 	Port int
 	Host string
 	DB   *database.DB
+	// Insecure allows listening on other than loopback addresses while no
+	// users exist
+	Insecure bool
+	// AssetsDir serves the web UI from a directory instead of the binary
+	AssetsDir string
 }
 
 func (s *Server) Start(addr string) error {
@@ -53,72 +87,159 @@ func (s *Server) Start(addr string) error {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Determine the path to serve static files from (React app)
-	webPath := filepath.Join(projectPath, "web", "dist")
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	origins := cfg.Server.CORSOrigins
+	apiServer, err := s.newAPIServer(filepath.Base(projectPath), s.DB, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Host every registered project too, each with its own settings
+	hub := api.NewHub(apiServer)
+	registry, err := projects.LoadDefault()
+	if err != nil {
+		return err
+	}
+	for _, p := range registry.Projects {
+		if p.Path == projectPath {
+			hub.Add(p.Name, p.Path, apiServer)
+			continue
+		}
+		projectCfg, err := config.LoadFrom(p.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: not serving %s: %v\n", p.Name, err)
+			continue
+		}
+		db, err := p.Open()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: not serving %v\n", err)
+			continue
+		}
+		projectServer, err := s.newAPIServer(p.Name, db, projectCfg)
+		if err != nil {
+			return err
+		}
+		hub.Add(p.Name, p.Path, projectServer)
+	}
+	if len(registry.Projects) > 0 {
+		fmt.Printf("Serving %d registered projects under %s/projects\n", len(registry.Projects), api.Prefix)
+	}
+
+	ui, err := s.webUI()
+	if err != nil {
+		return err
+	}
 
 	// Register routes
-	// API routes with CORS middleware
-	apiHandler := corsMiddleware(http.HandlerFunc(s.handleAPITodos))
+	// API routes with CORS middleware; the API's authentication covers
+	// them too
+	apiHandler := corsMiddleware(origins, apiServer.Protect(http.HandlerFunc(s.handleAPITodos)))
 	http.Handle("/api/todos", apiHandler)
 
-	apiDetailHandler := corsMiddleware(http.HandlerFunc(s.handleAPITodoDetail))
+	apiDetailHandler := corsMiddleware(origins, apiServer.Protect(http.HandlerFunc(s.handleAPITodoDetail)))
 	http.Handle("/api/todo/", apiDetailHandler)
 
-	statsHandler := corsMiddleware(http.HandlerFunc(s.handleAPIStats))
+	statsHandler := corsMiddleware(origins, apiServer.Protect(http.HandlerFunc(s.handleAPIStats)))
 	http.Handle("/api/stats", statsHandler)
 
-	searchHandler := corsMiddleware(http.HandlerFunc(s.handleAPISearch))
+	searchHandler := corsMiddleware(origins, apiServer.Protect(http.HandlerFunc(s.handleAPISearch)))
 	http.Handle("/api/search", searchHandler)
 
-	// Serve React static files for all other routes (SPA support)
-	staticHandler := corsMiddleware(http.HandlerFunc(s.handleStaticFiles(webPath)))
-	http.Handle("/", staticHandler)
+	// Versioned REST API
+	http.Handle(api.Prefix+"/", corsMiddleware(origins, hub))
+
+	// Serve the web UI for all other routes
+	http.Handle("/api/", http.NotFoundHandler())
+	http.Handle("/", ui)
 
 	fmt.Printf("Server listening on %s\n", addr)
 	return http.ListenAndServe(addr, nil)
 }
 
-// handleStaticFiles serves React static files or falls back to index.html for SPA
-func (s *Server) handleStaticFiles(webPath string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// API routes are already handled, skip them
-		if strings.HasPrefix(r.URL.Path, "/api/") {
-			http.NotFound(w, r)
-			return
+// newAPIServer sets up the API of one project with the settings in its
+// config and a stream of its changes
+func (s *Server) newAPIServer(name string, db *database.DB, cfg *config.Config) (*api.Server, error) {
+	hasUsers, err := db.HasUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users of %s: %w", name, err)
+	}
+	if !hasUsers {
+		if !isLoopback(s.Host) && !s.Insecure {
+			return nil, fmt.Errorf("refusing to serve %s on %s without users; create one with 'todo user add' or pass --insecure", name, s.Host)
 		}
+		fmt.Printf("Warning: %s has no users, so authentication is off for it. Create one with 'todo user add'.\n", name)
+	}
+	if cfg.Server.AnonymousRole != "" && !database.ValidRole(cfg.Server.AnonymousRole) {
+		return nil, fmt.Errorf("invalid server.anonymous_role %q in %s", cfg.Server.AnonymousRole, name)
+	}
 
-		// Get the file path
-		path := r.URL.Path
-		if path == "/" {
-			path = "/index.html"
-		}
+	apiServer := api.New(db)
+	apiServer.AnonymousRole = cfg.Server.AnonymousRole
+	apiServer.SessionTTL = time.Duration(cfg.Server.SessionHours) * time.Hour
 
-		filePath := filepath.Join(webPath, path)
+	// Stream changes made here and by other todo commands to web clients
+	apiServer.Changes, err = db.NewChangeBus(database.DefaultPollInterval)
+	if err != nil {
+		return nil, err
+	}
+	apiServer.Changes.OnError = func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", name, err)
+	}
+	go apiServer.Changes.Run(context.Background())
+	return apiServer, nil
+}
 
-		// Check if file exists
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			// For SPA, serve index.html for non-file routes
-			indexPath := filepath.Join(webPath, "index.html")
-			http.ServeFile(w, r, indexPath)
-			return
-		}
+// isLoopback reports whether host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
 
-		// Serve the file
-		http.ServeFile(w, r, filePath)
+// webUI returns the handler for the React app, embedded in the binary or
+// read from AssetsDir
+func (s *Server) webUI() (http.Handler, error) {
+	assets, err := webui.Assets(s.AssetsDir)
+	if errors.Is(err, webui.ErrNotBuilt) {
+		fmt.Println("Warning: this binary was built without the web UI; only the API is available.")
+		return webui.NotBuilt, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load web UI: %w", err)
 	}
+	return webui.Handler(assets), nil
 }
 
-// corsMiddleware adds CORS headers to responses
-func corsMiddleware(next http.Handler) http.Handler {
+// corsMiddleware lets pages from the allowed origins call next. A "*"
+// origin allows any page, but without cookies.
+func corsMiddleware(origins []string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Add CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+			for _, allowed := range origins {
+				if allowed == "*" || allowed == origin {
+					if allowed == "*" {
+						w.Header().Set("Access-Control-Allow-Origin", "*")
+					} else {
+						w.Header().Set("Access-Control-Allow-Origin", origin)
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					}
+					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+					break
+				}
+			}
+		}
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
@@ -133,6 +254,12 @@ type APIResponse struct {
 	Error    string      `json:"error,omitempty"`
 }
 
+// writeAPIError answers a request with an error status and message
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIResponse{Success: false, Error: message})
+}
+
 type TodoListResponse struct {
 	Todos []database.TODO `json:"todos"`
 	Total int             `json:"total"`
@@ -150,7 +277,7 @@ func (s *Server) handleAPITodos(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != "GET" {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "Method not allowed"})
+		writeAPIError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -172,7 +299,7 @@ func (s *Server) handleAPITodos(w http.ResponseWriter, r *http.Request) {
 	// Get TODOs
 	todos, err := s.DB.GetTODOs(filters)
 	if err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: err.Error()})
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -189,7 +316,7 @@ func (s *Server) handleAPITodoDetail(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path
 	id := strings.TrimPrefix(r.URL.Path, "/api/todo/")
 	if id == "" {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "TODO ID required"})
+		writeAPIError(w, http.StatusBadRequest, "TODO ID required")
 		return
 	}
 
@@ -201,14 +328,14 @@ func (s *Server) handleAPITodoDetail(w http.ResponseWriter, r *http.Request) {
 	case "DELETE":
 		s.handleDeleteTodo(w, id)
 	default:
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "Method not allowed"})
+		writeAPIError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
 }
 
 func (s *Server) handleGetTodo(w http.ResponseWriter, id string) {
 	todo, err := s.DB.GetTODOByID(id)
 	if err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "TODO not found"})
+		writeAPIError(w, http.StatusNotFound, "TODO not found")
 		return
 	}
 
@@ -219,14 +346,14 @@ func (s *Server) handleGetTodo(w http.ResponseWriter, id string) {
 func (s *Server) handleUpdateTodo(w http.ResponseWriter, r *http.Request, id string) {
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "Invalid request body"})
+		writeAPIError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Get existing TODO
 	todo, err := s.DB.GetTODOByID(id)
 	if err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "TODO not found"})
+		writeAPIError(w, http.StatusNotFound, "TODO not found")
 		return
 	}
 
@@ -254,7 +381,7 @@ func (s *Server) handleUpdateTodo(w http.ResponseWriter, r *http.Request, id str
 
 	// Save
 	if err := s.DB.UpdateTODO(todo); err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: err.Error()})
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -263,7 +390,7 @@ func (s *Server) handleUpdateTodo(w http.ResponseWriter, r *http.Request, id str
 
 func (s *Server) handleDeleteTodo(w http.ResponseWriter, id string) {
 	if err := s.DB.DeleteTODO(id); err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: err.Error()})
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -275,13 +402,13 @@ func (s *Server) handleAPIStats(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != "GET" {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "Method not allowed"})
+		writeAPIError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	stats, err := s.DB.GetStats()
 	if err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: err.Error()})
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -318,7 +445,7 @@ func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != "GET" {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: "Method not allowed"})
+		writeAPIError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -334,7 +461,7 @@ func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
 	}
 	todos, err := s.DB.GetTODOs(filters)
 	if err != nil {
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Error: err.Error()})
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -369,5 +496,7 @@ func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
 func init() {
 	serveCmd.Flags().IntP("port", "p", 8080, "Port to run the server on")
 	serveCmd.Flags().StringP("host", "H", "localhost", "Host to bind the server to")
+	serveCmd.Flags().Bool("insecure", false, "Serve on a non-loopback host even though no users exist")
+	serveCmd.Flags().String("assets-dir", "", "Serve the web UI from this directory instead of the binary")
 	rootCmd.AddCommand(serveCmd)
 }
@@ -1,3 +1,80 @@
 package cmd
 
-// ... rest of file
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	request := func(origins []string, method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/todos", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		corsMiddleware(origins, ok).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request([]string{"http://localhost:5173"}, "GET", "http://localhost:5173")
+	assert.Equal(t, "http://localhost:5173", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+
+	rec = request([]string{"http://localhost:5173"}, "GET", "http://evil.example")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = request(nil, "GET", "http://localhost:5173")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Any origin may read, but never with credentials
+	rec = request([]string{"*"}, "GET", "http://evil.example")
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	rec = request([]string{"http://localhost:5173"}, "OPTIONS", "http://localhost:5173")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+}
+
+func TestIsLoopback(t *testing.T) {
+	for host, want := range map[string]bool{
+		"localhost":   true,
+		"127.0.0.1":   true,
+		"::1":         true,
+		"[::1]":       true,
+		"0.0.0.0":     false,
+		"192.168.1.5": false,
+		"example.com": false,
+	} {
+		assert.Equal(t, want, isLoopback(host), host)
+	}
+}
+
+func TestLegacyAPIStatusCodes(t *testing.T) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	s := &Server{DB: db}
+	request := func(handler http.HandlerFunc, method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request(s.handleAPITodos, "GET", "/api/todos").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(s.handleAPITodos, "POST", "/api/todos").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(s.handleAPIStats, "DELETE", "/api/stats").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(s.handleAPISearch, "PUT", "/api/search").Code)
+	assert.Equal(t, http.StatusBadRequest, request(s.handleAPITodoDetail, "GET", "/api/todo/").Code)
+	assert.Equal(t, http.StatusBadRequest, request(s.handleAPITodoDetail, "PUT", "/api/todo/x").Code)
+
+	rec := request(s.handleAPITodoDetail, "GET", "/api/todo/missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"success":false,"error":"TODO not found"}`, rec.Body.String())
+}
@@ -66,6 +66,18 @@ Example:
 		fmt.Printf("Author:     %s\n", todo.Author)
 		fmt.Printf("Created:    %s\n", todo.CreatedAt.Format("2006-01-02 15:04:05"))
 		fmt.Printf("Updated:    %s\n", todo.UpdatedAt.Format("2006-01-02 15:04:05"))
+		if todo.RemovedAt != nil {
+			fmt.Printf("Removed:    %s", todo.RemovedAt.Format("2006-01-02 15:04:05"))
+			if todo.RemovedCommit != "" {
+				fmt.Printf(" (commit %s)", todo.RemovedCommit[:8])
+			}
+			fmt.Println()
+		}
+		if links, err := db.GetIssueLinksForTODO(todo.ID); err == nil {
+			for _, link := range links {
+				fmt.Printf("Issue:      %s %s\n", link.Tracker, link.URL)
+			}
+		}
 		fmt.Printf("\nContent:\n%s\n", todo.Content)
 
 		return nil
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/duncan-2126/ProjectManagement/internal/database"
 	"github.com/spf13/cobra"
@@ -56,11 +57,24 @@ Example:
 		fmt.Println("By Type:")
 		typeMap := stats["by_type"].(map[string]int64)
 		types := []string{"TODO", "FIXME", "HACK", "BUG", "NOTE", "XXX"}
+		builtin := make(map[string]bool)
 		for _, t := range types {
+			builtin[t] = true
 			if count, ok := typeMap[t]; ok {
 				fmt.Printf("  %s: %d\n", t, count)
 			}
 		}
+		// Custom markers follow the built-in ones
+		var custom []string
+		for t := range typeMap {
+			if !builtin[t] {
+				custom = append(custom, t)
+			}
+		}
+		sort.Strings(custom)
+		for _, t := range custom {
+			fmt.Printf("  %s: %d\n", t, typeMap[t])
+		}
 		fmt.Println()
 
 		// By priority
@@ -1,32 +1,40 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/duncan-2126/ProjectManagement/internal/config"
 	"github.com/duncan-2126/ProjectManagement/internal/database"
 	"github.com/duncan-2126/ProjectManagement/internal/git"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	// Issue trackers register themselves for todo sync
+	_ "github.com/duncan-2126/ProjectManagement/internal/gitea"
+	_ "github.com/duncan-2126/ProjectManagement/internal/github"
+	_ "github.com/duncan-2126/ProjectManagement/internal/gitlab"
+	_ "github.com/duncan-2126/ProjectManagement/internal/jira"
+	_ "github.com/duncan-2126/ProjectManagement/internal/linear"
+	_ "github.com/duncan-2126/ProjectManagement/internal/notion"
 )
 
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync TODO information with git or external services",
-	Long: `Synchronize TODO information with git metadata or external services like GitHub and Jira.
+	Long: `Synchronize TODO information with git metadata or external services like GitHub, GitLab, Gitea, Jira, Linear and Notion.
 
 Examples:
   todo sync                  # Sync with git
-  todo sync github --export  # Export TODOs to GitHub Issues
-  todo sync jira --export   # Export TODOs to Jira`,
+  todo sync github           # Sync TODOs with GitHub Issues
+  todo sync gitlab           # Sync TODOs with GitLab issues
+  todo sync gitea            # Sync TODOs with Gitea issues
+  todo sync jira             # Sync TODOs with Jira
+  todo sync linear           # Sync TODOs with Linear
+  todo sync notion           # Sync TODOs with a Notion database`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check if this is a git repo
 		if !git.IsRepo() {
@@ -60,29 +68,38 @@ Examples:
 
 		fmt.Printf("Syncing %d TODOs...\n", len(todos))
 
-		// Track files that need blame
-		fileBlame := make(map[string]map[int]git.Author)
+		if blameEnabled {
+			blamer, err := git.NewBlamer(projectPath, filepath.Join(projectPath, ".todo", "blame-cache.gob"))
+			if err != nil {
+				return fmt.Errorf("failed to blame: %w", err)
+			}
 
-		for _, todo := range todos {
-			if blameEnabled {
-				// Get blame for file if not already cached
-				if _, ok := fileBlame[todo.FilePath]; !ok {
-					blame, err := git.GetBlame(todo.FilePath)
-					if err == nil {
-						fileBlame[todo.FilePath] = blame
+			// Blame each file once; report files that cannot be blamed
+			fileBlame := make(map[string]map[int]git.Author)
+			for _, todo := range todos {
+				blame, ok := fileBlame[todo.FilePath]
+				if !ok {
+					blame, err = blamer.Blame(todo.FilePath)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 					}
+					fileBlame[todo.FilePath] = blame
 				}
 
 				// Update author info if available
-				if blame, ok := fileBlame[todo.FilePath]; ok {
-					if author, ok := blame[todo.LineNumber]; ok {
-						todo.Author = author.Name
-						todo.Email = author.Email
-						todo.CreatedAt = author.Date
-						db.UpdateTODO(&todo)
+				if author, ok := blame[todo.LineNumber]; ok {
+					todo.Author = author.Name
+					todo.Email = author.Email
+					todo.CreatedAt = author.Date
+					if err := db.UpdateTODO(&todo); err != nil {
+						return fmt.Errorf("failed to update TODO: %w", err)
 					}
 				}
 			}
+
+			if err := blamer.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
 		}
 
 		// Show current branch
@@ -96,176 +113,160 @@ Examples:
 	},
 }
 
-// GitHub export command
-var githubCmd = &cobra.Command{
-	Use:   "github",
-	Short: "Sync TODOs with GitHub Issues",
-	Long: `Export TODOs to GitHub Issues or import issues from GitHub.
-
-Examples:
-  todo sync github --export   # Export TODOs to GitHub Issues`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		export, _ := cmd.Flags().GetBool("export")
-		if export {
-			return exportToGitHub()
-		}
-		return nil
-	},
-}
-
-func exportToGitHub() error {
-	// Get configuration
-	cfg := config.Load()
+// trackerHelp is the long help of the sync command of each issue tracker
+var trackerHelp = map[string]string{
+	"github": `Sync TODOs with GitHub Issues in both directions.
 
-	// Check required config
-	if cfg.GitHub.Token == "" {
-		return fmt.Errorf("GitHub token not configured. Run: todo config set integration.github.token <token>")
-	}
-	if cfg.GitHub.Owner == "" {
-		return fmt.Errorf("GitHub owner not configured. Run: todo config set integration.github.owner <owner>")
-	}
-	if cfg.GitHub.Repo == "" {
-		return fmt.Errorf("GitHub repo not configured. Run: todo config set integration.github.repo <repo>")
-	}
-
-	// Get project path
-	projectPath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-
-	// Open database
-	db, err := database.New(projectPath)
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Get TODOs
-	todos, err := db.GetTODOs(nil)
-	if err != nil {
-		return fmt.Errorf("failed to get TODOs: %w", err)
-	}
+The first sync opens an issue for every unfinished TODO and remembers it, so
+later syncs update that issue instead of opening another. Local changes to
+status, priority, category, tags, assignee and location are pushed, issues
+are closed when their TODO is resolved or deleted, and state, assignee and
+labels changed on GitHub are imported. When both sides changed, the TODO is
+reported as a conflict unless --prefer says which side wins.
 
-	fmt.Printf("Exporting %d TODOs to GitHub...\n", len(todos))
-
-	// Export each TODO as GitHub issue
-	for i, todo := range todos {
-		// Truncate title to 256 chars (GitHub limit)
-		title := todo.Content
-		if len(title) > 256 {
-			title = title[:253] + "..."
-		}
-
-		// Build labels
-		labels := []string{todo.Priority, todo.Category}
-		if todo.Assignee != "" {
-			labels = append(labels, "assigned:"+todo.Assignee)
-		}
-
-		// Filter empty labels
-		var validLabels []string
-		for _, label := range labels {
-			if label != "" {
-				validLabels = append(validLabels, label)
-			}
-		}
-
-		// Determine state
-		state := "open"
-		if todo.Status == "closed" || todo.Status == "resolved" || todo.Status == "wontfix" {
-			state = "closed"
-		}
-
-		// Build issue body
-		body := fmt.Sprintf("**Source:** %s:%d\n", todo.FilePath, todo.LineNumber)
-		body += fmt.Sprintf("**Author:** %s\n", todo.Author)
-		body += fmt.Sprintf("**Status:** %s\n", todo.Status)
-		body += fmt.Sprintf("**Priority:** %s\n", todo.Priority)
-		if todo.Assignee != "" {
-			body += fmt.Sprintf("**Assignee:** %s\n", todo.Assignee)
-		}
-		if todo.DueDate != nil {
-			body += fmt.Sprintf("**Due Date:** %s\n", todo.DueDate.Format("2006-01-02"))
-		}
-		body += "\n---\n\n" + todo.Content
-
-		// Create issue via GitHub API
-		issueData := map[string]interface{}{
-			"title":  title,
-			"body":   body,
-			"labels": validLabels,
-			"state":  state,
-		}
-
-		jsonData, _ := json.Marshal(issueData)
-		req, err := http.NewRequest("POST",
-			fmt.Sprintf("https://api.github.com/repos/%s/%s/issues", cfg.GitHub.Owner, cfg.GitHub.Repo),
-			bytes.NewBuffer(jsonData))
-		if err != nil {
-			fmt.Printf("Failed to create request for TODO %d: %v\n", i+1, err)
-			continue
-		}
-
-		req.Header.Set("Authorization", "token "+cfg.GitHub.Token)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Printf("Failed to create issue for TODO %d: %v\n", i+1, err)
-			continue
-		}
-		defer resp.Body.Close()
+Examples:
+  todo sync github                  # Sync with GitHub Issues
+  todo sync github --prefer remote  # Let GitHub win conflicts
+  todo sync github --dry-run        # Show what a sync would change`,
+	"gitlab": `Sync TODOs with GitLab issues in both directions.
+
+The first sync opens an issue for every unfinished TODO and remembers it, so
+later syncs update that issue instead of opening another. Local changes to
+status, priority, category, tags, assignee, due date and location are
+pushed, issues are closed when their TODO is finished or deleted, and
+state, assignee, due date and labels changed on GitLab are imported. When
+both sides changed, the TODO is reported as a conflict unless --prefer says
+which side wins.
 
-		if resp.StatusCode != 201 {
-			fmt.Printf("Failed to create issue for TODO %d: HTTP %d\n", i+1, resp.StatusCode)
-			continue
-		}
+Examples:
+  todo sync gitlab                  # Sync with GitLab
+  todo sync gitlab --prefer remote  # Let GitLab win conflicts
+  todo sync gitlab --dry-run        # Show what a sync would change`,
+	"gitea": `Sync TODOs with Gitea issues in both directions.
+
+The first sync opens an issue for every unfinished TODO and remembers it, so
+later syncs update that issue instead of opening another. Local changes to
+status, priority, category, tags, assignee, due date and location are
+pushed, issues are closed when their TODO is finished or deleted, and
+state, assignee, due date and labels changed on Gitea are imported. Labels
+the repository lacks are created. When both sides changed, the TODO is
+reported as a conflict unless --prefer says which side wins.
 
-		var result map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&result)
-		issueNum := int(result["number"].(float64))
-		fmt.Printf("Created issue #%d: %s\n", issueNum, title)
-	}
+Examples:
+  todo sync gitea                  # Sync with Gitea
+  todo sync gitea --prefer remote  # Let Gitea win conflicts
+  todo sync gitea --dry-run        # Show what a sync would change`,
+	"jira": `Sync TODOs with Jira issues in both directions.
+
+The first sync creates an issue for every unfinished TODO and remembers its
+key, so later syncs update that issue instead of creating another. Local
+changes are pushed, moving issues through the workflow with the transition
+configured for each status, and status, resolution, priority, assignee,
+due date and labels changed in Jira are imported. When both sides changed,
+the TODO is reported as a conflict unless --prefer says which side wins.
+
+Statuses, priorities, issue types and users are mapped in the config:
+
+  [jira.transitions]   # TODO status = transition, or status it leads to
+  in_progress = "Start Progress"
+  wontfix = "Won't Do"
+  [jira.priorities]
+  P0 = "Blocker"
+  [jira.issue_types]
+  HACK = "Tech Debt"
+  [jira.users]         # assignee = Jira account ID
+  alice = "5b10a2844c20165700ede21g"
 
-	fmt.Println("GitHub export complete!")
-	return nil
-}
+Examples:
+  todo sync jira                  # Sync with Jira
+  todo sync jira --prefer local   # Let local changes win conflicts
+  todo sync jira --dry-run        # Show what a sync would change`,
+	"linear": `Sync TODOs with the issues of a Linear team in both directions.
+
+The first sync creates an issue for every unfinished TODO and remembers it,
+so later syncs update that issue instead of creating another. Local changes
+are pushed, and workflow state, priority, assignee, due date and labels
+changed in Linear are imported. Deleting a TODO cancels its issue. When both
+sides changed, the TODO is reported as a conflict unless --prefer says which
+side wins.
+
+TODO statuses move issues to the team's first state of the matching type
+(open to unstarted, in_progress to started, resolved to completed, wontfix
+to canceled) unless a state is named in the config:
+
+  [linear.states]      # TODO status = workflow state
+  blocked = "Blocked"
+  [linear.users]       # assignee = Linear user ID
+  alice = "a1b2c3d4-..."
 
-// Jira export command
-var jiraSyncCmd = &cobra.Command{
-	Use:   "jira",
-	Short: "Sync TODOs with Jira",
-	Long: `Export TODOs to Jira issues.
+Examples:
+  todo sync linear                  # Sync with Linear
+  todo sync linear --prefer remote  # Let Linear win conflicts
+  todo sync linear --dry-run        # Show what a sync would change`,
+	"notion": `Sync TODOs with the pages of a Notion database in both directions.
+
+The first sync adds a page for every unfinished TODO and remembers it, so
+later syncs update that page instead of adding another. Each TODO field is
+written to the property of the same name (Status, Priority, Type, File,
+Line, Assignee, Category, Tags and Due); properties the database lacks are
+skipped. Changes made in Notion are imported, and deleting a TODO archives
+its page. When both sides changed, the TODO is reported as a conflict unless
+--prefer says which side wins.
+
+Property names and status options can be changed in the config:
+
+  [notion.properties]  # field = property
+  status = "Stage"
+  [notion.statuses]    # TODO status = option
+  open = "Not started"
+  resolved = "Done"
 
 Examples:
-  todo sync jira --export  # Export TODOs to Jira`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		export, _ := cmd.Flags().GetBool("export")
-		if export {
-			return exportToJira()
-		}
-		return nil
-	},
+  todo sync notion                 # Sync with Notion
+  todo sync notion --prefer local  # Let local changes win conflicts
+  todo sync notion --dry-run       # Show what a sync would change`,
 }
 
-func exportToJira() error {
-	// Get configuration
-	cfg := config.Load()
+// trackerSyncCmd returns the sync command of a registered issue tracker
+func trackerSyncCmd(a integrations.Adapter) *cobra.Command {
+	long := trackerHelp[a.Name]
+	if long == "" {
+		long = fmt.Sprintf(`Sync TODOs with %s in both directions.
 
-	// Check required config
-	if cfg.Jira.URL == "" {
-		return fmt.Errorf("Jira URL not configured. Run: todo config set integration.jira.url <url>")
+The first sync creates an issue for every unfinished TODO and remembers it,
+so later syncs update that issue instead of creating another. When both
+sides changed, the TODO is reported as a conflict unless --prefer says which
+side wins.`, a.Title)
+	}
+	cmd := &cobra.Command{
+		Use:   a.Name,
+		Short: "Sync TODOs with " + a.Title,
+		Long:  long,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefer, _ := cmd.Flags().GetString("prefer")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runTrackerSync(a, prefer, dryRun)
+		},
 	}
-	if cfg.Jira.Email == "" {
-		return fmt.Errorf("Jira email not configured. Run: todo config set integration.jira.email <email>")
+	cmd.Flags().String("prefer", "", "Side that wins conflicts: local or remote (default: report them)")
+	cmd.Flags().Bool("dry-run", false, "Show what a sync would change without changing anything")
+	return cmd
+}
+
+// runTrackerSync syncs the current project with an issue tracker and
+// reports the outcome
+func runTrackerSync(a integrations.Adapter, prefer string, dryRun bool) error {
+	if prefer != "" && prefer != integrations.PreferLocal && prefer != integrations.PreferRemote {
+		return fmt.Errorf("invalid --prefer %q: use local or remote", prefer)
 	}
-	if cfg.Jira.APIToken == "" {
-		return fmt.Errorf("Jira API token not configured. Run: todo config set integration.jira.api-token <token>")
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
 	}
-	if cfg.Jira.Project == "" {
-		return fmt.Errorf("Jira project not configured. Run: todo config set integration.jira.project <project-key>")
+	tracker, err := a.New(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Get project path
@@ -280,121 +281,37 @@ func exportToJira() error {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Get TODOs
-	todos, err := db.GetTODOs(nil)
+	syncer := &integrations.Syncer{Tracker: tracker, DB: db, Prefer: prefer, DryRun: dryRun}
+	if dryRun {
+		fmt.Printf("Dry run with %s (%s); nothing will be changed\n", a.Title, tracker.Name())
+	} else {
+		fmt.Printf("Syncing with %s (%s)...\n", a.Title, tracker.Name())
+	}
+	result, err := syncer.Sync()
 	if err != nil {
-		return fmt.Errorf("failed to get TODOs: %w", err)
+		return err
 	}
 
-	fmt.Printf("Exporting %d TODOs to Jira...\n", len(todos))
-
-	// Export each TODO as Jira issue
-	for i, todo := range todos {
-		// Build description
-		description := fmt.Sprintf("*Source:* %s:%d\n", todo.FilePath, todo.LineNumber)
-		description += fmt.Sprintf("*Author:* %s\n", todo.Author)
-		description += fmt.Sprintf("*Status:* %s\n", todo.Status)
-		description += fmt.Sprintf("*Priority:* %s\n", todo.Priority)
-		if todo.Assignee != "" {
-			description += fmt.Sprintf("*Assignee:* %s\n", todo.Assignee)
-		}
-		if todo.DueDate != nil {
-			description += fmt.Sprintf("*Due Date:* %s\n", todo.DueDate.Format("2006-01-02"))
-		}
-		description += "\n---\n\n" + todo.Content
-
-		// Truncate summary to 255 chars (Jira limit)
-		summary := todo.Content
-		if len(summary) > 255 {
-			summary = summary[:252] + "..."
-		}
-
-		// Map priority to Jira priority
-		jiraPriority := mapPriorityToJira(todo.Priority)
-
-		// Build issue data
-		issueData := map[string]interface{}{
-			"fields": map[string]interface{}{
-				"project": map[string]string{
-					"key": cfg.Jira.Project,
-				},
-				"summary":     summary,
-				"description": description,
-				"issuetype": map[string]string{
-					"name": "Task",
-				},
-				"priority": map[string]string{
-					"name": jiraPriority,
-				},
-			},
-		}
-
-		// Add assignee if specified
-		if todo.Assignee != "" {
-			issueData["fields"].(map[string]interface{})["assignee"] = map[string]string{
-				"name": todo.Assignee,
-			}
-		}
-
-		// Add due date if specified
-		if todo.DueDate != nil {
-			issueData["fields"].(map[string]interface{})["duedate"] = todo.DueDate.Format("2006-01-02")
-		}
-
-		jsonData, _ := json.Marshal(issueData)
-		req, err := http.NewRequest("POST",
-			cfg.Jira.URL+"/rest/api/3/issue",
-			bytes.NewBuffer(jsonData))
-		if err != nil {
-			fmt.Printf("Failed to create request for TODO %d: %v\n", i+1, err)
-			continue
-		}
-
-		req.SetBasicAuth(cfg.Jira.Email, cfg.Jira.APIToken)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Printf("Failed to create Jira issue for TODO %d: %v\n", i+1, err)
-			continue
-		}
-		defer resp.Body.Close()
-
-			if resp.StatusCode != 201 {
-				fmt.Printf("Failed to create Jira issue for TODO %d: HTTP %d\n", i+1, resp.StatusCode)
-				// Read error response for debugging
-				body, _ := io.ReadAll(resp.Body)
-				fmt.Printf("Response: %s\n", string(body))
-				continue
-			}
-
-		var result map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&result)
-		key := result["key"].(string)
-		fmt.Printf("Created %s: %s\n", key, summary)
+	for _, err := range result.Errors {
+		fmt.Fprintf(os.Stderr, "Failed: %v\n", err)
 	}
-
-	fmt.Println("Jira export complete!")
-	return nil
-}
-
-func mapPriorityToJira(priority string) string {
-	switch priority {
-	case "P0":
-		return "Highest"
-	case "P1":
-		return "High"
-	case "P2":
-		return "Medium"
-	case "P3":
-		return "Low"
-	case "P4":
-		return "Lowest"
-	default:
-		return "Medium"
+	for _, c := range result.Conflicts {
+		fmt.Printf("Conflict: %s and %s both changed (%s)\n", c.TODO.ID[:8], c.Issue.Key, c.Issue.URL)
+	}
+	summary := fmt.Sprintf("created %d, updated %d, imported %d, closed %d, unchanged %d issues",
+		result.Created, result.Updated, result.Imported, result.Closed, result.Unchanged)
+	if dryRun {
+		fmt.Println("Would have " + summary)
+	} else {
+		fmt.Println(strings.ToUpper(summary[:1]) + summary[1:])
 	}
+	if len(result.Conflicts) > 0 {
+		fmt.Println("Settle conflicts with --prefer local or --prefer remote")
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d TODOs failed to sync", len(result.Errors))
+	}
+	return nil
 }
 
 // Config commands for integration settings
@@ -413,12 +330,32 @@ GitHub:
   todo config set integration.github.token <token>
   todo config set integration.github.owner <owner>
   todo config set integration.github.repo <repo>
+  todo config set integration.github.api-url <url>   # GitHub Enterprise
+
+GitLab:
+  todo config set integration.gitlab.token <token>
+  todo config set integration.gitlab.project <group/project>
+  todo config set integration.gitlab.url <url>       # Self-managed GitLab
+
+Gitea:
+  todo config set integration.gitea.url <url>
+  todo config set integration.gitea.token <token>
+  todo config set integration.gitea.owner <owner>
+  todo config set integration.gitea.repo <repo>
 
 Jira:
   todo config set integration.jira.url <url>
   todo config set integration.jira.email <email>
   todo config set integration.jira.api-token <token>
-  todo config set integration.jira.project <project-key>`,
+  todo config set integration.jira.project <project-key>
+
+Linear:
+  todo config set integration.linear.api-key <key>
+  todo config set integration.linear.team-id <team-id>
+
+Notion:
+  todo config set integration.notion.token <token>
+  todo config set integration.notion.database-id <database-id>`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 2 {
 			return fmt.Errorf("requires at least 2 arguments: <key> <value>")
@@ -428,59 +365,60 @@ Jira:
 
 		// Handle integration settings
 		if strings.HasPrefix(key, "integration.") {
-			parts := strings.Split(key, ".")
-				if len(parts) != 3 {
-					return fmt.Errorf("invalid integration key format. Use: integration.<service>.<field>")
-				}
-				_ = parts[1]
-				_ = parts[2]
+			field := strings.TrimPrefix(key, "integration.")
+			if len(strings.Split(field, ".")) != 2 {
+				return fmt.Errorf("invalid integration key format. Use: integration.<service>.<field>")
+			}
 
 			// Map field names to config keys
 			fieldMap := map[string]string{
-				"github.token":   "github.token",
-				"github.owner":   "github.owner",
-				"github.repo":    "github.repo",
-				"jira.url":       "jira.url",
-				"jira.email":     "jira.email",
-				"jira.api-token": "jira.api_token",
-				"jira.project":   "jira.project",
-			}
-
-			configKey := fieldMap[key]
-			if configKey == "" {
-				configKey = strings.ReplaceAll(key, "-", "_")
+				"github.token":       "github.token",
+				"github.owner":       "github.owner",
+				"github.repo":        "github.repo",
+				"github.api-url":     "github.api_url",
+				"gitlab.token":       "gitlab.token",
+				"gitlab.url":         "gitlab.url",
+				"gitlab.project":     "gitlab.project",
+				"gitea.token":        "gitea.token",
+				"gitea.url":          "gitea.url",
+				"gitea.owner":        "gitea.owner",
+				"gitea.repo":         "gitea.repo",
+				"jira.url":           "jira.url",
+				"jira.email":         "jira.email",
+				"jira.api-token":     "jira.api_token",
+				"jira.project":       "jira.project",
+				"linear.api-key":     "linear.api_key",
+				"linear.team-id":     "linear.team_id",
+				"linear.api-url":     "linear.api_url",
+				"notion.token":       "notion.token",
+				"notion.database-id": "notion.database_id",
+				"notion.api-url":     "notion.api_url",
 			}
 
-			viper.Set(configKey, value)
-
-			// Save to config file
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("failed to get home directory: %w", err)
+			key = fieldMap[field]
+			if key == "" {
+				key = strings.ReplaceAll(field, "-", "_")
 			}
-			configPath := filepath.Join(homeDir, ".config", "todolist")
-			os.MkdirAll(configPath, 0755)
-			configFile := filepath.Join(configPath, "config.toml")
-
-			return viper.SafeWriteConfigAs(configFile)
 		}
 
-		// Regular config settings
 		viper.Set(key, value)
-
-		// Save to config file
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
-		}
-		configPath := filepath.Join(homeDir, ".config", "todolist")
-		os.MkdirAll(configPath, 0755)
-		configFile := filepath.Join(configPath, "config.toml")
-
-		return viper.SafeWriteConfigAs(configFile)
+		return writeGlobalConfig()
 	},
 }
 
+// writeGlobalConfig saves the settings viper holds to the global config file,
+// replacing it
+func writeGlobalConfig() error {
+	configPath, err := config.Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configPath, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return viper.WriteConfigAs(filepath.Join(configPath, "config.toml"))
+}
+
 var configGetCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Get a configuration value",
@@ -503,12 +441,16 @@ func init() {
 	syncCmd.Flags().BoolP("blame", "b", false, "Run git blame to get author info")
 	rootCmd.AddCommand(syncCmd)
 
-	// Add GitHub and Jira subcommands
-	githubCmd.Flags().Bool("export", false, "Export TODOs to GitHub Issues")
-	jiraSyncCmd.Flags().Bool("export", false, "Export TODOs to Jira")
-
-	syncCmd.AddCommand(githubCmd)
-	syncCmd.AddCommand(jiraSyncCmd)
+	// Every registered issue tracker gets a subcommand
+	for _, a := range integrations.Adapters() {
+		cmd := trackerSyncCmd(a)
+		if a.Name == "github" || a.Name == "jira" {
+			// Kept from before syncing was two-way
+			cmd.Flags().Bool("export", false, "Export TODOs to "+a.Title)
+			cmd.Flags().MarkDeprecated("export", fmt.Sprintf("syncing with %s is now two-way and needs no flag", a.Title))
+		}
+		syncCmd.AddCommand(cmd)
+	}
 
 	// Add config commands
 	configCmd.AddCommand(configSetCmd)
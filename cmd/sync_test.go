@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigSetConfiguresTrackers follows the hints of each tracker, setting
+// one key per run as separate invocations of todo would, until it syncs
+func TestConfigSetConfiguresTrackers(t *testing.T) {
+	hint := regexp.MustCompile(`todo config set (\S+) <`)
+	// Notion reads the database when the tracker is made
+	notion := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"properties":{"Name":{"type":"title"}}}`)
+	}))
+	defer notion.Close()
+
+	for _, a := range integrations.Adapters() {
+		t.Run(a.Name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			if a.Name == "notion" {
+				require.NoError(t, configSetCmd.RunE(configSetCmd, []string{"integration.notion.api-url", notion.URL}))
+			}
+			var set []string
+			for {
+				viper.Reset()
+				cfg, err := config.Load()
+				require.NoError(t, err)
+				_, err = a.New(cfg)
+				if err == nil {
+					break
+				}
+				m := hint.FindStringSubmatch(err.Error())
+				require.NotNil(t, m, "no hint in %q", err)
+				require.NotContains(t, set, m[1], "setting %s did not configure it", m[1])
+				set = append(set, m[1])
+
+				require.NoError(t, configSetCmd.RunE(configSetCmd, []string{m[1], "https://example.com"}))
+			}
+			assert.NotEmpty(t, set)
+		})
+	}
+}
+
+// TestReadmeTrackerSteps runs the config set steps the README gives for each
+// tracker and checks they are enough to sync
+func TestReadmeTrackerSteps(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	require.NoError(t, err)
+	step := regexp.MustCompile(`(?m)^todo config set (integration\.(\w+)\.\S+) (\S+)`)
+	steps := make(map[string][][]string)
+	for _, m := range step.FindAllStringSubmatch(string(readme), -1) {
+		steps[m[2]] = append(steps[m[2]], []string{m[1], m[3]})
+	}
+
+	names := map[string]string{
+		"github": "github:acme/app",
+		"gitlab": "gitlab:group/app",
+		"gitea":  "gitea:acme/app",
+		"jira":   "jira:OPS",
+	}
+	for name, want := range names {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			require.NotEmpty(t, steps[name])
+			for _, args := range steps[name] {
+				viper.Reset()
+				_, err := config.Load()
+				require.NoError(t, err)
+				require.NoError(t, configSetCmd.RunE(configSetCmd, args))
+			}
+			viper.Reset()
+			a, ok := integrations.Lookup(name)
+			require.True(t, ok)
+			cfg, err := config.Load()
+			require.NoError(t, err)
+			tracker, err := a.New(cfg)
+			require.NoError(t, err)
+			assert.Equal(t, want, tracker.Name())
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users of the web server",
+	Long: `Manage who may use 'todo serve' and what they may do.
+
+Roles:
+  viewer       read TODOs and their tags, relationships, watches and time
+  contributor  also change them
+  admin        also manage users and read the audit trail
+
+Once the first user exists the server requires signing in.
+
+Example:
+  todo user add alice --role admin
+  todo user role bob viewer
+  todo user list`,
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a user",
+	Long: `Add a user. The password is prompted for; leave it empty for a user
+that only signs in with API tokens. Use --password-stdin in scripts.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		role, _ := cmd.Flags().GetString("role")
+		if !database.ValidRole(role) {
+			return fmt.Errorf("invalid role %q (valid: %s)", role, strings.Join(database.Roles, ", "))
+		}
+
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		if _, err := db.GetUser(args[0]); err == nil {
+			return fmt.Errorf("user %s already exists", args[0])
+		}
+
+		password, err := readPassword(cmd)
+		if err != nil {
+			return err
+		}
+		user, err := db.CreateUser(args[0], role, password)
+		if err != nil {
+			return fmt.Errorf("failed to add user: %w", err)
+		}
+		fmt.Printf("Added %s user %s\n", user.Role, user.Name)
+		return nil
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		users, err := db.GetUsers()
+		if err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		if len(users) == 0 {
+			fmt.Println("No users; the web server runs without authentication.")
+			return nil
+		}
+		for _, u := range users {
+			login := "password"
+			if u.PasswordHash == "" {
+				login = "tokens only"
+			}
+			fmt.Printf("  %-20s %-12s %s\n", u.Name, u.Role, login)
+		}
+		return nil
+	},
+}
+
+var userRoleCmd = &cobra.Command{
+	Use:   "role <name> <role>",
+	Short: "Change the role of a user",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, role := args[0], args[1]
+		if !database.ValidRole(role) {
+			return fmt.Errorf("invalid role %q (valid: %s)", role, strings.Join(database.Roles, ", "))
+		}
+
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		user, err := db.GetUser(name)
+		if err != nil {
+			return fmt.Errorf("user not found: %s", name)
+		}
+		if user.Role == database.RoleAdmin && role != database.RoleAdmin {
+			if err := checkOtherAdmins(db); err != nil {
+				return err
+			}
+		}
+		user.Role = role
+		if err := db.UpdateUser(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		fmt.Printf("%s is now %s\n", name, role)
+		return nil
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd <name>",
+	Short: "Set the password of a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		user, err := db.GetUser(args[0])
+		if err != nil {
+			return fmt.Errorf("user not found: %s", args[0])
+		}
+		password, err := readPassword(cmd)
+		if err != nil {
+			return err
+		}
+		if err := user.SetPassword(password); err != nil {
+			return err
+		}
+		if err := db.UpdateUser(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		fmt.Printf("Password of %s updated\n", user.Name)
+		return nil
+	},
+}
+
+var userRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a user with their tokens and sessions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		user, err := db.GetUser(args[0])
+		if err != nil {
+			return fmt.Errorf("user not found: %s", args[0])
+		}
+		if user.Role == database.RoleAdmin {
+			if err := checkOtherAdmins(db); err != nil {
+				return err
+			}
+		}
+		if err := db.DeleteUser(user); err != nil {
+			return fmt.Errorf("failed to remove user: %w", err)
+		}
+		fmt.Printf("Removed user %s\n", user.Name)
+		return nil
+	},
+}
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for the web server",
+	Long: `Manage API tokens. Scripts send them to 'todo serve' as
+Authorization: Bearer <token> and act with the role of the token's user.
+
+Example:
+  todo token create alice --name ci
+  todo token list
+  todo token revoke <token-id>`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <user>",
+	Short: "Create an API token for a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		user, err := db.GetUser(args[0])
+		if err != nil {
+			return fmt.Errorf("user not found: %s", args[0])
+		}
+		raw, token, err := db.CreateToken(user.ID, name)
+		if err != nil {
+			return fmt.Errorf("failed to create token: %w", err)
+		}
+		fmt.Printf("Created token %s for %s. It is shown only once:\n\n  %s\n", token.ID, user.Name, raw)
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		users, err := db.GetUsers()
+		if err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		names := make(map[string]string)
+		for _, u := range users {
+			names[u.ID] = u.Name
+		}
+
+		tokens, err := db.GetTokens("")
+		if err != nil {
+			return fmt.Errorf("failed to list tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No API tokens.")
+			return nil
+		}
+		for _, t := range tokens {
+			used := "never used"
+			if t.LastUsedAt != nil {
+				used = "last used " + t.LastUsedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("  %s  %-12s %-16s %s\n", t.ID, names[t.UserID], t.Name, used)
+		}
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openProjectDB()
+		if err != nil {
+			return err
+		}
+		if _, err := db.GetToken(args[0]); err != nil {
+			return fmt.Errorf("token not found: %s", args[0])
+		}
+		if err := db.RevokeToken(args[0]); err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+		fmt.Printf("Revoked token %s\n", args[0])
+		return nil
+	},
+}
+
+// openProjectDB opens the database of the project in the current directory
+func openProjectDB() (*database.DB, error) {
+	projectPath, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	db, err := database.New(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// checkOtherAdmins refuses to remove the last admin, who alone can manage
+// users through the web server
+func checkOtherAdmins(db *database.DB) error {
+	admins, err := db.CountAdmins()
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins <= 1 {
+		return fmt.Errorf("cannot remove the last admin")
+	}
+	return nil
+}
+
+// readPassword prompts for a password twice, or reads one line from stdin
+// with --password-stdin
+func readPassword(cmd *cobra.Command) (string, error) {
+	fromStdin, _ := cmd.Flags().GetBool("password-stdin")
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for a password; use --password-stdin")
+	}
+	fmt.Print("Password (empty for token-only): ")
+	first, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if len(first) == 0 {
+		return "", nil
+	}
+	fmt.Print("Repeat password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if string(first) != string(second) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(first), nil
+}
+
+func init() {
+	userAddCmd.Flags().String("role", database.RoleContributor, "Role: viewer, contributor or admin")
+	userAddCmd.Flags().Bool("password-stdin", false, "Read the password from stdin")
+	userPasswdCmd.Flags().Bool("password-stdin", false, "Read the password from stdin")
+	userCmd.AddCommand(userAddCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userRoleCmd)
+	userCmd.AddCommand(userPasswdCmd)
+	userCmd.AddCommand(userRemoveCmd)
+	rootCmd.AddCommand(userCmd)
+
+	tokenCreateCmd.Flags().String("name", "", "Name to recognise the token by")
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
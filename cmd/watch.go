@@ -1,26 +1,41 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/duncan-2126/ProjectManagement/internal/api"
+	"github.com/duncan-2126/ProjectManagement/internal/config"
 	"github.com/duncan-2126/ProjectManagement/internal/database"
 	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/scanner"
+	"github.com/duncan-2126/ProjectManagement/internal/watcher"
 	"github.com/spf13/cobra"
 )
 
 var watchCmd = &cobra.Command{
 	Use:   "watch",
 	Short: "Watch for file changes and auto-scan",
-	Long: `Watch the codebase for changes and automatically scan for new TODOs.
-Useful for development workflows where TODOs are added frequently.
+	Long: `Watch the codebase for changes and keep tracked TODOs up to date.
+Changes are picked up from filesystem notifications as they happen and
+only the files that changed are re-parsed. Renamed and deleted files are
+followed, and excludes come from the config.
+
+With --events, changes are also streamed to web clients as server-sent
+events at /api/v1/events, the same stream 'todo serve' provides.
 
 Example:
   todo watch
-  todo watch --interval 30s`,
+  todo watch --debounce 1s
+  todo watch --events localhost:8081`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get project path
 		projectPath, err := os.Getwd()
@@ -28,96 +43,156 @@ Example:
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
-		// Get interval
-		intervalStr, _ := cmd.Flags().GetString("interval")
-		interval, err := time.ParseDuration(intervalStr)
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+		eventsAddr, _ := cmd.Flags().GetString("events")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+
+		// Open database once for the whole session
+		db, err := database.New(projectPath)
 		if err != nil {
-			return fmt.Errorf("invalid interval: %w", err)
+			return fmt.Errorf("failed to open database: %w", err)
 		}
 
-		// Get exclude patterns
-		exclude := []string{".git", "node_modules", "vendor", "dist", ".todo"}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		w := &projectWatcher{root: projectPath, cfg: cfg, db: db}
+		w.reloadParser()
 
-		fmt.Printf("Watching %s for changes (scan every %s)...\n", projectPath, interval)
-		fmt.Println("Press Ctrl+C to stop.")
-		fmt.Println()
+		if eventsAddr != "" {
+			host, _, err := net.SplitHostPort(eventsAddr)
+			if err != nil {
+				return fmt.Errorf("invalid --events address: %w", err)
+			}
+			// Scans record their changes, which the API streams
+			s := &Server{Host: host, Insecure: insecure}
+			apiServer, err := s.newAPIServer(filepath.Base(projectPath), db, cfg)
+			if err != nil {
+				return err
+			}
+			mux := http.NewServeMux()
+			mux.Handle(api.Prefix+"/events", corsMiddleware(cfg.Server.CORSOrigins, apiServer))
+			go func() {
+				if err := http.ListenAndServe(eventsAddr, mux); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: event stream stopped: %v\n", err)
+				}
+			}()
+			fmt.Printf("Streaming changes on http://%s%s/events\n", eventsAddr, api.Prefix)
+		}
+
+		// Initial full scan
+		if _, err := w.scan(nil, nil); err != nil {
+			return err
+		}
 
-		// Initial scan
-		runScan(projectPath, exclude)
+		fw, err := watcher.New(projectPath, watcher.Options{
+			Debounce: debounce,
+			SkipDir:  w.skipDir,
+			OnError: func(err error) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		defer fw.Close()
 
-		// Set up file watcher using simple polling
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
+		fmt.Printf("Watching %s for changes (%d directories)...\n", projectPath, fw.Dirs())
+		fmt.Println("Press Ctrl+C to stop.")
+		fmt.Println()
 
 		// Handle Ctrl+C
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-		lastScan := time.Now()
-
-		for {
-			select {
-			case <-ticker.C:
-				// Run scan
-				count := runScan(projectPath, exclude)
-				lastScan = time.Now()
-				if count > 0 {
-					fmt.Printf("[%s] Found %d new TODOs\n", lastScan.Format("15:04:05"), count)
-				}
-			case <-sigChan:
-				fmt.Println("\nStopping watch...")
-				return nil
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err = fw.Run(ctx, func(b watcher.Batch) {
+			result, err := w.scan(b.Changed, b.Removed)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return
 			}
-		}
+			if len(result.New) > 0 || len(result.Moved) > 0 || len(result.Removed) > 0 {
+				fmt.Printf("[%s] %d new, %d moved, %d removed\n", time.Now().Format("15:04:05"),
+					len(result.New), len(result.Moved), len(result.Removed))
+			}
+		})
+		fmt.Println("\nStopping watch...")
+		return err
 	},
 }
 
-func runScan(projectPath string, exclude []string) int {
-	// Open database
-	db, err := database.New(projectPath)
-	if err != nil {
-		return 0
-	}
+// projectWatcher keeps the database in sync with the files of a project
+type projectWatcher struct {
+	root   string
+	cfg    *config.Config
+	db     *database.DB
+	parser *parser.Parser
+	cache  *parser.Cache
+}
 
-	// Create parser
-	p := parser.New(nil, exclude, nil)
+// reloadParser builds the parser, again after ignore files change so their
+// rules are re-read
+func (w *projectWatcher) reloadParser() {
+	w.parser = newParser(w.cfg, w.cfg.ExcludePatterns)
+	w.parser.SetRoot(w.root)
+	w.cache = parser.LoadCache(scanCachePath(w.root), time.Duration(w.cfg.CacheTTL)*time.Minute)
+	w.parser.SetCache(w.cache)
+}
 
-	// Parse directory
-	todos, err := p.ParseDir(projectPath)
-	if err != nil {
-		return 0
-	}
+// skipDir reports directories that are not watched: those a scan skips,
+// which include .todo as it is hidden
+func (w *projectWatcher) skipDir(path string) bool {
+	return w.parser.IgnoredDir(path)
+}
 
-	// Save new TODOs to database
-	newCount := 0
-	for _, t := range todos {
-		exists, _ := db.TODOExists(t.Hash, t.FilePath, t.LineNumber)
-		if exists {
-			continue
+// scan re-parses changed files and reconciles them, together with removed
+// paths, against the database. Without changes the whole tree is scanned.
+func (w *projectWatcher) scan(changed, removed []string) (*scanner.Result, error) {
+	for _, path := range changed {
+		for _, name := range parser.IgnoreFiles {
+			if filepath.Base(path) == name {
+				w.reloadParser()
+			}
 		}
+	}
 
-		todo := database.TODO{
-			FilePath:   t.FilePath,
-			LineNumber: t.LineNumber,
-			Column:     t.Column,
-			Type:       t.Type,
-			Content:    t.Content,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
-			Status:     "open",
-			Priority:   "P3",
-			Hash:       t.Hash,
+	var todos []parser.ParsedTODO
+	opts := scanOptions(w.cfg)
+	opts.Parser = w.parser
+	if changed == nil && removed == nil {
+		var err error
+		if todos, opts.Failed, err = parseDir(w.parser, w.root); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
 		}
-
-		if err := db.CreateTODO(&todo); err == nil {
-			newCount++
+	} else {
+		for _, path := range changed {
+			found, err := w.parser.ParseFile(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			todos = append(todos, found...)
 		}
+		opts.Scope = append(append([]string{}, changed...), removed...)
+	}
+	if err := w.cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 
-	return newCount
+	opts.Blamer = newBlamer(w.cfg, w.root)
+	result, err := scanner.Sync(w.db, w.root, todos, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save TODOs: %w", err)
+	}
+	reportBlame(opts.Blamer, result)
+	return result, nil
 }
 
 func init() {
-	watchCmd.Flags().StringP("interval", "i", "30s", "Scan interval (e.g., 30s, 1m, 5m)")
+	watchCmd.Flags().Duration("debounce", watcher.DefaultDebounce, "How long to wait for changes to settle before rescanning")
+	watchCmd.Flags().String("events", "", "Stream changes to web clients as server-sent events on this address")
+	watchCmd.Flags().Bool("insecure", false, "Stream events on a non-loopback host even though no users exist")
+	watchCmd.Flags().StringP("interval", "i", "", "Scan interval")
+	watchCmd.Flags().MarkDeprecated("interval", "changes are now picked up as they happen")
 	rootCmd.AddCommand(watchCmd)
 }
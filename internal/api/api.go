@@ -0,0 +1,195 @@
+// Package api implements the versioned REST API served by todo serve
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"gorm.io/gorm"
+)
+
+// Prefix is the path the API is mounted at
+const Prefix = "/api/v1"
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Server serves the API for one project database
+type Server struct {
+	DB *database.DB
+	// AnonymousRole is the role of requests without a token or session once
+	// users exist; empty requires authentication
+	AnonymousRole string
+	// SessionTTL is how long a login lasts; zero uses DefaultSessionTTL
+	SessionTTL time.Duration
+	// Changes feeds the event stream; without it /events is unavailable
+	Changes *database.ChangeBus
+}
+
+// New returns an API server for db
+func New(db *database.DB) *Server {
+	return &Server{DB: db}
+}
+
+// Error is the body of every failed request
+type Error struct {
+	Status  int    `json:"status"`
+	Message string `json:"error"`
+}
+
+// List is the body of collection responses. Page and PerPage are set on
+// paginated collections.
+type List struct {
+	Data    interface{} `json:"data"`
+	Total   int64       `json:"total"`
+	Page    int         `json:"page,omitempty"`
+	PerPage int         `json:"per_page,omitempty"`
+}
+
+// ServeHTTP authenticates and routes requests below Prefix
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Protect(http.HandlerFunc(s.route)).ServeHTTP(w, r)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	seg, err := segments(r.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid path: %v", err)
+		return
+	}
+
+	switch {
+	case len(seg) == 1 && seg[0] == "openapi.json":
+		dispatch(w, r, methods{http.MethodGet: func() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(openAPI)
+		}})
+	case len(seg) == 1 && seg[0] == "stats":
+		dispatch(w, r, methods{http.MethodGet: func() { s.getStats(w) }})
+	case len(seg) == 1 && seg[0] == "events":
+		dispatch(w, r, methods{http.MethodGet: func() { s.streamEvents(w, r) }})
+	case len(seg) > 0 && seg[0] == "todos":
+		s.routeTODOs(w, r, seg[1:])
+	case len(seg) > 0 && seg[0] == "tags":
+		s.routeTags(w, r, seg[1:])
+	case len(seg) == 2 && seg[0] == "relationships":
+		s.routeRelationship(w, r, seg[1])
+	case len(seg) > 0 && seg[0] == "filters":
+		s.routeFilters(w, r, seg[1:])
+	case len(seg) == 3 && seg[0] == "users" && seg[2] == "watches":
+		dispatch(w, r, methods{http.MethodGet: func() { s.listWatched(w, r, seg[1]) }})
+	case len(seg) > 0 && seg[0] == "users":
+		s.routeUsers(w, r, seg[1:])
+	case len(seg) == 1 && seg[0] == "session":
+		s.routeSession(w, r)
+	case len(seg) > 0 && seg[0] == "tokens":
+		s.routeTokens(w, r, seg[1:])
+	case len(seg) == 1 && seg[0] == "audit":
+		dispatch(w, r, methods{http.MethodGet: func() { s.listAudit(w, r) }})
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	}
+}
+
+// segments splits the path below Prefix into unescaped segments
+func segments(u *url.URL) ([]string, error) {
+	path := strings.Trim(strings.TrimPrefix(u.EscapedPath(), Prefix), "/")
+	if path == "" {
+		return nil, nil
+	}
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		unescaped, err := url.PathUnescape(p)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = unescaped
+	}
+	return parts, nil
+}
+
+// methods maps HTTP methods to their handlers for one route
+type methods map[string]func()
+
+// dispatch runs the handler for the request method, answering 405 with the
+// allowed methods otherwise
+func dispatch(w http.ResponseWriter, r *http.Request, m methods) {
+	if h, ok := m[r.Method]; ok {
+		h()
+		return
+	}
+	allowed := make([]string, 0, len(m))
+	for method := range m {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, Error{Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// writeDBError answers 404 for missing records and 500 for other failures
+func writeDBError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, "%s not found", what)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "%v", err)
+}
+
+// decode reads a JSON request body into v
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return false
+	}
+	return true
+}
+
+// Stats summarises the TODOs of the project
+type Stats struct {
+	Total      int64            `json:"total"`
+	Overdue    int              `json:"overdue"`
+	ByStatus   map[string]int64 `json:"by_status"`
+	ByType     map[string]int64 `json:"by_type"`
+	ByPriority map[string]int64 `json:"by_priority"`
+}
+
+func (s *Server) getStats(w http.ResponseWriter) {
+	stats, err := s.DB.GetStats()
+	if err != nil {
+		writeDBError(w, err, "stats")
+		return
+	}
+	overdue, err := s.DB.GetOverdueTODOs()
+	if err != nil {
+		writeDBError(w, err, "stats")
+		return
+	}
+	writeJSON(w, http.StatusOK, Stats{
+		Total:      stats["total"].(int64),
+		Overdue:    len(overdue),
+		ByStatus:   stats["by_status"].(map[string]int64),
+		ByType:     stats["by_type"].(map[string]int64),
+		ByPriority: stats["by_priority"].(map[string]int64),
+	})
+}
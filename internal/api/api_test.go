@@ -0,0 +1,339 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (*Server, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+
+	past := time.Now().AddDate(0, 0, -3)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 1, Type: "TODO", Content: "add retries", Status: "open", Priority: "P1", Assignee: "alice", Hash: "a"},
+		{FilePath: "/p/a.go", LineNumber: 9, Type: "FIXME", Content: "fix parser", Status: "in_progress", Priority: "P0", Hash: "b", DueDate: &past},
+		{FilePath: "/p/b.go", LineNumber: 4, Type: "BUG", Content: "crash on empty input", Status: "resolved", Priority: "P2", Assignee: "bob", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	return New(db), todos
+}
+
+func request(t *testing.T, s *Server, method, path, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeList(t *testing.T, rec *httptest.ResponseRecorder, items interface{}) List {
+	var raw struct {
+		List
+		Data json.RawMessage `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+	require.NoError(t, json.Unmarshal(raw.Data, items))
+	return raw.List
+}
+
+func TestListTODOs(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var todos []database.TODO
+	list := decodeList(t, request(t, s, "GET", "/api/v1/todos?per_page=2", ""), &todos)
+	assert.Equal(t, int64(3), list.Total)
+	assert.Equal(t, 1, list.Page)
+	require.Len(t, todos, 2)
+	assert.Equal(t, 1, todos[0].LineNumber)
+	assert.Equal(t, 9, todos[1].LineNumber)
+
+	list = decodeList(t, request(t, s, "GET", "/api/v1/todos?per_page=2&page=2", ""), &todos)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "/p/b.go", todos[0].FilePath)
+
+	decodeList(t, request(t, s, "GET", "/api/v1/todos?sort=priority", ""), &todos)
+	assert.Equal(t, "P0", todos[0].Priority)
+	decodeList(t, request(t, s, "GET", "/api/v1/todos?sort=-priority", ""), &todos)
+	assert.Equal(t, "P2", todos[0].Priority)
+
+	var fields []map[string]interface{}
+	decodeList(t, request(t, s, "GET", "/api/v1/todos?fields=id,due_date&per_page=1", ""), &fields)
+	require.Len(t, fields, 1)
+	assert.Len(t, fields[0], 2)
+	assert.Contains(t, fields[0], "due_date")
+
+	for _, bad := range []string{"sort=secret", "fields=password", "page=0", "per_page=10000", "overdue=maybe", "due_before=soon"} {
+		rec := request(t, s, "GET", "/api/v1/todos?"+bad, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, bad)
+	}
+}
+
+func TestListTODOsFilters(t *testing.T) {
+	s, todos := newTestServer(t)
+	require.NoError(t, s.DB.AddTagToTODO(todos[2].ID, mustTag(t, s, "backend")))
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"status=open,in_progress", 2},
+		{"assignee=bob", 1},
+		{"type=BUG", 1},
+		{"file=b.go", 1},
+		{"q=PARSER", 1},
+		{"tag=backend", 1},
+		{"overdue=true", 1},
+		{"overdue=false", 2},
+		{"due_before=2100-01-01", 1},
+		{"removed=false", 3},
+	}
+	for _, tt := range tests {
+		var found []database.TODO
+		list := decodeList(t, request(t, s, "GET", "/api/v1/todos?"+tt.query, ""), &found)
+		assert.Equal(t, int64(tt.want), list.Total, tt.query)
+		assert.Len(t, found, tt.want, tt.query)
+	}
+}
+
+func mustTag(t *testing.T, s *Server, name string) string {
+	tag, err := s.DB.GetOrCreateTag(name)
+	require.NoError(t, err)
+	return tag.ID
+}
+
+func TestTODOResource(t *testing.T) {
+	s, todos := newTestServer(t)
+	path := "/api/v1/todos/" + todos[1].ID
+
+	rec := request(t, s, "GET", path+"?fields=content", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"content":"fix parser"}`, rec.Body.String())
+
+	rec = request(t, s, "PATCH", path, `{"status":"blocked","assignee":"carol","due_date":null,"estimate":30}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	updated, err := s.DB.GetTODOByID(todos[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "blocked", updated.Status)
+	assert.Equal(t, "carol", updated.Assignee)
+	assert.Nil(t, updated.DueDate)
+	require.NotNil(t, updated.Estimate)
+	assert.Equal(t, 30, *updated.Estimate)
+
+	rec = request(t, s, "PATCH", path, `{"due_date":"2030-05-01"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	updated, _ = s.DB.GetTODOByID(todos[1].ID)
+	require.NotNil(t, updated.DueDate)
+	assert.Equal(t, "2030-05-01", updated.DueDate.Format("2006-01-02"))
+
+	for _, bad := range []string{`{"status":"done"}`, `{"priority":"P9"}`, `{"hash":"x"}`, `{"due_date":"tomorrow"}`, `not json`} {
+		rec = request(t, s, "PATCH", path, bad)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, bad)
+	}
+
+	rec = request(t, s, "DELETE", path, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = request(t, s, "GET", path, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var body Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusNotFound, body.Status)
+	assert.Contains(t, body.Message, "not found")
+}
+
+func TestRouting(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	assert.Equal(t, http.StatusNotFound, request(t, s, "GET", "/api/v1/nothing", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(t, s, "GET", "/api/v1/todos/x/unknown", "").Code)
+
+	rec := request(t, s, "POST", "/api/v1/todos", "{}")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET", rec.Header().Get("Allow"))
+
+	rec = request(t, s, "PUT", "/api/v1/todos/x", "{}")
+	assert.Equal(t, "DELETE, GET, PATCH", rec.Header().Get("Allow"))
+}
+
+func TestTags(t *testing.T) {
+	s, todos := newTestServer(t)
+	path := "/api/v1/todos/" + todos[0].ID + "/tags"
+
+	assert.Equal(t, http.StatusCreated, request(t, s, "POST", "/api/v1/tags", `{"name":"ui"}`).Code)
+	assert.Equal(t, http.StatusConflict, request(t, s, "POST", "/api/v1/tags", `{"name":"ui"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(t, s, "POST", "/api/v1/tags", `{"name":" "}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, request(t, s, "PUT", path+"/ui", "").Code)
+	assert.Equal(t, http.StatusNoContent, request(t, s, "PUT", path+"/tech%20debt", "").Code)
+	assert.Equal(t, http.StatusNoContent, request(t, s, "PUT", path+"/ui", "").Code)
+
+	var tags []database.Tag
+	decodeList(t, request(t, s, "GET", path, ""), &tags)
+	assert.Len(t, tags, 2)
+
+	var counts []TagCount
+	decodeList(t, request(t, s, "GET", "/api/v1/tags", ""), &counts)
+	require.Len(t, counts, 2)
+	assert.Equal(t, TagCount{ID: counts[0].ID, Name: "tech debt", Count: 1}, counts[0])
+
+	assert.Equal(t, http.StatusNoContent, request(t, s, "DELETE", path+"/ui", "").Code)
+	assert.Equal(t, http.StatusNoContent, request(t, s, "DELETE", "/api/v1/tags/tech%20debt", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(t, s, "DELETE", "/api/v1/tags/tech%20debt", "").Code)
+	decodeList(t, request(t, s, "GET", path, ""), &tags)
+	assert.Empty(t, tags)
+}
+
+func TestRelationships(t *testing.T) {
+	s, todos := newTestServer(t)
+	a, b := todos[0].ID, todos[1].ID
+
+	rec := request(t, s, "POST", "/api/v1/todos/"+a+"/relationships", `{"type":"depends_on","target_id":"`+b+`"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var rel database.Relationship
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rel))
+
+	blockers, err := s.DB.GetBlockers(b)
+	require.NoError(t, err)
+	require.Len(t, blockers, 1)
+	assert.Equal(t, a, blockers[0].ID)
+
+	rec = request(t, s, "POST", "/api/v1/todos/"+a+"/relationships", `{"type":"depends_on","target_id":"`+b+`"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = request(t, s, "POST", "/api/v1/todos/"+b+"/relationships", `{"type":"depends_on","target_id":"`+a+`"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = request(t, s, "POST", "/api/v1/todos/"+a+"/relationships", `{"type":"blocked_by","target_id":"`+b+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = request(t, s, "POST", "/api/v1/todos/"+a+"/relationships", `{"type":"relates_to","target_id":"missing"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var rels []database.Relationship
+	decodeList(t, request(t, s, "GET", "/api/v1/todos/"+a+"/relationships", ""), &rels)
+	assert.Len(t, rels, 2)
+
+	assert.Equal(t, http.StatusNoContent, request(t, s, "DELETE", "/api/v1/relationships/"+rel.ID, "").Code)
+	decodeList(t, request(t, s, "GET", "/api/v1/todos/"+a+"/relationships", ""), &rels)
+	assert.Empty(t, rels)
+	assert.Equal(t, http.StatusNotFound, request(t, s, "GET", "/api/v1/relationships/"+rel.ID, "").Code)
+}
+
+func TestWatches(t *testing.T) {
+	s, todos := newTestServer(t)
+	path := "/api/v1/todos/" + todos[0].ID + "/watchers/alice"
+
+	assert.Equal(t, http.StatusNoContent, request(t, s, "PUT", path, "").Code)
+	assert.Equal(t, http.StatusNoContent, request(t, s, "PUT", path, "").Code)
+
+	var watches []database.Watch
+	decodeList(t, request(t, s, "GET", "/api/v1/todos/"+todos[0].ID+"/watchers", ""), &watches)
+	assert.Len(t, watches, 1)
+
+	var watched []database.TODO
+	decodeList(t, request(t, s, "GET", "/api/v1/users/alice/watches", ""), &watched)
+	require.Len(t, watched, 1)
+	assert.Equal(t, todos[0].ID, watched[0].ID)
+
+	assert.Equal(t, http.StatusNoContent, request(t, s, "DELETE", path, "").Code)
+	assert.Equal(t, http.StatusNotFound, request(t, s, "DELETE", path, "").Code)
+}
+
+func TestTimeTracking(t *testing.T) {
+	s, todos := newTestServer(t)
+	path := "/api/v1/todos/" + todos[0].ID + "/time"
+
+	assert.Equal(t, http.StatusCreated, request(t, s, "POST", path, `{"minutes":45,"description":"review"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(t, s, "POST", path, `{"minutes":0}`).Code)
+
+	assert.Equal(t, http.StatusConflict, request(t, s, "POST", path+"/stop", "").Code)
+	assert.Equal(t, http.StatusCreated, request(t, s, "POST", path+"/start", "").Code)
+	assert.Equal(t, http.StatusConflict, request(t, s, "POST", path+"/start", `{"description":"again"}`).Code)
+
+	rec := request(t, s, "GET", path, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var summary TimeSummary
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 45, summary.TotalMinutes)
+	assert.Len(t, summary.Entries, 2)
+	assert.NotNil(t, summary.Running)
+
+	assert.Equal(t, http.StatusOK, request(t, s, "POST", path+"/stop", "").Code)
+}
+
+func TestSavedFilters(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := request(t, s, "POST", "/api/v1/filters", `{"name":"mine","query":"assignee=alice&status=open"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, http.StatusConflict, request(t, s, "POST", "/api/v1/filters", `{"name":"mine"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(t, s, "POST", "/api/v1/filters", `{"name":"bad","query":"color=red"}`).Code)
+
+	var found []database.TODO
+	list := decodeList(t, request(t, s, "GET", "/api/v1/filters/mine/todos", ""), &found)
+	assert.Equal(t, int64(1), list.Total)
+	decodeList(t, request(t, s, "GET", "/api/v1/filters/mine/todos?type=BUG", ""), &found)
+	assert.Empty(t, found)
+
+	var filters []database.SavedFilter
+	decodeList(t, request(t, s, "GET", "/api/v1/filters", ""), &filters)
+	assert.Len(t, filters, 1)
+
+	assert.Equal(t, http.StatusNoContent, request(t, s, "DELETE", "/api/v1/filters/mine", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(t, s, "GET", "/api/v1/filters/mine/todos", "").Code)
+}
+
+func TestStats(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := request(t, s, "GET", "/api/v1/stats", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, int64(3), stats.Total)
+	assert.Equal(t, 1, stats.Overdue)
+	assert.Equal(t, int64(1), stats.ByType["BUG"])
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	s, _ := newTestServer(t)
+	hub := NewHub(s)
+	hub.Add("x", "/x", s)
+
+	rec := request(t, s, "GET", "/api/v1/openapi.json", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var doc struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	// Every documented operation is routed
+	for path, ops := range doc.Paths {
+		concrete := strings.NewReplacer("{id}", "x", "{name}", "x", "{user}", "x").Replace(path)
+		for method := range ops {
+			if method == "parameters" {
+				continue
+			}
+			rec := httptest.NewRecorder()
+			hub.ServeHTTP(rec, httptest.NewRequest(strings.ToUpper(method), Prefix+concrete, nil))
+			assert.NotEqual(t, http.StatusMethodNotAllowed, rec.Code, "%s %s", method, path)
+			if rec.Code == http.StatusNotFound {
+				assert.NotContains(t, rec.Body.String(), "no such endpoint", "%s %s", method, path)
+			}
+		}
+	}
+}
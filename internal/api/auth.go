@@ -0,0 +1,570 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"gorm.io/gorm"
+)
+
+// SessionCookie holds the session of a browser login
+const SessionCookie = "todo_session"
+
+// DefaultSessionTTL is how long a login lasts unless configured otherwise
+const DefaultSessionTTL = 24 * time.Hour
+
+// maxAuditDetails caps the request body kept in the audit trail
+const maxAuditDetails = 4096
+
+// Identity is who made a request
+type Identity struct {
+	// User is nil for anonymous requests and while no users exist
+	User *database.User
+	Role string
+}
+
+// Name returns the user name recorded in the audit trail
+func (i Identity) Name() string {
+	if i.User == nil {
+		return "anonymous"
+	}
+	return i.User.Name
+}
+
+type identityKey struct{}
+
+// IdentityFrom returns the identity Protect attached to a request
+func IdentityFrom(r *http.Request) Identity {
+	id, _ := r.Context().Value(identityKey{}).(Identity)
+	return id
+}
+
+// Protect authenticates requests to next from an API token or session
+// cookie. Reading requires the viewer role and changing TODOs the
+// contributor role; changes are recorded in the audit trail. Until the
+// first user is created every request is treated as an admin.
+func (s *Server) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if public(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := s.identify(r)
+		if errors.Is(err, database.ErrInvalidCredentials) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid API token")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+
+		if !database.RoleAllows(id.Role, requiredRole(r)) {
+			if id.User == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "authentication required")
+			} else {
+				writeError(w, http.StatusForbidden, "the %s role cannot do this", id.Role)
+			}
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), identityKey{}, id))
+		if readOnly(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		details := auditDetails(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		s.audit(id, r, rec.status, details)
+	})
+}
+
+// public reports requests allowed without authentication: the API
+// description, logging in and logging out
+func public(r *http.Request) bool {
+	switch r.URL.Path {
+	case Prefix + "/openapi.json":
+		return r.Method == http.MethodGet
+	case Prefix + "/session":
+		return r.Method == http.MethodPost || r.Method == http.MethodDelete
+	}
+	return false
+}
+
+// requiredRole is the least role allowed to make a request. Anyone signed
+// in may manage their own tokens and password; the handlers check
+// ownership.
+func requiredRole(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, Prefix)
+	switch {
+	case readOnly(r.Method):
+		return database.RoleViewer
+	case path == "/tokens" || strings.HasPrefix(path, "/tokens/"):
+		return database.RoleViewer
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/users/"):
+		return database.RoleViewer
+	}
+	return database.RoleContributor
+}
+
+func readOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// identify works out who made a request. A bad token is an error; a stale
+// session cookie is ignored so the browser can log in again.
+func (s *Server) identify(r *http.Request) (Identity, error) {
+	hasUsers, err := s.DB.HasUsers()
+	if err != nil {
+		return Identity{}, err
+	}
+	if !hasUsers {
+		return Identity{Role: database.RoleAdmin}, nil
+	}
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		raw, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return Identity{}, database.ErrInvalidCredentials
+		}
+		user, err := s.DB.UserForToken(strings.TrimSpace(raw))
+		if err != nil {
+			return Identity{}, err
+		}
+		return Identity{User: user, Role: user.Role}, nil
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		user, err := s.DB.UserForSession(cookie.Value)
+		if err == nil {
+			return Identity{User: user, Role: user.Role}, nil
+		}
+		if !errors.Is(err, database.ErrInvalidCredentials) {
+			return Identity{}, err
+		}
+	}
+	return Identity{Role: s.AnonymousRole}, nil
+}
+
+// requireRole answers 403 unless the request has at least role
+func requireRole(w http.ResponseWriter, r *http.Request, role string) bool {
+	id := IdentityFrom(r)
+	if !database.RoleAllows(id.Role, role) {
+		writeError(w, http.StatusForbidden, "the %s role cannot do this", id.Role)
+		return false
+	}
+	return true
+}
+
+// requireUser answers 400 while the server runs without users
+func requireUser(w http.ResponseWriter, r *http.Request) (*database.User, bool) {
+	user := IdentityFrom(r).User
+	if user == nil {
+		writeError(w, http.StatusBadRequest, "no user is signed in; create one with 'todo user add'")
+		return nil, false
+	}
+	return user, true
+}
+
+func (s *Server) audit(id Identity, r *http.Request, status int, details string) {
+	entry := &database.AuditEntry{
+		User:    id.Name(),
+		Role:    id.Role,
+		Method:  r.Method,
+		Path:    r.URL.RequestURI(),
+		Status:  status,
+		Details: details,
+	}
+	// Failing to audit must not fail a change that already happened
+	_ = s.DB.AddAuditEntry(entry)
+}
+
+// auditDetails reads the JSON body of a request for the audit trail,
+// leaving it in place for the handler. Passwords are dropped.
+func auditDetails(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) == nil {
+		delete(fields, "password")
+		body, _ = json.Marshal(fields)
+	}
+	if len(body) > maxAuditDetails {
+		body = body[:maxAuditDetails]
+	}
+	return string(body)
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// SessionInfo describes the caller
+type SessionInfo struct {
+	User         *database.User `json:"user,omitempty"`
+	Role         string         `json:"role"`
+	AuthRequired bool           `json:"auth_required"`
+}
+
+// routeSession routes /session: who am I, log in and log out
+func (s *Server) routeSession(w http.ResponseWriter, r *http.Request) {
+	dispatch(w, r, methods{
+		http.MethodGet: func() {
+			id := IdentityFrom(r)
+			hasUsers, err := s.DB.HasUsers()
+			if err != nil {
+				writeDBError(w, err, "users")
+				return
+			}
+			writeJSON(w, http.StatusOK, SessionInfo{User: id.User, Role: id.Role, AuthRequired: hasUsers})
+		},
+		http.MethodPost:   func() { s.login(w, r) },
+		http.MethodDelete: func() { s.logout(w, r) },
+	})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	user, err := s.DB.Login(body.Name, body.Password)
+	status := http.StatusCreated
+	switch {
+	case errors.Is(err, database.ErrInvalidCredentials):
+		status = http.StatusUnauthorized
+		writeError(w, status, "invalid name or password")
+	case err != nil:
+		status = http.StatusInternalServerError
+		writeError(w, status, "%v", err)
+	}
+	if err != nil {
+		s.audit(Identity{User: &database.User{Name: body.Name}}, r, status, "")
+		return
+	}
+
+	ttl := s.SessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	raw, session, err := s.DB.CreateSession(user.ID, ttl)
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    raw,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Keeps other sites from making requests with the session
+		SameSite: http.SameSiteStrictMode,
+	})
+	s.audit(Identity{User: user, Role: user.Role}, r, status, "")
+	writeJSON(w, status, SessionInfo{User: user, Role: user.Role, AuthRequired: true})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		if err := s.DB.DeleteSession(cookie.Value); err != nil {
+			writeDBError(w, err, "session")
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// NewToken is returned once when a token is created
+type NewToken struct {
+	database.APIToken
+	Token string `json:"token"`
+}
+
+// routeTokens routes /tokens and /tokens/{id}
+func (s *Server) routeTokens(w http.ResponseWriter, r *http.Request, seg []string) {
+	switch len(seg) {
+	case 0:
+		dispatch(w, r, methods{
+			http.MethodGet:  func() { s.listTokens(w, r) },
+			http.MethodPost: func() { s.createToken(w, r) },
+		})
+	case 1:
+		dispatch(w, r, methods{http.MethodDelete: func() { s.revokeToken(w, r, seg[0]) }})
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	}
+}
+
+// listTokens lists the caller's tokens; admins see every token
+func (s *Server) listTokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	owner := user.ID
+	if user.Role == database.RoleAdmin {
+		owner = ""
+	}
+	tokens, err := s.DB.GetTokens(owner)
+	if err != nil {
+		writeDBError(w, err, "tokens")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: nonNil(tokens), Total: int64(len(tokens))})
+}
+
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	raw, token, err := s.DB.CreateToken(user.ID, body.Name)
+	if err != nil {
+		writeDBError(w, err, "token")
+		return
+	}
+	writeJSON(w, http.StatusCreated, NewToken{APIToken: *token, Token: raw})
+}
+
+// revokeToken deletes one of the caller's tokens; admins may revoke any
+func (s *Server) revokeToken(w http.ResponseWriter, r *http.Request, id string) {
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	token, err := s.DB.GetToken(id)
+	if err == nil && token.UserID != user.ID && user.Role != database.RoleAdmin {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		writeDBError(w, err, "token "+id)
+		return
+	}
+	if err := s.DB.RevokeToken(id); err != nil {
+		writeDBError(w, err, "token "+id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// routeUsers routes /users and /users/{name}
+func (s *Server) routeUsers(w http.ResponseWriter, r *http.Request, seg []string) {
+	switch len(seg) {
+	case 0:
+		dispatch(w, r, methods{
+			http.MethodGet:  func() { s.listUsers(w, r) },
+			http.MethodPost: func() { s.createUser(w, r) },
+		})
+	case 1:
+		dispatch(w, r, methods{
+			http.MethodGet:    func() { s.getUser(w, r, seg[0]) },
+			http.MethodPatch:  func() { s.updateUser(w, r, seg[0]) },
+			http.MethodDelete: func() { s.deleteUser(w, r, seg[0]) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	}
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, database.RoleAdmin) {
+		return
+	}
+	users, err := s.DB.GetUsers()
+	if err != nil {
+		writeDBError(w, err, "users")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: nonNil(users), Total: int64(len(users))})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string `json:"name"`
+		Role     string `json:"role"`
+		Password string `json:"password"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if !requireRole(w, r, database.RoleAdmin) {
+		return
+	}
+	if body.Name = strings.TrimSpace(body.Name); body.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if !database.ValidRole(body.Role) {
+		writeError(w, http.StatusBadRequest, "role must be one of %v", database.Roles)
+		return
+	}
+	if _, err := s.DB.GetUser(body.Name); err == nil {
+		writeError(w, http.StatusConflict, "user %s already exists", body.Name)
+		return
+	}
+	user, err := s.DB.CreateUser(body.Name, body.Role, body.Password)
+	if err != nil {
+		writeDBError(w, err, "user "+body.Name)
+		return
+	}
+	writeJSON(w, http.StatusCreated, user)
+}
+
+// getUser returns a user to admins and to the user themselves
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request, name string) {
+	if self := IdentityFrom(r).User; self == nil || self.Name != name {
+		if !requireRole(w, r, database.RoleAdmin) {
+			return
+		}
+	}
+	user, err := s.DB.GetUser(name)
+	if err != nil {
+		writeDBError(w, err, "user "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// updateUser changes the role or password of a user. Users may change
+// their own password; everything else needs an admin.
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request, name string) {
+	var body struct {
+		Role     *string `json:"role"`
+		Password *string `json:"password"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if self := IdentityFrom(r).User; self == nil || self.Name != name || body.Role != nil {
+		if !requireRole(w, r, database.RoleAdmin) {
+			return
+		}
+	}
+	user, err := s.DB.GetUser(name)
+	if err != nil {
+		writeDBError(w, err, "user "+name)
+		return
+	}
+
+	if body.Role != nil {
+		if !database.ValidRole(*body.Role) {
+			writeError(w, http.StatusBadRequest, "role must be one of %v", database.Roles)
+			return
+		}
+		if user.Role == database.RoleAdmin && *body.Role != database.RoleAdmin && !s.otherAdmins(w) {
+			return
+		}
+		user.Role = *body.Role
+	}
+	if body.Password != nil {
+		if err := user.SetPassword(*body.Password); err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+	}
+	if err := s.DB.UpdateUser(user); err != nil {
+		writeDBError(w, err, "user "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, name string) {
+	if !requireRole(w, r, database.RoleAdmin) {
+		return
+	}
+	user, err := s.DB.GetUser(name)
+	if err != nil {
+		writeDBError(w, err, "user "+name)
+		return
+	}
+	if user.Role == database.RoleAdmin && !s.otherAdmins(w) {
+		return
+	}
+	if err := s.DB.DeleteUser(user); err != nil {
+		writeDBError(w, err, "user "+name)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// otherAdmins answers 409 when the last admin would be removed, which would
+// lock everyone out of user management
+func (s *Server) otherAdmins(w http.ResponseWriter) bool {
+	admins, err := s.DB.CountAdmins()
+	if err != nil {
+		writeDBError(w, err, "users")
+		return false
+	}
+	if admins <= 1 {
+		writeError(w, http.StatusConflict, "cannot remove the last admin")
+		return false
+	}
+	return true
+}
+
+// listAudit pages through the audit trail, newest first
+func (s *Server) listAudit(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, database.RoleAdmin) {
+		return
+	}
+	page, perPage, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	query := s.DB.Model(&database.AuditEntry{})
+	if user := r.URL.Query().Get("user"); user != "" {
+		query = query.Where("user = ?", user)
+	}
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		writeDBError(w, err, "audit trail")
+		return
+	}
+	entries := []database.AuditEntry{}
+	if err := query.Order("created_at DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&entries).Error; err != nil {
+		writeDBError(w, err, "audit trail")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: entries, Total: total, Page: page, PerPage: perPage})
+}
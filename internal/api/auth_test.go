@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// as sends a request with a bearer token or session cookie
+func as(t *testing.T, s *Server, credential, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	switch {
+	case strings.HasPrefix(credential, "todo_"):
+		req.Header.Set("Authorization", "Bearer "+credential)
+	case credential != "":
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: credential})
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func newUser(t *testing.T, s *Server, name, role, password string) string {
+	user, err := s.DB.CreateUser(name, role, password)
+	require.NoError(t, err)
+	token, _, err := s.DB.CreateToken(user.ID, "test")
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticationOffWithoutUsers(t *testing.T) {
+	s, todos := newTestServer(t)
+
+	rec := request(t, s, "PATCH", "/api/v1/todos/"+todos[0].ID, `{"status":"blocked"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	entries, err := s.DB.GetAuditEntries("", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "anonymous", entries[0].User)
+	assert.Equal(t, "PATCH", entries[0].Method)
+	assert.JSONEq(t, `{"status":"blocked"}`, entries[0].Details)
+}
+
+func TestRoles(t *testing.T) {
+	s, todos := newTestServer(t)
+	path := "/api/v1/todos/" + todos[0].ID
+	newUser(t, s, "alice", database.RoleAdmin, "")
+	viewer := newUser(t, s, "bob", database.RoleViewer, "")
+	contributor := newUser(t, s, "carol", database.RoleContributor, "")
+
+	assert.Equal(t, http.StatusUnauthorized, as(t, s, "", "GET", path, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, as(t, s, "todo_wrong", "GET", path, "").Code)
+	assert.Equal(t, http.StatusOK, as(t, s, "", "GET", "/api/v1/openapi.json", "").Code)
+
+	assert.Equal(t, http.StatusOK, as(t, s, viewer, "GET", path, "").Code)
+	assert.Equal(t, http.StatusForbidden, as(t, s, viewer, "PATCH", path, `{"status":"blocked"}`).Code)
+	assert.Equal(t, http.StatusOK, as(t, s, contributor, "PATCH", path, `{"status":"blocked"}`).Code)
+	assert.Equal(t, http.StatusForbidden, as(t, s, contributor, "GET", "/api/v1/users", "").Code)
+	assert.Equal(t, http.StatusForbidden, as(t, s, contributor, "GET", "/api/v1/audit", "").Code)
+
+	// Only the successful change is audited
+	entries, err := s.DB.GetAuditEntries("", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "carol", entries[0].User)
+	assert.Equal(t, database.RoleContributor, entries[0].Role)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+
+	s.AnonymousRole = database.RoleViewer
+	assert.Equal(t, http.StatusOK, as(t, s, "", "GET", path, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, as(t, s, "", "PATCH", path, `{}`).Code)
+}
+
+func TestSessions(t *testing.T) {
+	s, _ := newTestServer(t)
+	newUser(t, s, "alice", database.RoleAdmin, "secret")
+
+	rec := as(t, s, "", "POST", "/api/v1/session", `{"name":"alice","password":"wrong"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = as(t, s, "", "POST", "/api/v1/session", `{"name":"alice","password":"secret"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	session := cookies[0].Value
+
+	rec = as(t, s, session, "GET", "/api/v1/session", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var info SessionInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "alice", info.User.Name)
+	assert.True(t, info.AuthRequired)
+
+	assert.Equal(t, http.StatusOK, as(t, s, session, "GET", "/api/v1/users", "").Code)
+	assert.Equal(t, http.StatusNoContent, as(t, s, session, "DELETE", "/api/v1/session", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, as(t, s, session, "GET", "/api/v1/users", "").Code)
+
+	// Login attempts are audited under the name tried
+	entries, err := s.DB.GetAuditEntries("alice", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, http.StatusCreated, entries[0].Status)
+	assert.Equal(t, http.StatusUnauthorized, entries[1].Status)
+}
+
+func TestUserManagement(t *testing.T) {
+	s, _ := newTestServer(t)
+	admin := newUser(t, s, "alice", database.RoleAdmin, "")
+
+	rec := as(t, s, admin, "POST", "/api/v1/users", `{"name":"bob","role":"viewer","password":"hunter2"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "hunter2")
+	assert.Equal(t, http.StatusConflict, as(t, s, admin, "POST", "/api/v1/users", `{"name":"bob","role":"viewer"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, as(t, s, admin, "POST", "/api/v1/users", `{"name":"eve","role":"root"}`).Code)
+
+	// Passwords stay out of the audit trail
+	entries, err := s.DB.GetAuditEntries("alice", 0)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Details, "hunter2")
+	}
+
+	// Users may change their own password but not their role
+	_, err = s.DB.Login("bob", "hunter2")
+	require.NoError(t, err)
+	bob, err := s.DB.GetUser("bob")
+	require.NoError(t, err)
+	bobToken, _, err := s.DB.CreateToken(bob.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, as(t, s, bobToken, "PATCH", "/api/v1/users/bob", `{"password":"changed"}`).Code)
+	assert.Equal(t, http.StatusForbidden, as(t, s, bobToken, "PATCH", "/api/v1/users/bob", `{"role":"admin"}`).Code)
+	_, err = s.DB.Login("bob", "changed")
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusConflict, as(t, s, admin, "PATCH", "/api/v1/users/alice", `{"role":"viewer"}`).Code)
+	assert.Equal(t, http.StatusConflict, as(t, s, admin, "DELETE", "/api/v1/users/alice", "").Code)
+	assert.Equal(t, http.StatusNoContent, as(t, s, admin, "DELETE", "/api/v1/users/bob", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, as(t, s, bobToken, "GET", "/api/v1/todos", "").Code)
+
+	var audit []database.AuditEntry
+	rec = as(t, s, admin, "GET", "/api/v1/audit?per_page=2", "")
+	list := decodeList(t, rec, &audit)
+	assert.Len(t, audit, 2)
+	assert.Greater(t, list.Total, int64(2))
+}
+
+func TestTokens(t *testing.T) {
+	s, _ := newTestServer(t)
+	admin := newUser(t, s, "alice", database.RoleAdmin, "")
+	viewer := newUser(t, s, "bob", database.RoleViewer, "")
+
+	rec := as(t, s, viewer, "POST", "/api/v1/tokens", `{"name":"ci"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created NewToken
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Token, "todo_"))
+	assert.Equal(t, http.StatusOK, as(t, s, created.Token, "GET", "/api/v1/todos", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, as(t, s, created.Token, "PATCH", "/api/v1/todos/x", `{}`).Code)
+
+	var tokens []database.APIToken
+	decodeList(t, as(t, s, viewer, "GET", "/api/v1/tokens", ""), &tokens)
+	assert.Len(t, tokens, 2)
+	decodeList(t, as(t, s, admin, "GET", "/api/v1/tokens", ""), &tokens)
+	assert.Len(t, tokens, 3)
+
+	// Only owners and admins may revoke a token
+	adminTokens, err := s.DB.GetTokens(tokens[0].UserID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, as(t, s, viewer, "DELETE", "/api/v1/tokens/"+adminTokens[0].ID, "").Code)
+	assert.Equal(t, http.StatusNoContent, as(t, s, admin, "DELETE", "/api/v1/tokens/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, as(t, s, created.Token, "GET", "/api/v1/todos", "").Code)
+}
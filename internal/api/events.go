@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+)
+
+// keepAlive is how often an idle event stream sends a comment so proxies
+// keep the connection open
+const keepAlive = 30 * time.Second
+
+// maxReplay is how many missed changes a reconnecting client is sent before
+// it is told to reload instead
+const maxReplay = 1000
+
+// Event is the data of a server-sent event
+type Event struct {
+	Seq    uint64          `json:"seq"`
+	Type   string          `json:"type"`
+	TODOID string          `json:"todo_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Time   time.Time       `json:"time"`
+}
+
+// streamEvents sends changes to the project as server-sent events. A client
+// reconnecting with Last-Event-ID first receives the changes it missed, or a
+// reset event when too many were missed to replay.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	if s.Changes == nil {
+		writeError(w, http.StatusServiceUnavailable, "event stream is not enabled")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	var since uint64
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("since")
+	}
+	if lastID != "" {
+		var err error
+		if since, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid event ID %q", lastID)
+			return
+		}
+	}
+
+	// Subscribe before replaying so nothing falls between the two
+	changes, unsubscribe := s.Changes.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if since > 0 {
+		missed, err := s.DB.GetChanges(since, maxReplay)
+		if err != nil {
+			return
+		}
+		if len(missed) == maxReplay || len(missed) > 0 && missed[0].Seq != since+1 {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		} else {
+			for _, c := range missed {
+				writeEvent(w, c)
+				since = c.Seq
+			}
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case c, ok := <-changes:
+			// A closed channel means this client fell behind; it reconnects
+			// and catches up from its last event
+			if !ok {
+				return
+			}
+			if c.Seq <= since {
+				continue
+			}
+			writeEvent(w, c)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, c database.Change) {
+	data, err := json.Marshal(Event{
+		Seq:    c.Seq,
+		Type:   c.Type,
+		TODOID: c.TODOID,
+		Data:   json.RawMessage(c.Data),
+		Time:   c.CreatedAt,
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.Seq, c.Type, data)
+}
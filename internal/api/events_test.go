@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents returns the events of a server-sent event stream as they arrive
+func readEvents(t *testing.T, url, lastID string) <-chan Event {
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan Event, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var typ string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				typ = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				var e Event
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+				e.Type = typ
+				events <- e
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	s, todos := newTestServer(t)
+	assert.Equal(t, http.StatusServiceUnavailable, request(t, s, "GET", "/api/v1/events", "").Code)
+
+	var err error
+	s.Changes, err = s.DB.NewChangeBus(10 * time.Millisecond)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Changes.Run(ctx)
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	// A reconnecting client first gets what it missed
+	events := readEvents(t, srv.URL+"/api/v1/events", "1")
+	for _, todo := range todos[1:] {
+		e := nextEvent(t, events)
+		assert.Equal(t, database.ChangeTODOCreated, e.Type)
+		assert.Equal(t, todo.ID, e.TODOID)
+	}
+
+	rec := request(t, s, "PATCH", "/api/v1/todos/"+todos[0].ID, `{"status":"blocked"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	e := nextEvent(t, events)
+	assert.Equal(t, database.ChangeTODOUpdated, e.Type)
+	assert.Equal(t, todos[0].ID, e.TODOID)
+	var updated database.TODO
+	require.NoError(t, json.Unmarshal(e.Data, &updated))
+	assert.Equal(t, "blocked", updated.Status)
+
+	rec = request(t, s, "POST", "/api/v1/todos/"+todos[0].ID+"/time/start", "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, database.ChangeTimerStarted, nextEvent(t, events).Type)
+}
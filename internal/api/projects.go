@@ -0,0 +1,237 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+)
+
+// AllProjects is the project name that queries every project at once
+const AllProjects = "*"
+
+// Hub serves the API of several projects: the default project at the top
+// level and each registered project below /projects/{name}. Queries below
+// /projects/* cover every project the caller may read.
+type Hub struct {
+	Default *Server
+
+	projects map[string]hubProject
+}
+
+type hubProject struct {
+	path   string
+	server *Server
+}
+
+// ProjectSummary describes a project served by a Hub
+type ProjectSummary struct {
+	Name        string     `json:"name"`
+	Path        string     `json:"path"`
+	Total       int64      `json:"total"`
+	Open        int64      `json:"open"`
+	LastScanned *time.Time `json:"last_scanned,omitempty"`
+}
+
+// ProjectTODO is a TODO returned by a query across projects
+type ProjectTODO struct {
+	Project string `json:"project"`
+	database.TODO
+}
+
+// NewHub returns a hub serving def at the top level
+func NewHub(def *Server) *Hub {
+	return &Hub{Default: def, projects: make(map[string]hubProject)}
+}
+
+// Add serves the project at path under name
+func (h *Hub) Add(name, path string, s *Server) {
+	h.projects[name] = hubProject{path: path, server: s}
+}
+
+// ServeHTTP routes requests below /projects to their project and all others
+// to the default project
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	seg, err := segments(r.URL)
+	if err != nil || len(seg) == 0 || seg[0] != "projects" {
+		h.Default.ServeHTTP(w, r)
+		return
+	}
+
+	switch {
+	case len(seg) == 1:
+		dispatch(w, r, methods{http.MethodGet: func() { h.listProjects(w, r) }})
+	case len(seg) == 3 && seg[1] == AllProjects && seg[2] == "todos":
+		dispatch(w, r, methods{http.MethodGet: func() { h.listAllTODOs(w, r) }})
+	case seg[1] == AllProjects:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	default:
+		p, ok := h.projects[seg[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "project %s not found", seg[1])
+			return
+		}
+		// The project's server sees the request as if made at the top
+		// level. Project names need no escaping.
+		r2 := r.Clone(r.Context())
+		r2.URL.RawPath = Prefix + strings.TrimPrefix(r.URL.EscapedPath(), Prefix+"/projects/"+seg[1])
+		r2.URL.Path, _ = url.PathUnescape(r2.URL.RawPath)
+		p.server.ServeHTTP(w, r2)
+	}
+}
+
+// readable returns the projects the caller may read, sorted by name.
+// Credentials belong to one project, so each project decides for itself.
+func (h *Hub) readable(r *http.Request) []string {
+	var names []string
+	for name, p := range h.projects {
+		id, err := p.server.identify(r)
+		if err == nil && database.RoleAllows(id.Role, database.RoleViewer) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (h *Hub) listProjects(w http.ResponseWriter, r *http.Request) {
+	summaries := []ProjectSummary{}
+	for _, name := range h.readable(r) {
+		p := h.projects[name]
+		summary := ProjectSummary{Name: name, Path: p.path}
+		db := p.server.DB
+		if err := db.Model(&database.TODO{}).Count(&summary.Total).Error; err != nil {
+			writeDBError(w, err, "projects")
+			return
+		}
+		if err := db.Model(&database.TODO{}).Where("status = ?", "open").Count(&summary.Open).Error; err != nil {
+			writeDBError(w, err, "projects")
+			return
+		}
+		if project, err := db.GetProjectByPath(p.path); err == nil {
+			summary.LastScanned = project.LastScanned
+		}
+		summaries = append(summaries, summary)
+	}
+	writeJSON(w, http.StatusOK, List{Data: summaries, Total: int64(len(summaries))})
+}
+
+// listAllTODOs answers a TODO query across every readable project. It takes
+// the parameters of GET /todos plus project, a list of projects to query.
+func (h *Hub) listAllTODOs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts, err := parseListOptions(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	only := make(map[string]bool)
+	for _, name := range splitList(q.Get("project")) {
+		only[name] = true
+	}
+
+	todos := []ProjectTODO{}
+	for _, name := range h.readable(r) {
+		if len(only) > 0 && !only[name] {
+			continue
+		}
+		query, err := filterTODOs(h.projects[name].server.DB.Model(&database.TODO{}), q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		var found []database.TODO
+		if err := query.Find(&found).Error; err != nil {
+			writeDBError(w, err, "TODOs")
+			return
+		}
+		for _, t := range found {
+			todos = append(todos, ProjectTODO{Project: name, TODO: t})
+		}
+	}
+	sortTODOs(todos, opts.order)
+
+	total := len(todos)
+	start := (opts.page - 1) * opts.perPage
+	end := start + opts.perPage
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	page := todos[start:end]
+
+	var data interface{} = page
+	if len(opts.fields) > 0 {
+		selected := make([]map[string]interface{}, 0, len(page))
+		for _, t := range page {
+			item := map[string]interface{}{"project": t.Project}
+			for field, v := range selectTODOFields(t.TODO, opts.fields) {
+				item[field] = v
+			}
+			selected = append(selected, item)
+		}
+		data = selected
+	}
+	writeJSON(w, http.StatusOK, List{Data: data, Total: int64(total), Page: opts.page, PerPage: opts.perPage})
+}
+
+// sortTODOs orders TODOs from several projects like the database orders
+// those of one: by the "column ASC|DESC" keys of order, with empty values
+// first
+func sortTODOs(todos []ProjectTODO, order []string) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := reflect.ValueOf(todos[i].TODO), reflect.ValueOf(todos[j].TODO)
+		for _, key := range order {
+			column, dir, _ := strings.Cut(key, " ")
+			field, ok := todoFields[column]
+			if !ok {
+				continue
+			}
+			c := compareValues(a.Field(field), b.Field(field))
+			if c == 0 {
+				continue
+			}
+			if dir == "DESC" {
+				return c > 0
+			}
+			return c < 0
+		}
+		return todos[i].Project < todos[j].Project
+	})
+}
+
+// compareValues compares strings, integers and times, and pointers to them
+// with nil first
+func compareValues(a, b reflect.Value) int {
+	if a.Kind() == reflect.Ptr {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	switch v := a.Interface().(type) {
+	case time.Time:
+		return v.Compare(b.Interface().(time.Time))
+	case string:
+		return strings.Compare(v, b.String())
+	case int:
+		switch w := b.Interface().(int); {
+		case v < w:
+			return -1
+		case v > w:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHub serves the test project as "alpha", and as the default, next
+// to a second project "beta"
+func newTestHub(t *testing.T) (*Hub, *Server, *Server) {
+	alpha, _ := newTestServer(t)
+
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	for _, todo := range []database.TODO{
+		{FilePath: "/q/main.go", LineNumber: 2, Type: "TODO", Content: "add metrics", Status: "open", Priority: "P0", Hash: "d"},
+		{FilePath: "/q/main.go", LineNumber: 7, Type: "HACK", Content: "remove sleep", Status: "open", Priority: "P3", Hash: "e"},
+	} {
+		require.NoError(t, db.CreateTODO(&todo))
+	}
+	beta := New(db)
+
+	hub := NewHub(alpha)
+	hub.Add("alpha", "/p", alpha)
+	hub.Add("beta", "/q", beta)
+	return hub, alpha, beta
+}
+
+func hubRequest(hub *Hub, credential, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	rec := httptest.NewRecorder()
+	hub.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHubRouting(t *testing.T) {
+	hub, _, _ := newTestHub(t)
+
+	var todos []database.TODO
+	decodeList(t, hubRequest(hub, "", "GET", "/api/v1/todos"), &todos)
+	assert.Len(t, todos, 3)
+
+	list := decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects/beta/todos?sort=-priority"), &todos)
+	assert.EqualValues(t, 2, list.Total)
+	assert.Equal(t, "remove sleep", todos[0].Content)
+
+	rec := hubRequest(hub, "", "GET", "/api/v1/projects/beta/todos/"+todos[0].ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = hubRequest(hub, "", "GET", "/api/v1/todos/"+todos[0].ID)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "TODOs of beta are not in the default project")
+
+	rec = hubRequest(hub, "", "GET", "/api/v1/projects/gamma/todos")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "project gamma not found")
+
+	var projects []ProjectSummary
+	decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects"), &projects)
+	require.Len(t, projects, 2)
+	assert.Equal(t, ProjectSummary{Name: "beta", Path: "/q", Total: 2, Open: 2}, projects[1])
+	assert.EqualValues(t, 1, projects[0].Open)
+}
+
+func TestHubQueryAllProjects(t *testing.T) {
+	hub, _, _ := newTestHub(t)
+
+	var todos []ProjectTODO
+	list := decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects/*/todos?sort=priority,line_number&per_page=3"), &todos)
+	assert.EqualValues(t, 5, list.Total)
+	require.Len(t, todos, 3)
+	assert.Equal(t, []string{"add metrics", "fix parser", "add retries"},
+		[]string{todos[0].Content, todos[1].Content, todos[2].Content})
+	assert.Equal(t, []string{"beta", "alpha", "alpha"},
+		[]string{todos[0].Project, todos[1].Project, todos[2].Project})
+
+	decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects/*/todos?status=open&project=beta"), &todos)
+	assert.Len(t, todos, 2)
+	for _, todo := range todos {
+		assert.Equal(t, "beta", todo.Project)
+	}
+
+	var selected []map[string]interface{}
+	decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects/*/todos?type=HACK&fields=content"), &selected)
+	assert.Equal(t, []map[string]interface{}{{"project": "beta", "content": "remove sleep"}}, selected)
+
+	rec := hubRequest(hub, "", "GET", "/api/v1/projects/*/todos?sort=nope")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = hubRequest(hub, "", "GET", "/api/v1/projects/*/stats")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHubOnlyShowsReadableProjects(t *testing.T) {
+	hub, _, beta := newTestHub(t)
+	token := newUser(t, beta, "alice", database.RoleViewer, "")
+
+	var projects []ProjectSummary
+	decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects"), &projects)
+	require.Len(t, projects, 1)
+	assert.Equal(t, "alpha", projects[0].Name)
+
+	var todos []ProjectTODO
+	decodeList(t, hubRequest(hub, "", "GET", "/api/v1/projects/*/todos"), &todos)
+	assert.Len(t, todos, 3)
+
+	decodeList(t, hubRequest(hub, token, "GET", "/api/v1/projects/*/todos"), &todos)
+	assert.Len(t, todos, 5)
+
+	assert.Equal(t, http.StatusUnauthorized, hubRequest(hub, "", "GET", "/api/v1/projects/beta/todos").Code)
+}
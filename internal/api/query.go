@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"gorm.io/gorm"
+)
+
+// Pagination limits for collections
+const (
+	DefaultPerPage = 50
+	MaxPerPage     = 500
+)
+
+// defaultSort orders TODOs like the CLI does
+const defaultSort = "file_path,line_number"
+
+// sortable are the TODO columns a collection can be sorted by
+var sortable = map[string]bool{
+	"file_path": true, "line_number": true, "type": true, "status": true,
+	"priority": true, "severity": true, "category": true, "assignee": true,
+	"author": true, "due_date": true, "created_at": true, "updated_at": true,
+}
+
+// filterKeys are the query parameters that narrow a TODO collection; saved
+// filters may use these too
+var filterKeys = map[string]bool{
+	"status": true, "priority": true, "type": true, "assignee": true,
+	"author": true, "category": true, "severity": true, "file": true,
+	"tag": true, "q": true, "overdue": true, "due_before": true,
+	"due_after": true, "removed": true,
+}
+
+// todoFields maps the JSON fields of a TODO, which can be selected, to
+// their index in the struct
+var todoFields = jsonFields(reflect.TypeOf(database.TODO{}))
+
+// listOptions are the pagination, order and field selection of a request
+type listOptions struct {
+	page    int
+	perPage int
+	order   []string
+	fields  []string
+}
+
+// parseListOptions reads page, per_page, sort and fields
+func parseListOptions(q url.Values) (listOptions, error) {
+	var opts listOptions
+	var err error
+	if opts.page, opts.perPage, err = parsePage(q); err != nil {
+		return opts, err
+	}
+
+	sortBy := q.Get("sort")
+	if sortBy == "" {
+		sortBy = defaultSort
+	}
+	for _, key := range splitList(sortBy) {
+		dir := "ASC"
+		if strings.HasPrefix(key, "-") {
+			key, dir = key[1:], "DESC"
+		}
+		if !sortable[key] {
+			return opts, fmt.Errorf("cannot sort by %q", key)
+		}
+		opts.order = append(opts.order, key+" "+dir)
+	}
+	// Keep pages stable when sort keys tie
+	opts.order = append(opts.order, "id ASC")
+
+	for _, field := range splitList(q.Get("fields")) {
+		if _, ok := todoFields[field]; !ok {
+			return opts, fmt.Errorf("unknown field %q", field)
+		}
+		opts.fields = append(opts.fields, field)
+	}
+	return opts, nil
+}
+
+// parsePage reads page and per_page
+func parsePage(q url.Values) (page, perPage int, err error) {
+	page, perPage = 1, DefaultPerPage
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive number")
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > MaxPerPage {
+			return 0, 0, fmt.Errorf("per_page must be between 1 and %d", MaxPerPage)
+		}
+	}
+	return page, perPage, nil
+}
+
+// filterTODOs narrows query by the filter parameters in q. Lists are
+// comma-separated and match any of their values.
+func filterTODOs(query *gorm.DB, q url.Values) (*gorm.DB, error) {
+	for _, column := range []string{"status", "priority", "type", "assignee", "author", "category", "severity"} {
+		if values := splitList(q.Get(column)); len(values) > 0 {
+			query = query.Where(column+" IN ?", values)
+		}
+	}
+	if file := q.Get("file"); file != "" {
+		query = query.Where("file_path LIKE ?", "%"+file+"%")
+	}
+	if tags := splitList(q.Get("tag")); len(tags) > 0 {
+		query = query.Where("id IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Table("todo_tags").Select("todo_tags.todo_id").
+			Joins("JOIN tags ON tags.id = todo_tags.tag_id").
+			Where("tags.name IN ?", tags))
+	}
+	if text := q.Get("q"); text != "" {
+		like := "%" + text + "%"
+		query = query.Where("content LIKE ? OR assignee LIKE ? OR file_path LIKE ?", like, like, like)
+	}
+	if v := q.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("overdue must be true or false")
+		}
+		condition := "due_date IS NOT NULL AND due_date < ? AND status NOT IN ('closed', 'resolved')"
+		if overdue {
+			query = query.Where(condition, time.Now())
+		} else {
+			query = query.Not(condition, time.Now())
+		}
+	}
+	if v := q.Get("due_before"); v != "" {
+		due, err := parseDate(v)
+		if err != nil {
+			return nil, fmt.Errorf("due_before: %w", err)
+		}
+		query = query.Where("due_date IS NOT NULL AND due_date < ?", due)
+	}
+	if v := q.Get("due_after"); v != "" {
+		due, err := parseDate(v)
+		if err != nil {
+			return nil, fmt.Errorf("due_after: %w", err)
+		}
+		query = query.Where("due_date IS NOT NULL AND due_date > ?", due)
+	}
+	if v := q.Get("removed"); v != "" {
+		removed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("removed must be true or false")
+		}
+		if removed {
+			query = query.Where("removed_at IS NOT NULL")
+		} else {
+			query = query.Where("removed_at IS NULL")
+		}
+	}
+	return query, nil
+}
+
+// listTODOs answers with a page of the TODOs in base matching the request
+func (s *Server) listTODOs(w http.ResponseWriter, q url.Values, base *gorm.DB) {
+	opts, err := parseListOptions(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	query, err := filterTODOs(base, q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		writeDBError(w, err, "TODOs")
+		return
+	}
+	todos := []database.TODO{}
+	for _, order := range opts.order {
+		query = query.Order(order)
+	}
+	if err := query.Offset((opts.page - 1) * opts.perPage).Limit(opts.perPage).Find(&todos).Error; err != nil {
+		writeDBError(w, err, "TODOs")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, List{
+		Data:    selectFields(todos, opts.fields),
+		Total:   total,
+		Page:    opts.page,
+		PerPage: opts.perPage,
+	})
+}
+
+// selectFields keeps only the given fields of each TODO; without fields the
+// TODOs are returned whole
+func selectFields(todos []database.TODO, fields []string) interface{} {
+	if len(fields) == 0 {
+		return todos
+	}
+	selected := make([]map[string]json.RawMessage, 0, len(todos))
+	for _, t := range todos {
+		selected = append(selected, selectTODOFields(t, fields))
+	}
+	return selected
+}
+
+func selectTODOFields(t database.TODO, fields []string) map[string]json.RawMessage {
+	data, _ := json.Marshal(t)
+	var all map[string]json.RawMessage
+	json.Unmarshal(data, &all)
+
+	picked := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			picked[f] = v
+		} else {
+			// Omitted because empty
+			picked[f] = json.RawMessage("null")
+		}
+	}
+	return picked
+}
+
+// jsonFields maps the JSON names of the fields of a struct type to their
+// index
+func jsonFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// parseDate accepts a date or an RFC 3339 timestamp
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
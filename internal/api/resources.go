@@ -0,0 +1,467 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"gorm.io/gorm"
+)
+
+// relationshipTypes can be created through the API; their inverses are
+// recorded automatically
+var relationshipTypes = map[string]bool{"parent": true, "depends_on": true, "relates_to": true}
+
+// TagCount is a tag with the number of TODOs carrying it
+type TagCount struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// TimeSummary is the time tracked on a TODO
+type TimeSummary struct {
+	TotalMinutes int                  `json:"total_minutes"`
+	Running      *database.TimeEntry  `json:"running,omitempty"`
+	Entries      []database.TimeEntry `json:"entries"`
+}
+
+// routeTags routes /tags and /tags/{name}
+func (s *Server) routeTags(w http.ResponseWriter, r *http.Request, seg []string) {
+	switch len(seg) {
+	case 0:
+		dispatch(w, r, methods{
+			http.MethodGet:  func() { s.listTags(w) },
+			http.MethodPost: func() { s.createTag(w, r) },
+		})
+	case 1:
+		dispatch(w, r, methods{http.MethodDelete: func() { s.deleteTag(w, seg[0]) }})
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	}
+}
+
+func (s *Server) listTags(w http.ResponseWriter) {
+	tags := []TagCount{}
+	err := s.DB.Table("tags").
+		Select("tags.id, tags.name, COUNT(todo_tags.todo_id) AS count").
+		Joins("LEFT JOIN todo_tags ON todo_tags.tag_id = tags.id").
+		Group("tags.id, tags.name").Order("tags.name").
+		Scan(&tags).Error
+	if err != nil {
+		writeDBError(w, err, "tags")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: tags, Total: int64(len(tags))})
+}
+
+func (s *Server) createTag(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Name = strings.TrimSpace(body.Name); body.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	var existing database.Tag
+	if err := s.DB.First(&existing, "name = ?", body.Name).Error; err == nil {
+		writeError(w, http.StatusConflict, "tag %s already exists", body.Name)
+		return
+	}
+	tag, err := s.DB.GetOrCreateTag(body.Name)
+	if err != nil {
+		writeDBError(w, err, "tag "+body.Name)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tag)
+}
+
+func (s *Server) deleteTag(w http.ResponseWriter, name string) {
+	var tag database.Tag
+	if err := s.DB.First(&tag, "name = ?", name).Error; err != nil {
+		writeDBError(w, err, "tag "+name)
+		return
+	}
+	if err := s.DB.DeleteTag(tag.ID); err != nil {
+		writeDBError(w, err, "tag "+name)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listTODOTags(w http.ResponseWriter, id string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	tags, err := s.DB.GetTagsForTODO(id)
+	if err != nil {
+		writeDBError(w, err, "tags")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: nonNil(tags), Total: int64(len(tags))})
+}
+
+// tagTODO adds a tag to a TODO, creating the tag when needed
+func (s *Server) tagTODO(w http.ResponseWriter, id, name string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	tag, err := s.DB.GetOrCreateTag(name)
+	if err != nil {
+		writeDBError(w, err, "tag "+name)
+		return
+	}
+	if err := s.DB.AddTagToTODO(id, tag.ID); err != nil {
+		writeDBError(w, err, "tag "+name)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) untagTODO(w http.ResponseWriter, id, name string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	var tag database.Tag
+	if err := s.DB.First(&tag, "name = ?", name).Error; err != nil {
+		writeDBError(w, err, "tag "+name)
+		return
+	}
+	if err := s.DB.RemoveTagFromTODO(id, tag.ID); err != nil {
+		writeDBError(w, err, "tag "+name)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listRelationships(w http.ResponseWriter, id string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	rels, err := s.DB.GetRelationships(id)
+	if err != nil {
+		writeDBError(w, err, "relationships")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: nonNil(rels), Total: int64(len(rels))})
+}
+
+func (s *Server) createRelationship(w http.ResponseWriter, r *http.Request, id string) {
+	var body struct {
+		Type     string `json:"type"`
+		TargetID string `json:"target_id"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if !relationshipTypes[body.Type] {
+		writeError(w, http.StatusBadRequest, "type must be parent, depends_on or relates_to")
+		return
+	}
+	if body.TargetID == "" || body.TargetID == id {
+		writeError(w, http.StatusBadRequest, "target_id must name another TODO")
+		return
+	}
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	if _, ok := s.findTODO(w, body.TargetID); !ok {
+		return
+	}
+
+	rel, err := s.DB.AddRelationship(id, body.TargetID, body.Type)
+	switch {
+	case errors.Is(err, database.ErrRelationshipExists), errors.Is(err, database.ErrCircularDependency):
+		writeError(w, http.StatusConflict, "%v", err)
+	case err != nil:
+		writeDBError(w, err, "relationship")
+	default:
+		writeJSON(w, http.StatusCreated, rel)
+	}
+}
+
+// routeRelationship routes /relationships/{id}
+func (s *Server) routeRelationship(w http.ResponseWriter, r *http.Request, id string) {
+	dispatch(w, r, methods{
+		http.MethodGet: func() {
+			rel, err := s.DB.GetRelationship(id)
+			if err != nil {
+				writeDBError(w, err, "relationship "+id)
+				return
+			}
+			writeJSON(w, http.StatusOK, rel)
+		},
+		http.MethodDelete: func() {
+			rel, err := s.DB.GetRelationship(id)
+			if err != nil {
+				writeDBError(w, err, "relationship "+id)
+				return
+			}
+			if err := s.DB.RemoveRelationship(rel); err != nil {
+				writeDBError(w, err, "relationship "+id)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+}
+
+func (s *Server) listWatchers(w http.ResponseWriter, id string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	watches, err := s.DB.GetWatchers(id)
+	if err != nil {
+		writeDBError(w, err, "watchers")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: nonNil(watches), Total: int64(len(watches))})
+}
+
+func (s *Server) watch(w http.ResponseWriter, id, user string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	if !s.DB.IsWatching(id, user) {
+		if err := s.DB.CreateWatch(id, user); err != nil {
+			writeDBError(w, err, "watch")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) unwatch(w http.ResponseWriter, id, user string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	if !s.DB.IsWatching(id, user) {
+		writeError(w, http.StatusNotFound, "%s is not watching TODO %s", user, id)
+		return
+	}
+	if err := s.DB.DeleteWatch(id, user); err != nil {
+		writeDBError(w, err, "watch")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listWatched lists the TODOs a user watches
+func (s *Server) listWatched(w http.ResponseWriter, r *http.Request, user string) {
+	watched := s.DB.Model(&database.Watch{}).Select("todo_id").Where("user_id = ?", user)
+	s.listTODOs(w, r.URL.Query(), s.DB.Model(&database.TODO{}).Where("id IN (?)", watched))
+}
+
+func (s *Server) getTime(w http.ResponseWriter, id string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	entries, err := s.DB.GetTimeEntries(id)
+	if err != nil {
+		writeDBError(w, err, "time entries")
+		return
+	}
+	summary := TimeSummary{Entries: nonNil(entries)}
+	for i, e := range entries {
+		summary.TotalMinutes += e.Duration
+		if e.EndTime == nil {
+			summary.Running = &entries[i]
+		}
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// logTime records time spent without a timer
+func (s *Server) logTime(w http.ResponseWriter, r *http.Request, id string) {
+	var body struct {
+		Minutes     int    `json:"minutes"`
+		Description string `json:"description"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Minutes <= 0 {
+		writeError(w, http.StatusBadRequest, "minutes must be positive")
+		return
+	}
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	entry, err := s.DB.AddManualTime(id, body.Minutes, body.Description)
+	if err != nil {
+		writeDBError(w, err, "time entry")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+func (s *Server) startTimer(w http.ResponseWriter, r *http.Request, id string) {
+	var body struct {
+		Description string `json:"description"`
+	}
+	// The body is optional
+	if r.ContentLength != 0 && !decode(w, r, &body) {
+		return
+	}
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	if _, err := s.DB.GetRunningTimer(id); err == nil {
+		writeError(w, http.StatusConflict, "a timer is already running for TODO %s", id)
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeDBError(w, err, "timer")
+		return
+	}
+	entry, err := s.DB.StartTimer(id, body.Description)
+	if err != nil {
+		writeDBError(w, err, "timer")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+func (s *Server) stopTimer(w http.ResponseWriter, id string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	entry, err := s.DB.StopTimer(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusConflict, "no timer is running for TODO %s", id)
+		return
+	}
+	if err != nil {
+		writeDBError(w, err, "timer")
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// routeFilters routes /filters, /filters/{name} and /filters/{name}/todos
+func (s *Server) routeFilters(w http.ResponseWriter, r *http.Request, seg []string) {
+	switch {
+	case len(seg) == 0:
+		dispatch(w, r, methods{
+			http.MethodGet:  func() { s.listFilters(w) },
+			http.MethodPost: func() { s.createFilter(w, r) },
+		})
+	case len(seg) == 1:
+		dispatch(w, r, methods{
+			http.MethodGet:    func() { s.getFilter(w, seg[0]) },
+			http.MethodDelete: func() { s.deleteFilter(w, seg[0]) },
+		})
+	case len(seg) == 2 && seg[1] == "todos":
+		dispatch(w, r, methods{http.MethodGet: func() { s.runFilter(w, r, seg[0]) }})
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	}
+}
+
+func (s *Server) listFilters(w http.ResponseWriter) {
+	filters, err := s.DB.GetSavedFilters()
+	if err != nil {
+		writeDBError(w, err, "filters")
+		return
+	}
+	writeJSON(w, http.StatusOK, List{Data: nonNil(filters), Total: int64(len(filters))})
+}
+
+// createFilter saves a filter. Its query uses the filter parameters of
+// GET /todos, in the format written by todo filter save.
+func (s *Server) createFilter(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Name = strings.TrimSpace(body.Name); body.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	q, err := url.ParseQuery(body.Query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid query: %v", err)
+		return
+	}
+	for key := range q {
+		if !filterKeys[key] && key != "all" {
+			writeError(w, http.StatusBadRequest, "invalid query: unknown filter %q", key)
+			return
+		}
+	}
+	if _, err := filterTODOs(s.DB.Model(&database.TODO{}), q); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid query: %v", err)
+		return
+	}
+	if _, err := s.DB.GetSavedFilter(body.Name); err == nil {
+		writeError(w, http.StatusConflict, "filter %s already exists", body.Name)
+		return
+	}
+	if body.Query == "" {
+		body.Query = "all=true"
+	}
+
+	filter, err := s.DB.CreateSavedFilter(body.Name, body.Query)
+	if err != nil {
+		writeDBError(w, err, "filter "+body.Name)
+		return
+	}
+	writeJSON(w, http.StatusCreated, filter)
+}
+
+func (s *Server) getFilter(w http.ResponseWriter, name string) {
+	filter, err := s.DB.GetSavedFilter(name)
+	if err != nil {
+		writeDBError(w, err, "filter "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, filter)
+}
+
+func (s *Server) deleteFilter(w http.ResponseWriter, name string) {
+	if _, err := s.DB.GetSavedFilter(name); err != nil {
+		writeDBError(w, err, "filter "+name)
+		return
+	}
+	if err := s.DB.DeleteSavedFilter(name); err != nil {
+		writeDBError(w, err, "filter "+name)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runFilter lists the TODOs matching a saved filter. Request parameters
+// narrow the result further.
+func (s *Server) runFilter(w http.ResponseWriter, r *http.Request, name string) {
+	filter, err := s.DB.GetSavedFilter(name)
+	if err != nil {
+		writeDBError(w, err, "filter "+name)
+		return
+	}
+	saved, err := url.ParseQuery(filter.Query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "filter %s has an invalid query: %v", name, err)
+		return
+	}
+	base, err := filterTODOs(s.DB.Model(&database.TODO{}), saved)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "filter %s has an invalid query: %v", name, err)
+		return
+	}
+	s.listTODOs(w, r.URL.Query(), base)
+}
+
+// nonNil keeps empty collections from encoding as null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+)
+
+// Valid values of editable TODO fields, as accepted by todo edit
+var (
+	statuses   = []string{"open", "in_progress", "blocked", "resolved", "wontfix", "closed"}
+	priorities = []string{"P0", "P1", "P2", "P3", "P4"}
+	severities = []string{"info", "low", "medium", "high", "critical"}
+)
+
+// routeTODOs routes /todos and the resources of a TODO
+func (s *Server) routeTODOs(w http.ResponseWriter, r *http.Request, seg []string) {
+	if len(seg) == 0 {
+		dispatch(w, r, methods{http.MethodGet: func() {
+			s.listTODOs(w, r.URL.Query(), s.DB.Model(&database.TODO{}))
+		}})
+		return
+	}
+
+	id := seg[0]
+	switch {
+	case len(seg) == 1:
+		dispatch(w, r, methods{
+			http.MethodGet:    func() { s.getTODO(w, r, id) },
+			http.MethodPatch:  func() { s.updateTODO(w, r, id) },
+			http.MethodDelete: func() { s.deleteTODO(w, id) },
+		})
+	case seg[1] == "tags" && len(seg) == 2:
+		dispatch(w, r, methods{http.MethodGet: func() { s.listTODOTags(w, id) }})
+	case seg[1] == "tags" && len(seg) == 3:
+		dispatch(w, r, methods{
+			http.MethodPut:    func() { s.tagTODO(w, id, seg[2]) },
+			http.MethodDelete: func() { s.untagTODO(w, id, seg[2]) },
+		})
+	case seg[1] == "relationships" && len(seg) == 2:
+		dispatch(w, r, methods{
+			http.MethodGet:  func() { s.listRelationships(w, id) },
+			http.MethodPost: func() { s.createRelationship(w, r, id) },
+		})
+	case seg[1] == "watchers" && len(seg) == 2:
+		dispatch(w, r, methods{http.MethodGet: func() { s.listWatchers(w, id) }})
+	case seg[1] == "watchers" && len(seg) == 3:
+		dispatch(w, r, methods{
+			http.MethodPut:    func() { s.watch(w, id, seg[2]) },
+			http.MethodDelete: func() { s.unwatch(w, id, seg[2]) },
+		})
+	case seg[1] == "time" && len(seg) == 2:
+		dispatch(w, r, methods{
+			http.MethodGet:  func() { s.getTime(w, id) },
+			http.MethodPost: func() { s.logTime(w, r, id) },
+		})
+	case seg[1] == "time" && len(seg) == 3 && seg[2] == "start":
+		dispatch(w, r, methods{http.MethodPost: func() { s.startTimer(w, r, id) }})
+	case seg[1] == "time" && len(seg) == 3 && seg[2] == "stop":
+		dispatch(w, r, methods{http.MethodPost: func() { s.stopTimer(w, id) }})
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: %s", r.URL.Path)
+	}
+}
+
+// findTODO loads a TODO, answering 404 when it does not exist
+func (s *Server) findTODO(w http.ResponseWriter, id string) (*database.TODO, bool) {
+	todo, err := s.DB.GetTODOByID(id)
+	if err != nil {
+		writeDBError(w, err, "TODO "+id)
+		return nil, false
+	}
+	return todo, true
+}
+
+func (s *Server) getTODO(w http.ResponseWriter, r *http.Request, id string) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	todo, ok := s.findTODO(w, id)
+	if !ok {
+		return
+	}
+	if len(opts.fields) > 0 {
+		writeJSON(w, http.StatusOK, selectTODOFields(*todo, opts.fields))
+		return
+	}
+	writeJSON(w, http.StatusOK, todo)
+}
+
+// updateTODO applies a partial update. Fields left out are unchanged; null
+// clears assignee, category, severity, due_date and estimate.
+func (s *Server) updateTODO(w http.ResponseWriter, r *http.Request, id string) {
+	var patch map[string]json.RawMessage
+	if !decode(w, r, &patch) {
+		return
+	}
+	todo, ok := s.findTODO(w, id)
+	if !ok {
+		return
+	}
+	if err := applyPatch(todo, patch); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err := s.DB.UpdateTODO(todo); err != nil {
+		writeDBError(w, err, "TODO "+id)
+		return
+	}
+	writeJSON(w, http.StatusOK, todo)
+}
+
+func applyPatch(todo *database.TODO, patch map[string]json.RawMessage) error {
+	for field, raw := range patch {
+		null := string(raw) == "null"
+		var err error
+		switch field {
+		case "status":
+			err = decodeChoice(raw, &todo.Status, statuses, false)
+		case "priority":
+			err = decodeChoice(raw, &todo.Priority, priorities, false)
+		case "severity":
+			err = decodeChoice(raw, &todo.Severity, severities, true)
+		case "content":
+			if err = json.Unmarshal(raw, &todo.Content); err == nil && todo.Content == "" {
+				err = fmt.Errorf("must not be empty")
+			}
+		case "assignee":
+			todo.Assignee = ""
+			if !null {
+				err = json.Unmarshal(raw, &todo.Assignee)
+			}
+		case "category":
+			todo.Category = ""
+			if !null {
+				err = json.Unmarshal(raw, &todo.Category)
+			}
+		case "due_date":
+			todo.DueDate = nil
+			if !null {
+				var value string
+				if err = json.Unmarshal(raw, &value); err == nil {
+					var due time.Time
+					if due, err = parseDate(value); err == nil {
+						todo.DueDate = &due
+					}
+				}
+			}
+		case "estimate":
+			todo.Estimate = nil
+			if !null {
+				var minutes int
+				if err = json.Unmarshal(raw, &minutes); err == nil {
+					if minutes < 0 {
+						err = fmt.Errorf("must not be negative")
+					}
+					todo.Estimate = &minutes
+				}
+			}
+		default:
+			return fmt.Errorf("field %q cannot be changed", field)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// decodeChoice decodes a string that must be one of choices; clearable
+// fields also accept null and ""
+func decodeChoice(raw json.RawMessage, dst *string, choices []string, clearable bool) error {
+	if string(raw) == "null" && clearable {
+		*dst = ""
+		return nil
+	}
+	var v string
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	if v == "" && clearable {
+		*dst = ""
+		return nil
+	}
+	for _, c := range choices {
+		if v == c {
+			*dst = v
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %v", v, choices)
+}
+
+func (s *Server) deleteTODO(w http.ResponseWriter, id string) {
+	if _, ok := s.findTODO(w, id); !ok {
+		return
+	}
+	if err := s.DB.DeleteTODO(id); err != nil {
+		writeDBError(w, err, "TODO "+id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -15,6 +18,19 @@ type Config struct {
 	TodoTypes       []string `mapstructure:"todo_types"`
 	IgnoreCase      bool     `mapstructure:"ignore_case"`
 
+	// Markers sets defaults for TODOs found with a given marker, keyed by
+	// marker name. Markers listed here are scanned for even when they are
+	// not in TodoTypes.
+	Markers map[string]MarkerConfig `mapstructure:"markers"`
+
+	// AnnotationPrecedence decides who wins when an inline annotation such
+	// as TODO(alice)[P1] disagrees with the database: "code" applies
+	// annotations on every scan, "database" only when a TODO is first found
+	AnnotationPrecedence string `mapstructure:"annotation_precedence"`
+
+	// Languages defines extra languages or overrides built-in ones by name
+	Languages []LanguageConfig `mapstructure:"languages"`
+
 	// Git Integration
 	GitAuthor       bool   `mapstructure:"git_author"`
 	GitBranchFilter string `mapstructure:"git_branch_filter"`
@@ -27,7 +43,7 @@ type Config struct {
 
 	// Performance
 	ParallelWorkers int `mapstructure:"parallel_workers"`
-	CacheTTL        int `mapstructure:"cache_ttl"`
+	CacheTTL        int `mapstructure:"cache_ttl"` // minutes before unchanged files are re-hashed
 
 	// Notifications
 	Notifications NotificationsConfig `mapstructure:"notifications"`
@@ -38,6 +54,12 @@ type Config struct {
 	// Digest
 	Digest DigestConfig `mapstructure:"digest"`
 
+	// Policy rules enforced by 'todo check'
+	Policy PolicyConfig `mapstructure:"policy"`
+
+	// Web server
+	Server ServerConfig `mapstructure:"server"`
+
 	// Paths
 	ProjectPath string `mapstructure:"-"`
 	DBPath      string `mapstructure:"db_path"`
@@ -47,16 +69,95 @@ type Config struct {
 
 	// Integrations
 	GitHub GitHubConfig `mapstructure:"github"`
+	GitLab GitLabConfig `mapstructure:"gitlab"`
+	Gitea  GiteaConfig  `mapstructure:"gitea"`
 	Jira   JiraConfig   `mapstructure:"jira"`
 	Linear LinearConfig `mapstructure:"linear"`
 	Notion NotionConfig `mapstructure:"notion"`
 }
 
+// MarkerConfig holds the defaults applied to new TODOs found with a marker
+type MarkerConfig struct {
+	Priority string `mapstructure:"priority"` // P0-P4
+	Category string `mapstructure:"category"`
+	Severity string `mapstructure:"severity"` // info, low, medium, high, critical
+}
+
+// MarkerNames returns every marker to scan for, upper-cased and de-duplicated
+func (c *Config) MarkerNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, t := range c.TodoTypes {
+		add(t)
+	}
+	// Map order is random; keep the scan fingerprint stable
+	var extra []string
+	for name := range c.Markers {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		add(name)
+	}
+	return names
+}
+
+// Marker returns the defaults configured for a marker, ignoring case
+func (c *Config) Marker(name string) (MarkerConfig, bool) {
+	for key, m := range c.Markers {
+		if strings.EqualFold(key, name) {
+			return m, true
+		}
+	}
+	return MarkerConfig{}, false
+}
+
+// LanguageConfig defines how the scanner recognises a language and its
+// comments
+type LanguageConfig struct {
+	Name          string   `mapstructure:"name"`
+	Extensions    []string `mapstructure:"extensions"`
+	Filenames     []string `mapstructure:"filenames"`
+	Shebangs      []string `mapstructure:"shebangs"`
+	LineComments  []string `mapstructure:"line_comments"`
+	BlockComments []string `mapstructure:"block_comments"` // start/end pairs
+	Strings       []string `mapstructure:"strings"`
+	RawStrings    []string `mapstructure:"raw_strings"` // those of Strings without backslash escapes
+	Nested        bool     `mapstructure:"nested"`
+}
+
 // GitHubConfig holds GitHub integration settings
 type GitHubConfig struct {
 	Token string `mapstructure:"token"`
 	Owner string `mapstructure:"owner"`
 	Repo  string `mapstructure:"repo"`
+	// APIURL points at GitHub Enterprise, e.g. https://ghe.example.com/api/v3
+	APIURL string `mapstructure:"api_url"`
+}
+
+// GitLabConfig holds GitLab integration settings
+type GitLabConfig struct {
+	Token string `mapstructure:"token"`
+	// URL is the GitLab instance; empty means https://gitlab.com
+	URL string `mapstructure:"url"`
+	// Project is the path of the project, e.g. group/app, or its ID
+	Project string `mapstructure:"project"`
+}
+
+// GiteaConfig holds Gitea integration settings
+type GiteaConfig struct {
+	Token string `mapstructure:"token"`
+	// URL is the Gitea instance, e.g. https://git.example.com
+	URL   string `mapstructure:"url"`
+	Owner string `mapstructure:"owner"`
+	Repo  string `mapstructure:"repo"`
 }
 
 // JiraConfig holds Jira integration settings
@@ -65,20 +166,152 @@ type JiraConfig struct {
 	Email    string `mapstructure:"email"`
 	APIToken string `mapstructure:"api_token"`
 	Project  string `mapstructure:"project"`
+
+	// Transitions maps TODO statuses to the workflow transition, or the
+	// status it leads to, that applies them in Jira. Statuses without one
+	// are only told apart as unfinished or done.
+	Transitions map[string]string `mapstructure:"transitions"`
+	// Priorities maps P0-P4 to Jira priority names
+	Priorities map[string]string `mapstructure:"priorities"`
+	// IssueTypes maps TODO types to Jira issue types
+	IssueTypes map[string]string `mapstructure:"issue_types"`
+	// Users maps assignees to Jira account IDs
+	Users map[string]string `mapstructure:"users"`
+}
+
+// Jira mappings used for keys missing from the configuration; they match
+// the default workflow and schemes of Jira Cloud
+var (
+	DefaultJiraTransitions = map[string]string{
+		"open":        "To Do",
+		"in_progress": "In Progress",
+		"resolved":    "Done",
+		"closed":      "Done",
+		"wontfix":     "Won't Do",
+	}
+	DefaultJiraPriorities = map[string]string{
+		"P0": "Highest",
+		"P1": "High",
+		"P2": "Medium",
+		"P3": "Low",
+		"P4": "Lowest",
+	}
+	DefaultJiraIssueTypes = map[string]string{
+		"BUG":   "Bug",
+		"FIXME": "Bug",
+	}
+)
+
+// DefaultJiraIssueType is the issue type of TODO types without a mapping
+const DefaultJiraIssueType = "Task"
+
+// Transition returns the transition or status for a TODO status, or ""
+func (c JiraConfig) Transition(status string) string {
+	return lookup(c.Transitions, DefaultJiraTransitions, status)
+}
+
+// Priority returns the Jira priority for P0-P4, or ""
+func (c JiraConfig) Priority(priority string) string {
+	return lookup(c.Priorities, DefaultJiraPriorities, priority)
+}
+
+// IssueType returns the Jira issue type for a TODO type
+func (c JiraConfig) IssueType(todoType string) string {
+	if t := lookup(c.IssueTypes, DefaultJiraIssueTypes, todoType); t != "" {
+		return t
+	}
+	return DefaultJiraIssueType
+}
+
+// AccountID returns the Jira account of an assignee, or ""
+func (c JiraConfig) AccountID(user string) string {
+	return lookup(c.Users, nil, user)
 }
 
-// LinearConfig holds Linear integration settings (future)
+// lookup finds key in m and then in defaults, ignoring case since viper
+// lowercases keys
+func lookup(m, defaults map[string]string, key string) string {
+	for _, source := range []map[string]string{m, defaults} {
+		for k, v := range source {
+			if strings.EqualFold(k, key) {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// LinearConfig holds Linear integration settings
 type LinearConfig struct {
 	APIKey  string `mapstructure:"api_key"`
 	TeamID  string `mapstructure:"team_id"`
 	Enabled bool   `mapstructure:"enabled"`
+	// APIURL overrides the GraphQL endpoint
+	APIURL string `mapstructure:"api_url"`
+
+	// States maps TODO statuses to workflow state names. Statuses without
+	// one use the team's first state of the matching type.
+	States map[string]string `mapstructure:"states"`
+	// Users maps assignees to Linear user IDs
+	Users map[string]string `mapstructure:"users"`
 }
 
-// NotionConfig holds Notion integration settings (future)
+// State returns the workflow state for a TODO status, or ""
+func (c LinearConfig) State(status string) string {
+	return lookup(c.States, nil, status)
+}
+
+// UserID returns the Linear user of an assignee, or ""
+func (c LinearConfig) UserID(user string) string {
+	return lookup(c.Users, nil, user)
+}
+
+// NotionConfig holds Notion integration settings
 type NotionConfig struct {
 	Token      string `mapstructure:"token"`
 	DatabaseID string `mapstructure:"database_id"`
 	Enabled    bool   `mapstructure:"enabled"`
+	// APIURL overrides the API endpoint
+	APIURL string `mapstructure:"api_url"`
+
+	// Properties renames the database properties a sync writes: status,
+	// priority, type, file, line, assignee, category, tags and due
+	Properties map[string]string `mapstructure:"properties"`
+	// Statuses maps TODO statuses to options of the status property
+	Statuses map[string]string `mapstructure:"statuses"`
+}
+
+// Default Notion property names and status options
+var (
+	DefaultNotionProperties = map[string]string{
+		"status":   "Status",
+		"priority": "Priority",
+		"type":     "Type",
+		"file":     "File",
+		"line":     "Line",
+		"assignee": "Assignee",
+		"category": "Category",
+		"tags":     "Tags",
+		"due":      "Due",
+	}
+	DefaultNotionStatuses = map[string]string{
+		"open":        "Open",
+		"in_progress": "In Progress",
+		"blocked":     "Blocked",
+		"resolved":    "Resolved",
+		"closed":      "Closed",
+		"wontfix":     "Won't Fix",
+	}
+)
+
+// Property returns the name of the database property for a field
+func (c NotionConfig) Property(field string) string {
+	return lookup(c.Properties, DefaultNotionProperties, field)
+}
+
+// Status returns the status option for a TODO status
+func (c NotionConfig) Status(status string) string {
+	return lookup(c.Statuses, DefaultNotionStatuses, status)
 }
 
 // NotificationsConfig holds notification settings
@@ -96,6 +329,38 @@ type StaleConfig struct {
 	DaysSinceUpdate int  `mapstructure:"days_since_update"`
 }
 
+// PolicyConfig holds the rules enforced by 'todo check'. Marker lists may
+// contain "*" to apply a rule to every marker.
+type PolicyConfig struct {
+	// RequireAssignee lists markers whose new TODOs must name an assignee
+	RequireAssignee []string `mapstructure:"require_assignee"`
+	// RequireIssue lists markers whose new TODOs must reference an issue
+	RequireIssue []string `mapstructure:"require_issue"`
+	// MaxPriority caps the number of open TODOs per priority, e.g. P0 = 0
+	MaxPriority map[string]int `mapstructure:"max_priority"`
+	// MaxAgeDays caps how long TODOs with a marker may stay open, e.g. HACK = 90
+	MaxAgeDays map[string]int `mapstructure:"max_age_days"`
+	// NoIncrease fails when the TODO count grows relative to the base
+	NoIncrease bool `mapstructure:"no_increase"`
+	// NoOverdue fails when open TODOs are past their due date
+	NoOverdue bool `mapstructure:"no_overdue"`
+	// NoStale fails when open TODOs are stale, as set by Stale
+	NoStale bool `mapstructure:"no_stale"`
+}
+
+// ServerConfig holds settings for 'todo serve'
+type ServerConfig struct {
+	// CORSOrigins lists the origins whose pages may call the API, such as
+	// http://localhost:5173; "*" allows any origin without credentials.
+	// Empty allows same-origin requests only.
+	CORSOrigins []string `mapstructure:"cors_origins"`
+	// AnonymousRole is the role of requests without a token or session once
+	// users exist: viewer, contributor, or empty to require signing in
+	AnonymousRole string `mapstructure:"anonymous_role"`
+	// SessionHours is how long a browser login lasts
+	SessionHours int `mapstructure:"session_hours"`
+}
+
 // DigestConfig holds daily digest settings
 type DigestConfig struct {
 	Enabled bool   `mapstructure:"enabled"`
@@ -110,17 +375,18 @@ func DefaultConfig() *Config {
 			".git", "node_modules", "vendor", "dist", "build",
 			".next", "__pycache__", ".cache", "coverage",
 		},
-		IncludePatterns: []string{},
-		TodoTypes:       []string{"TODO", "FIXME", "HACK", "BUG", "NOTE", "XXX"},
-		IgnoreCase:      true,
-		GitAuthor:       true,
-		ColorMode:       "auto",
-		DateFormat:      "2006-01-02",
-		Editor:          os.Getenv("EDITOR"),
-		OutputFormat:    "table",
-		ParallelWorkers: 4,
-		CacheTTL:        60,
-		Verbose:         false,
+		IncludePatterns:      []string{},
+		TodoTypes:            []string{"TODO", "FIXME", "HACK", "BUG", "NOTE", "XXX"},
+		IgnoreCase:           true,
+		AnnotationPrecedence: "code",
+		GitAuthor:            true,
+		ColorMode:            "auto",
+		DateFormat:           "2006-01-02",
+		Editor:               os.Getenv("EDITOR"),
+		OutputFormat:         "table",
+		ParallelWorkers:      4,
+		CacheTTL:             60,
+		Verbose:              false,
 		Notifications: NotificationsConfig{
 			Enabled:       false,
 			DueDaysBefore: []int{3, 1},
@@ -137,7 +403,12 @@ func DefaultConfig() *Config {
 			Time:    "08:00",
 			Include: "assigned,due-soon,stale",
 		},
+		Server: ServerConfig{
+			SessionHours: 24,
+		},
 		GitHub: GitHubConfig{},
+		GitLab: GitLabConfig{},
+		Gitea:  GiteaConfig{},
 		Jira:   JiraConfig{},
 		Linear: LinearConfig{
 			Enabled: false,
@@ -149,17 +420,20 @@ func DefaultConfig() *Config {
 }
 
 // Load loads configuration from file and environment
-func Load() *Config {
-	cfg := DefaultConfig()
-
+func Load() (*Config, error) {
 	// Get project path (current directory or specified)
 	projectPath, _ := os.Getwd()
+	return LoadFrom(projectPath)
+}
+
+// LoadFrom loads the configuration of the project at projectPath
+func LoadFrom(projectPath string) (*Config, error) {
+	cfg := DefaultConfig()
 	cfg.ProjectPath = projectPath
 
 	// Try to load from config file
-	homeDir, err := os.UserHomeDir()
+	configPath, err := Dir()
 	if err == nil {
-		configPath := filepath.Join(homeDir, ".config", "todolist")
 		viper.SetConfigType("toml")
 		viper.SetConfigName("config")
 		viper.AddConfigPath(configPath)
@@ -167,12 +441,39 @@ func Load() *Config {
 
 		// Merge with defaults
 		if err := viper.ReadInConfig(); err == nil {
-			viper.Unmarshal(cfg)
+			if err := viper.Unmarshal(cfg); err != nil {
+				return nil, fmt.Errorf("failed to load %s: %w", viper.ConfigFileUsed(), err)
+			}
+		}
+	}
+
+	// Project settings in .todo/config.toml override global ones
+	project := viper.New()
+	path := ProjectConfigPath(projectPath)
+	project.SetConfigFile(path)
+	if err := project.ReadInConfig(); err == nil {
+		if err := project.Unmarshal(cfg); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
 		}
 	}
 
 	// CLI flags override config file
 	// (handled in commands via viper)
 
-	return cfg
+	return cfg, nil
+}
+
+// Dir returns the user config directory holding the global config and the
+// project registry
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "todolist"), nil
+}
+
+// ProjectConfigPath returns where the project configuration for root is kept
+func ProjectConfigPath(root string) string {
+	return filepath.Join(root, ".todo", "config.toml")
 }
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry records a change made through the web server
+type AuditEntry struct {
+	ID        string    `gorm:"primaryKey;type:text" json:"id"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+	User      string    `gorm:"type:text;index" json:"user"`
+	Role      string    `gorm:"type:text" json:"role"`
+	Method    string    `gorm:"type:text;not null" json:"method"`
+	Path      string    `gorm:"type:text;not null" json:"path"`
+	Status    int       `gorm:"not null" json:"status"`
+	// Details is the JSON request body, without secrets
+	Details string `gorm:"type:text" json:"details,omitempty"`
+}
+
+// AddAuditEntry records an entry in the audit trail
+func (db *DB) AddAuditEntry(e *AuditEntry) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	return db.Create(e).Error
+}
+
+// GetAuditEntries returns the newest entries first, optionally only those
+// of one user; limit 0 returns all
+func (db *DB) GetAuditEntries(user string, limit int) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	query := db.Order("created_at DESC")
+	if user != "" {
+		query = query.Where("user = ?", user)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
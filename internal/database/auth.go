@@ -0,0 +1,299 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Roles of users of the web server, from least to most privileged
+const (
+	RoleViewer      = "viewer"      // read everything
+	RoleContributor = "contributor" // also change TODOs and their resources
+	RoleAdmin       = "admin"       // also manage users and read the audit trail
+)
+
+// Roles lists every role from least to most privileged
+var Roles = []string{RoleViewer, RoleContributor, RoleAdmin}
+
+// ErrInvalidCredentials is returned when a name, password, token or session
+// does not match
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// tokenPrefix starts every API token so leaked tokens are easy to spot
+const tokenPrefix = "todo_"
+
+// User is someone allowed to use the web server
+type User struct {
+	ID           string    `gorm:"primaryKey;type:text" json:"id"`
+	Name         string    `gorm:"type:text;uniqueIndex;not null" json:"name"`
+	Role         string    `gorm:"type:text;not null" json:"role"`
+	PasswordHash string    `gorm:"type:text" json:"-"`
+	CreatedAt    time.Time `gorm:"not null" json:"created_at"`
+}
+
+// APIToken authenticates scripts as a user. Only a hash of the token is
+// stored.
+type APIToken struct {
+	ID         string     `gorm:"primaryKey;type:text" json:"id"`
+	UserID     string     `gorm:"type:text;not null;index" json:"user_id"`
+	Name       string     `gorm:"type:text" json:"name"`
+	Hash       string     `gorm:"type:text;uniqueIndex;not null" json:"-"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+	LastUsedAt *time.Time `gorm:"type:timestamp" json:"last_used_at,omitempty"`
+}
+
+// Session is a browser login. Only a hash of the session cookie is stored.
+type Session struct {
+	Hash      string    `gorm:"primaryKey;type:text"`
+	UserID    string    `gorm:"type:text;not null;index"`
+	CreatedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	return roleRank(role) >= 0
+}
+
+// RoleAllows reports whether role has at least the privileges of required
+func RoleAllows(role, required string) bool {
+	rank := roleRank(role)
+	return rank >= 0 && rank >= roleRank(required)
+}
+
+func roleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasUsers reports whether any user exists; until then the server runs
+// without authentication
+func (db *DB) HasUsers() (bool, error) {
+	var count int64
+	err := db.Model(&User{}).Count(&count).Error
+	return count > 0, err
+}
+
+// CreateUser adds a user. Without a password the user can only sign in
+// with API tokens.
+func (db *DB) CreateUser(name, role, password string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	user := &User{ID: uuid.New().String(), Name: name, Role: role, CreatedAt: time.Now()}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := db.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPassword replaces the password of u; an empty password disables
+// password login. The user still has to be saved.
+func (u *User) SetPassword(password string) error {
+	if password == "" {
+		u.PasswordHash = ""
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// GetUser returns a user by name
+func (db *DB) GetUser(name string) (*User, error) {
+	var user User
+	if err := db.First(&user, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUsers returns all users
+func (db *DB) GetUsers() ([]User, error) {
+	var users []User
+	err := db.Order("name").Find(&users).Error
+	return users, err
+}
+
+// UpdateUser saves changes to a user
+func (db *DB) UpdateUser(u *User) error {
+	return db.Save(u).Error
+}
+
+// DeleteUser deletes a user with their tokens and sessions
+func (db *DB) DeleteUser(u *User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", u.ID).Delete(&APIToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", u.ID).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{}, "id = ?", u.ID).Error
+	})
+}
+
+// CountAdmins returns the number of admins
+func (db *DB) CountAdmins() (int64, error) {
+	var count int64
+	err := db.Model(&User{}).Where("role = ?", RoleAdmin).Count(&count).Error
+	return count, err
+}
+
+// Login checks a name and password
+func (db *DB) Login(name, password string) (*User, error) {
+	user, err := db.GetUser(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// CreateToken issues an API token for a user. The token itself is only
+// returned here.
+func (db *DB) CreateToken(userID, name string) (string, *APIToken, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	raw := tokenPrefix + secret
+	token := &APIToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Hash:      hashSecret(raw),
+		CreatedAt: time.Now(),
+	}
+	if err := db.Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+// GetTokens returns the tokens of a user, or of everyone when userID is empty
+func (db *DB) GetTokens(userID string) ([]APIToken, error) {
+	var tokens []APIToken
+	query := db.Order("created_at")
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Find(&tokens).Error
+	return tokens, err
+}
+
+// GetToken returns a token by ID
+func (db *DB) GetToken(id string) (*APIToken, error) {
+	var token APIToken
+	if err := db.First(&token, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeToken deletes a token
+func (db *DB) RevokeToken(id string) error {
+	return db.Delete(&APIToken{}, "id = ?", id).Error
+}
+
+// UserForToken returns the user an API token belongs to and notes its use
+func (db *DB) UserForToken(raw string) (*User, error) {
+	var token APIToken
+	err := db.First(&token, "hash = ?", hashSecret(raw)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	db.Model(&token).Update("last_used_at", &now)
+	return db.userByID(token.UserID)
+}
+
+// CreateSession starts a login session for a user and returns the value of
+// its cookie
+func (db *DB) CreateSession(userID string, ttl time.Duration) (string, *Session, error) {
+	raw, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	session := &Session{Hash: hashSecret(raw), UserID: userID, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	// Drop expired sessions while we are here
+	if err := db.Where("expires_at < ?", now).Delete(&Session{}).Error; err != nil {
+		return "", nil, err
+	}
+	if err := db.Create(session).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, session, nil
+}
+
+// UserForSession returns the user of an unexpired session
+func (db *DB) UserForSession(raw string) (*User, error) {
+	var session Session
+	err := db.First(&session, "hash = ? AND expires_at > ?", hashSecret(raw), time.Now()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.userByID(session.UserID)
+}
+
+// DeleteSession ends a session
+func (db *DB) DeleteSession(raw string) error {
+	return db.Delete(&Session{}, "hash = ?", hashSecret(raw)).Error
+}
+
+func (db *DB) userByID(id string) (*User, error) {
+	var user User
+	err := db.First(&user, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret hashes a token or session; they are random, so a plain hash
+// is enough
+func hashSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Change types recorded on the change bus
+const (
+	ChangeTODOCreated   = "todo.created"
+	ChangeTODOUpdated   = "todo.updated"
+	ChangeTODODeleted   = "todo.deleted"
+	ChangeScanCompleted = "scan.completed"
+	ChangeTimerStarted  = "timer.started"
+	ChangeTimerStopped  = "timer.stopped"
+)
+
+// DefaultPollInterval is how often a ChangeBus looks for changes recorded
+// by other processes
+const DefaultPollInterval = time.Second
+
+// changeRetention is how long recorded changes are kept for clients that
+// reconnect; older ones are pruned every pruneEvery changes
+const (
+	changeRetention = 24 * time.Hour
+	pruneEvery      = 100
+)
+
+// changeBuffer is how many changes a subscriber may fall behind before it
+// is dropped
+const changeBuffer = 64
+
+// Change is a modification recorded in the database so that every process
+// using it, such as a running 'todo serve', learns about edits made by
+// others
+type Change struct {
+	Seq       uint64    `gorm:"primaryKey;autoIncrement" json:"seq"`
+	Type      string    `gorm:"type:text;not null" json:"type"`
+	TODOID    string    `gorm:"type:text" json:"todo_id,omitempty"`
+	Data      string    `gorm:"type:text" json:"-"` // JSON
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+// RecordChange stores a change with data encoded as JSON and wakes the
+// change bus of this process
+func (db *DB) RecordChange(typ, todoID string, data interface{}) error {
+	change := Change{Type: typ, TODOID: todoID, CreatedAt: time.Now()}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode change: %w", err)
+		}
+		change.Data = string(encoded)
+	}
+	if err := db.Create(&change).Error; err != nil {
+		return fmt.Errorf("failed to record change: %w", err)
+	}
+	if change.Seq%pruneEvery == 0 {
+		db.Where("created_at < ?", change.CreatedAt.Add(-changeRetention)).Delete(&Change{})
+	}
+
+	select {
+	case db.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// GetChanges returns up to limit changes recorded after seq, oldest first
+func (db *DB) GetChanges(after uint64, limit int) ([]Change, error) {
+	var changes []Change
+	err := db.Where("seq > ?", after).Order("seq").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// LastChange returns the sequence number of the newest change, or zero
+func (db *DB) LastChange() (uint64, error) {
+	var seq uint64
+	err := db.Model(&Change{}).Select("COALESCE(MAX(seq), 0)").Scan(&seq).Error
+	return seq, err
+}
+
+// ChangeBus fans changes recorded by any process out to subscribers
+type ChangeBus struct {
+	// OnError, if set, is called when reading changes fails; the read is
+	// retried at the next poll
+	OnError func(error)
+
+	db       *DB
+	interval time.Duration
+	last     uint64
+
+	mu   sync.Mutex
+	subs map[chan Change]struct{}
+}
+
+// NewChangeBus returns a bus delivering changes recorded from now on. It
+// polls every interval, or DefaultPollInterval when zero, once Run starts.
+func (db *DB) NewChangeBus(interval time.Duration) (*ChangeBus, error) {
+	last, err := db.LastChange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes: %w", err)
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &ChangeBus{db: db, interval: interval, last: last, subs: make(map[chan Change]struct{})}, nil
+}
+
+// Subscribe returns a channel receiving every change delivered from now on
+// and a function that ends the subscription. The channel is closed when the
+// subscriber falls too far behind; it can catch up with GetChanges.
+func (b *ChangeBus) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, changeBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() { b.drop(ch) }
+}
+
+func (b *ChangeBus) drop(ch chan Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// Run delivers changes until ctx is done
+func (b *ChangeBus) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.db.changed:
+		}
+		if err := b.poll(); err != nil && b.OnError != nil {
+			b.OnError(err)
+		}
+	}
+}
+
+// poll delivers the changes recorded since the last poll
+func (b *ChangeBus) poll() error {
+	for {
+		changes, err := b.db.GetChanges(b.last, 500)
+		if err != nil {
+			return fmt.Errorf("failed to read changes: %w", err)
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		b.last = changes[len(changes)-1].Seq
+
+		b.mu.Lock()
+		var slow []chan Change
+		for ch := range b.subs {
+			for _, c := range changes {
+				select {
+				case ch <- c:
+					continue
+				default:
+				}
+				slow = append(slow, ch)
+				break
+			}
+		}
+		b.mu.Unlock()
+		for _, ch := range slow {
+			b.drop(ch)
+		}
+	}
+}
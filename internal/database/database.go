@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TODO represents a TODO comment entry
@@ -26,10 +28,23 @@ type TODO struct {
 	Status     string     `gorm:"type:text;default:'open'" json:"status"` // open, in_progress, blocked, resolved, wontfix, closed
 	Priority   string     `gorm:"type:text;default:'P3'" json:"priority"` // P0, P1, P2, P3, P4
 	Category   string     `gorm:"type:text" json:"category"`
+	Severity   string     `gorm:"type:text" json:"severity,omitempty"` // info, low, medium, high, critical
 	Assignee   string     `gorm:"type:text" json:"assignee"`
 	DueDate    *time.Time `gorm:"type:timestamp" json:"due_date,omitempty"`
 	Estimate   *int       `gorm:"type:integer" json:"estimate,omitempty"` // minutes
 	Hash       string     `gorm:"type:text;not null" json:"hash"`
+	// ContextHash fingerprints the code around the comment for reconciliation
+	ContextHash string `gorm:"type:text" json:"context_hash,omitempty"`
+	// RemovedAt and RemovedCommit record when a scan found the comment gone
+	RemovedAt     *time.Time `gorm:"type:timestamp" json:"removed_at,omitempty"`
+	RemovedCommit string     `gorm:"type:text" json:"removed_commit,omitempty"`
+	// IssueKeys are external issue references written in the comment
+	IssueKeys []string `gorm:"serializer:json" json:"issue_keys,omitempty"`
+}
+
+// IsDone reports whether a TODO status means the work is finished
+func IsDone(status string) bool {
+	return status == "resolved" || status == "closed" || status == "wontfix"
 }
 
 // Tag represents a tag for TODOs
@@ -73,6 +88,8 @@ type Watch struct {
 // DB represents the database connection
 type DB struct {
 	*gorm.DB
+	// changed wakes the change bus when this process records a change
+	changed chan struct{}
 }
 
 // New creates a new database connection
@@ -92,11 +109,11 @@ func New(projectPath string) (*DB, error) {
 	}
 
 	// Auto migrate
-	if err := gormDB.AutoMigrate(&TODO{}, &Tag{}, &TODOTag{}, &Project{}, &Relationship{}, &Watch{}); err != nil {
+	if err := gormDB.AutoMigrate(&TODO{}, &Tag{}, &TODOTag{}, &Project{}, &Relationship{}, &Watch{}, &TODOEvent{}, &TimeEntry{}, &SavedFilter{}, &User{}, &APIToken{}, &Session{}, &AuditEntry{}, &Change{}, &IssueLink{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	return &DB{DB: gormDB}, nil
+	return &DB{DB: gormDB, changed: make(chan struct{}, 1)}, nil
 }
 
 // CreateTODO creates a new TODO entry
@@ -104,7 +121,10 @@ func (db *DB) CreateTODO(t *TODO) error {
 	if t.ID == "" {
 		t.ID = uuid.New().String()
 	}
-	return db.Create(t).Error
+	if err := db.Create(t).Error; err != nil {
+		return err
+	}
+	return db.RecordChange(ChangeTODOCreated, t.ID, t)
 }
 
 // GetTODOs returns all TODOs with optional filters
@@ -154,12 +174,18 @@ func (db *DB) GetTODOByID(id string) (*TODO, error) {
 // UpdateTODO updates a TODO entry
 func (db *DB) UpdateTODO(t *TODO) error {
 	t.UpdatedAt = time.Now()
-	return db.Save(t).Error
+	if err := db.Save(t).Error; err != nil {
+		return err
+	}
+	return db.RecordChange(ChangeTODOUpdated, t.ID, t)
 }
 
 // DeleteTODO deletes a TODO entry
 func (db *DB) DeleteTODO(id string) error {
-	return db.Delete(&TODO{}, "id = ?", id).Error
+	if err := db.Delete(&TODO{}, "id = ?", id).Error; err != nil {
+		return err
+	}
+	return db.RecordChange(ChangeTODODeleted, id, nil)
 }
 
 // TODOExists checks if a TODO already exists by hash and location
@@ -230,6 +256,21 @@ func (db *DB) InitProject(name, path string) error {
 	return db.Create(&project).Error
 }
 
+// GetProjectByPath returns the project registered for a path, or
+// gorm.ErrRecordNotFound. Most paths have no row, so the lookup does not
+// go through First, which would log every miss.
+func (db *DB) GetProjectByPath(path string) (*Project, error) {
+	var project Project
+	result := db.Where("path = ?", path).Limit(1).Find(&project)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &project, nil
+}
+
 // CreateRelationship creates a new relationship between TODOs
 func (db *DB) CreateRelationship(sourceID, targetID, relType string) error {
 	rel := Relationship{
@@ -242,6 +283,93 @@ func (db *DB) CreateRelationship(sourceID, targetID, relType string) error {
 	return db.Create(&rel).Error
 }
 
+// Errors returned by AddRelationship
+var (
+	ErrRelationshipExists = errors.New("relationship already exists")
+	ErrCircularDependency = errors.New("circular dependency detected")
+)
+
+// inverseRelationships maps relationship types to the type recorded on the
+// other TODO; relates_to has no inverse
+var inverseRelationships = map[string]string{
+	"parent":     "child",
+	"child":      "parent",
+	"depends_on": "blocked_by",
+	"blocked_by": "depends_on",
+}
+
+// AddRelationship links two TODOs together with the inverse relationship, as
+// the relate command does. A TODO has one parent, so setting a parent
+// replaces the previous one.
+func (db *DB) AddRelationship(sourceID, targetID, relType string) (*Relationship, error) {
+	var count int64
+	if err := db.Model(&Relationship{}).Where("source_id = ? AND target_id = ? AND type = ?", sourceID, targetID, relType).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRelationshipExists
+	}
+	if relType == "depends_on" && db.HasCircularDependency(sourceID, targetID) {
+		return nil, ErrCircularDependency
+	}
+
+	rel := Relationship{
+		ID:        uuid.New().String(),
+		SourceID:  sourceID,
+		TargetID:  targetID,
+		Type:      relType,
+		CreatedAt: time.Now(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if relType == "parent" {
+			var previous []Relationship
+			if err := tx.Where("source_id = ? AND type = ?", sourceID, "parent").Find(&previous).Error; err != nil {
+				return err
+			}
+			for _, p := range previous {
+				if err := tx.Where("(id = ?) OR (source_id = ? AND target_id = ? AND type = ?)", p.ID, p.TargetID, sourceID, "child").Delete(&Relationship{}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Create(&rel).Error; err != nil {
+			return err
+		}
+		if inverse, ok := inverseRelationships[relType]; ok {
+			return tx.Create(&Relationship{
+				ID:        uuid.New().String(),
+				SourceID:  targetID,
+				TargetID:  sourceID,
+				Type:      inverse,
+				CreatedAt: rel.CreatedAt,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// GetRelationship returns a relationship by ID
+func (db *DB) GetRelationship(id string) (*Relationship, error) {
+	var rel Relationship
+	if err := db.First(&rel, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// RemoveRelationship deletes a relationship together with its inverse
+func (db *DB) RemoveRelationship(rel *Relationship) error {
+	query := db.Where("id = ?", rel.ID)
+	if inverse, ok := inverseRelationships[rel.Type]; ok {
+		query = query.Or("source_id = ? AND target_id = ? AND type = ?", rel.TargetID, rel.SourceID, inverse)
+	}
+	return query.Delete(&Relationship{}).Error
+}
+
 // GetRelationships returns all relationships for a TODO
 func (db *DB) GetRelationships(todoID string) ([]Relationship, error) {
 	var relationships []Relationship
@@ -397,7 +525,6 @@ func (db *DB) GetParent(todoID string) (*TODO, error) {
 	return &todo, nil
 }
 
-
 // GetRelatedTODOs returns TODOs related to a given TODO
 func (db *DB) GetRelatedTODOs(todoID string) ([]Relationship, error) {
 	var relationships []Relationship
@@ -447,6 +574,13 @@ func (db *DB) GetWatchedTODOs(userID string) ([]TODO, error) {
 	return todos, nil
 }
 
+// GetWatchers returns the watches on a TODO
+func (db *DB) GetWatchers(todoID string) ([]Watch, error) {
+	var watches []Watch
+	err := db.Where("todo_id = ?", todoID).Order("user_id").Find(&watches).Error
+	return watches, err
+}
+
 // IsWatching checks if a user is watching a TODO
 func (db *DB) IsWatching(todoID, userID string) bool {
 	var count int64
@@ -497,25 +631,45 @@ func (db *DB) GetTagsForTODO(todoID string) ([]Tag, error) {
 	return tags, err
 }
 
-// AddTagToTODO adds a tag to a TODO
+// AddTagToTODO adds a tag to a TODO; adding a tag twice is a no-op
 func (db *DB) AddTagToTODO(todoID, tagID string) error {
 	todoTag := TODOTag{
 		TODOID: todoID,
 		TagID:  tagID,
 	}
-	return db.Create(&todoTag).Error
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&todoTag).Error
+}
+
+// RemoveTagFromTODO removes a tag from a TODO
+func (db *DB) RemoveTagFromTODO(todoID, tagID string) error {
+	return db.Where("todo_id = ? AND tag_id = ?", todoID, tagID).Delete(&TODOTag{}).Error
+}
+
+// DeleteTag deletes a tag and removes it from every TODO
+func (db *DB) DeleteTag(id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&TODOTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Tag{}, "id = ?", id).Error
+	})
 }
 
 // GetOrCreateTag gets a tag by name or creates it if it doesn't exist
 func (db *DB) GetOrCreateTag(name string) (*Tag, error) {
 	var tag Tag
-	err := db.First(&tag, "name = ?", name).Error
-	if err == gorm.ErrRecordNotFound {
+	result := db.Where("name = ?", name).Limit(1).Find(&tag)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
 		tag = Tag{
 			ID:   uuid.New().String(),
 			Name: name,
 		}
-		err = db.Create(&tag).Error
+		if err := db.Create(&tag).Error; err != nil {
+			return nil, err
+		}
 	}
-	return &tag, err
+	return &tag, nil
 }
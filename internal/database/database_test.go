@@ -1,11 +1,14 @@
 package database
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestDB(t *testing.T) {
@@ -161,3 +164,203 @@ func TestGetTODOsByPriority(t *testing.T) {
 		assert.Equal(t, priority, todos[0].Priority)
 	}
 }
+
+func TestAddRelationship(t *testing.T) {
+	db, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		todo := TODO{FilePath: "test.go", LineNumber: i + 1, Type: "TODO", Content: "Test", Hash: "hash"}
+		require.NoError(t, db.CreateTODO(&todo))
+		ids = append(ids, todo.ID)
+	}
+
+	// Inverses are recorded
+	dep, err := db.AddRelationship(ids[0], ids[1], "depends_on")
+	require.NoError(t, err)
+	blockedBy, err := db.GetRelationshipsByType(ids[1], "blocked_by")
+	require.NoError(t, err)
+	require.Len(t, blockedBy, 1)
+	assert.Equal(t, ids[0], blockedBy[0].TargetID)
+
+	_, err = db.AddRelationship(ids[0], ids[1], "depends_on")
+	assert.ErrorIs(t, err, ErrRelationshipExists)
+	_, err = db.AddRelationship(ids[1], ids[0], "depends_on")
+	assert.ErrorIs(t, err, ErrCircularDependency)
+
+	// A new parent replaces the old one on both sides
+	_, err = db.AddRelationship(ids[0], ids[1], "parent")
+	require.NoError(t, err)
+	_, err = db.AddRelationship(ids[0], ids[2], "parent")
+	require.NoError(t, err)
+	parents, err := db.GetRelationshipsByType(ids[0], "parent")
+	require.NoError(t, err)
+	require.Len(t, parents, 1)
+	assert.Equal(t, ids[2], parents[0].TargetID)
+	children, err := db.GetRelationshipsByType(ids[1], "child")
+	require.NoError(t, err)
+	assert.Empty(t, children)
+
+	require.NoError(t, db.RemoveRelationship(dep))
+	blockedBy, err = db.GetRelationshipsByType(ids[1], "blocked_by")
+	require.NoError(t, err)
+	assert.Empty(t, blockedBy)
+}
+
+func TestAuthentication(t *testing.T) {
+	db, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	hasUsers, err := db.HasUsers()
+	require.NoError(t, err)
+	assert.False(t, hasUsers)
+
+	alice, err := db.CreateUser("alice", RoleContributor, "secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, "secret", alice.PasswordHash)
+
+	_, err = db.Login("alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = db.Login("nobody", "secret")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	user, err := db.Login("alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, user.ID)
+
+	raw, token, err := db.CreateToken(alice.ID, "ci")
+	require.NoError(t, err)
+	assert.NotContains(t, token.Hash, raw)
+	user, err = db.UserForToken(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Name)
+	token, err = db.GetToken(token.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, token.LastUsedAt)
+
+	// Expired sessions no longer authenticate
+	expired, _, err := db.CreateSession(alice.ID, -time.Minute)
+	require.NoError(t, err)
+	_, err = db.UserForSession(expired)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	session, _, err := db.CreateSession(alice.ID, time.Hour)
+	require.NoError(t, err)
+	_, err = db.UserForSession(session)
+	require.NoError(t, err)
+
+	// Deleting a user revokes their credentials
+	require.NoError(t, db.DeleteUser(alice))
+	_, err = db.UserForToken(raw)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = db.UserForSession(session)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	assert.True(t, RoleAllows(RoleAdmin, RoleContributor))
+	assert.False(t, RoleAllows(RoleViewer, RoleContributor))
+	assert.False(t, RoleAllows("", RoleViewer))
+}
+
+func TestChangeBus(t *testing.T) {
+	dir := t.TempDir()
+	db, err := New(dir)
+	require.NoError(t, err)
+	// Another process using the same project
+	other, err := New(dir)
+	require.NoError(t, err)
+
+	bus, err := db.NewChangeBus(10 * time.Millisecond)
+	require.NoError(t, err)
+	changes, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Run(ctx)
+
+	next := func() Change {
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change delivered")
+			return Change{}
+		}
+	}
+
+	todo := TODO{FilePath: "test.go", LineNumber: 1, Type: "TODO", Content: "Test", Hash: "hash"}
+	require.NoError(t, other.CreateTODO(&todo))
+	c := next()
+	assert.Equal(t, ChangeTODOCreated, c.Type)
+	assert.Equal(t, todo.ID, c.TODOID)
+	assert.Contains(t, c.Data, `"content":"Test"`)
+
+	_, err = db.StartTimer(todo.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeTimerStarted, next().Type)
+	require.NoError(t, db.DeleteTODO(todo.ID))
+	assert.Equal(t, ChangeTODODeleted, next().Type)
+
+	missed, err := db.GetChanges(c.Seq, 10)
+	require.NoError(t, err)
+	assert.Len(t, missed, 2)
+}
+
+func TestIssueLinks(t *testing.T) {
+	db, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	todo := TODO{FilePath: "test.go", LineNumber: 1, Type: "TODO", Content: "Test", Hash: "hash"}
+	require.NoError(t, db.CreateTODO(&todo))
+
+	link := IssueLink{TODOID: todo.ID, Tracker: "github:acme/app", Key: "7"}
+	require.NoError(t, db.SaveIssueLink(&link))
+	assert.NotEmpty(t, link.ID)
+	require.NoError(t, db.SaveIssueLink(&IssueLink{TODOID: todo.ID, Tracker: "jira:OPS", Key: "OPS-1"}))
+
+	// A TODO has one issue per tracker, and an issue one TODO
+	assert.Error(t, db.SaveIssueLink(&IssueLink{TODOID: todo.ID, Tracker: "github:acme/app", Key: "8"}))
+	assert.Error(t, db.SaveIssueLink(&IssueLink{TODOID: "other", Tracker: "github:acme/app", Key: "7"}))
+
+	link.Key = "9"
+	require.NoError(t, db.SaveIssueLink(&link))
+	links, err := db.GetIssueLinks("github:acme/app")
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "9", links[0].Key)
+
+	links, err = db.GetIssueLinksForTODO(todo.ID)
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+
+	require.NoError(t, db.DeleteIssueLink(&link))
+	links, err = db.GetIssueLinks("github:acme/app")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func TestGetProjectByPath(t *testing.T) {
+	db, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = db.GetProjectByPath("/p")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, db.InitProject("p", "/p"))
+	project, err := db.GetProjectByPath("/p")
+	require.NoError(t, err)
+	assert.Equal(t, "p", project.Name)
+}
+
+func TestGetOrCreateTag(t *testing.T) {
+	db, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	tag, err := db.GetOrCreateTag("security")
+	require.NoError(t, err)
+	again, err := db.GetOrCreateTag("security")
+	require.NoError(t, err)
+	assert.Equal(t, tag.ID, again.ID)
+
+	tags, err := db.GetTags()
+	require.NoError(t, err)
+	assert.Len(t, tags, 1)
+}
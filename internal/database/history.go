@@ -0,0 +1,58 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Event types recorded from git history
+const (
+	EventIntroduced = "introduced"
+	EventModified   = "modified"
+	EventMoved      = "moved"
+	EventRemoved    = "removed"
+)
+
+// TODOEvent records a commit that introduced, changed or removed a TODO
+type TODOEvent struct {
+	ID          string    `gorm:"primaryKey;type:text" json:"id"`
+	TODOID      string    `gorm:"type:text;not null;index" json:"todo_id"`
+	Type        string    `gorm:"type:text;not null" json:"type"` // introduced, modified, moved, removed
+	Commit      string    `gorm:"type:text;not null" json:"commit"`
+	Author      string    `gorm:"type:text" json:"author"`
+	Email       string    `gorm:"type:text" json:"email"`
+	FilePath    string    `gorm:"type:text" json:"file_path"`
+	LineNumber  int       `json:"line_number"`
+	Content     string    `gorm:"type:text" json:"content"`
+	CommittedAt time.Time `gorm:"not null" json:"committed_at"`
+}
+
+// ReplaceTODOEvents replaces the recorded history of a TODO
+func (db *DB) ReplaceTODOEvents(todoID string, events []TODOEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("todo_id = ?", todoID).Delete(&TODOEvent{}).Error; err != nil {
+			return err
+		}
+		for i := range events {
+			events[i].TODOID = todoID
+			if events[i].ID == "" {
+				events[i].ID = uuid.New().String()
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Create(&events).Error
+	})
+}
+
+// GetTODOEvents returns the history of a TODO, oldest first
+func (db *DB) GetTODOEvents(todoID string) ([]TODOEvent, error) {
+	var events []TODOEvent
+	if err := db.Where("todo_id = ?", todoID).Order("committed_at ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IssueLink ties a TODO to an issue in an external tracker, so that syncs
+// update the issue instead of creating another one
+type IssueLink struct {
+	ID     string `gorm:"primaryKey;type:text" json:"id"`
+	TODOID string `gorm:"type:text;not null;uniqueIndex:idx_issue_links_todo" json:"todo_id"`
+	// Tracker names the tracker and the place in it, such as
+	// "github:owner/repo"
+	Tracker string `gorm:"type:text;not null;uniqueIndex:idx_issue_links_todo;uniqueIndex:idx_issue_links_key" json:"tracker"`
+	Key     string `gorm:"type:text;not null;uniqueIndex:idx_issue_links_key" json:"key"` // issue number or key
+	URL     string `gorm:"type:text" json:"url,omitempty"`
+	// LocalHash and RemoteHash fingerprint the TODO and the issue as of the
+	// last sync, so the next one can tell which side changed
+	LocalHash  string    `gorm:"type:text" json:"-"`
+	RemoteHash string    `gorm:"type:text" json:"-"`
+	SyncedAt   time.Time `gorm:"not null" json:"synced_at"`
+}
+
+// GetIssueLinks returns the links of a tracker
+func (db *DB) GetIssueLinks(tracker string) ([]IssueLink, error) {
+	var links []IssueLink
+	if err := db.Where("tracker = ?", tracker).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// GetIssueLinksForTODO returns the issues a TODO is linked to
+func (db *DB) GetIssueLinksForTODO(todoID string) ([]IssueLink, error) {
+	var links []IssueLink
+	if err := db.Where("todo_id = ?", todoID).Order("tracker").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// SaveIssueLink creates or updates a link
+func (db *DB) SaveIssueLink(l *IssueLink) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+	if l.SyncedAt.IsZero() {
+		l.SyncedAt = time.Now()
+	}
+	return db.Save(l).Error
+}
+
+// DeleteIssueLink forgets a link; the issue is left alone
+func (db *DB) DeleteIssueLink(l *IssueLink) error {
+	return db.Delete(&IssueLink{}, "id = ?", l.ID).Error
+}
@@ -29,7 +29,7 @@ func (db *DB) StartTimer(todoID, description string) (*TimeEntry, error) {
 	if err := db.Create(entry).Error; err != nil {
 		return nil, err
 	}
-	return entry, nil
+	return entry, db.RecordChange(ChangeTimerStarted, todoID, entry)
 }
 
 // StopTimer stops a running timer
@@ -49,6 +49,15 @@ func (db *DB) StopTimer(todoID string) (*TimeEntry, error) {
 	if err := db.Save(&entry).Error; err != nil {
 		return nil, err
 	}
+	return &entry, db.RecordChange(ChangeTimerStopped, todoID, &entry)
+}
+
+// GetRunningTimer returns the timer running for a TODO
+func (db *DB) GetRunningTimer(todoID string) (*TimeEntry, error) {
+	var entry TimeEntry
+	if err := db.First(&entry, "todo_id = ? AND end_time IS NULL", todoID).Error; err != nil {
+		return nil, err
+	}
 	return &entry, nil
 }
 
@@ -0,0 +1,340 @@
+package diffscan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/git"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/reconcile"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxFileSize is the largest file version that is parsed
+const maxFileSize = 10 * 1024 * 1024
+
+// Options selects what is compared
+type Options struct {
+	// Base is the ref the working tree is compared against. The comparison
+	// starts from the merge base of Base and HEAD, so only changes made on
+	// the current branch are reported, as in a pull request.
+	Base string
+	// Staged compares the index against HEAD instead, ignoring Base
+	Staged bool
+}
+
+// Change is a TODO whose text or marker changed
+type Change struct {
+	Old parser.ParsedTODO
+	New parser.ParsedTODO
+}
+
+// Result lists the TODOs that differ between the two sides. File paths are
+// relative to the repository root and slash separated.
+type Result struct {
+	Root    string // repository root
+	Base    string // commit compared against
+	Added   []parser.ParsedTODO
+	Changed []Change
+	Removed []parser.ParsedTODO
+}
+
+// Diff reports the TODOs added, changed and removed in the repository
+// containing root. Only files that differ are parsed and nothing is stored.
+func Diff(root string, p *parser.Parser, opts Options) (*Result, error) {
+	repo, repoRoot, err := git.OpenRepo(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	var head *object.Commit
+	if ref, err := repo.Head(); err == nil {
+		if head, err = repo.CommitObject(ref.Hash()); err != nil {
+			return nil, fmt.Errorf("failed to read HEAD commit: %w", err)
+		}
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	// Only report files under root when it is a subdirectory
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := filepath.Rel(repoRoot, absRoot)
+	if err != nil || scope == "." {
+		scope = ""
+	}
+
+	d := &differ{repo: repo, root: repoRoot, scope: filepath.ToSlash(scope), parser: p, renames: make(map[string]string)}
+	if opts.Staged {
+		err = d.staged(head)
+	} else {
+		if head == nil {
+			return nil, fmt.Errorf("failed to resolve HEAD: repository has no commits")
+		}
+		err = d.branch(head, opts.Base)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return d.compare(), nil
+}
+
+// version reads one side of a file; it returns false when the file does not
+// exist on that side or is not parsed
+type version func(path string) ([]byte, bool)
+
+// differ collects the changed paths and how to read each side of them
+type differ struct {
+	repo    *gogit.Repository
+	root    string
+	scope   string // slash-separated subdirectory to report, or empty
+	parser  *parser.Parser
+	base    *object.Commit
+	paths   map[string]bool
+	renames map[string]string
+	old     version
+	new     version
+}
+
+// branch compares the working tree against the merge base of base and head
+func (d *differ) branch(head *object.Commit, base string) error {
+	hash, err := d.repo.ResolveRevision(plumbing.Revision(base))
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", base, err)
+	}
+	baseCommit, err := d.repo.CommitObject(*hash)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", base, err)
+	}
+	if bases, err := baseCommit.MergeBase(head); err == nil && len(bases) > 0 {
+		baseCommit = bases[0]
+	}
+	d.base = baseCommit
+
+	baseTree, err := baseCommit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %w", baseCommit.Hash, err)
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %w", head.Hash, err)
+	}
+
+	// Committed changes on the branch, with renames
+	changes, err := object.DiffTreeWithOptions(context.Background(), baseTree, headTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %w", baseCommit.Hash, err)
+	}
+	d.paths = make(map[string]bool)
+	for _, ch := range changes {
+		if ch.From.Name != "" {
+			d.paths[ch.From.Name] = true
+		}
+		if ch.To.Name != "" {
+			d.paths[ch.To.Name] = true
+		}
+		if ch.From.Name != "" && ch.To.Name != "" && ch.From.Name != ch.To.Name {
+			d.renames[ch.From.Name] = ch.To.Name
+		}
+	}
+
+	// Uncommitted changes on top
+	status, err := d.worktreeStatus()
+	if err != nil {
+		return err
+	}
+	for path, s := range status {
+		if s.Worktree != gogit.Unmodified || s.Staging != gogit.Unmodified {
+			d.paths[path] = true
+		}
+	}
+
+	d.old = treeVersion(baseTree)
+	d.new = d.diskVersion
+	return nil
+}
+
+// staged compares the index against head
+func (d *differ) staged(head *object.Commit) error {
+	var headTree *object.Tree
+	if head != nil {
+		d.base = head
+		var err error
+		if headTree, err = head.Tree(); err != nil {
+			return fmt.Errorf("failed to read tree of %s: %w", head.Hash, err)
+		}
+	}
+
+	status, err := d.worktreeStatus()
+	if err != nil {
+		return err
+	}
+	d.paths = make(map[string]bool)
+	for path, s := range status {
+		if s.Staging != gogit.Unmodified && s.Staging != gogit.Untracked {
+			d.paths[path] = true
+		}
+	}
+
+	idx, err := d.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	d.old = treeVersion(headTree)
+	d.new = func(path string) ([]byte, bool) {
+		entry, err := idx.Entry(path)
+		if err != nil || !entry.Mode.IsFile() || entry.Mode == filemode.Symlink || entry.Size > maxFileSize {
+			return nil, false
+		}
+		blob, err := d.repo.BlobObject(entry.Hash)
+		if err != nil {
+			return nil, false
+		}
+		return readBlob(blob)
+	}
+	return nil
+}
+
+func (d *differ) worktreeStatus() (gogit.Status, error) {
+	wt, err := d.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	return status, nil
+}
+
+// diskVersion reads the working copy of a file
+func (d *differ) diskVersion(path string) ([]byte, bool) {
+	absPath := filepath.Join(d.root, filepath.FromSlash(path))
+	info, err := os.Lstat(absPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+		return nil, false
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// treeVersion reads files from a tree, which may be nil for an empty side
+func treeVersion(tree *object.Tree) version {
+	return func(path string) ([]byte, bool) {
+		if tree == nil {
+			return nil, false
+		}
+		f, err := tree.File(path)
+		if err != nil || !f.Mode.IsFile() || f.Mode == filemode.Symlink || f.Size > maxFileSize {
+			return nil, false
+		}
+		return readBlob(&f.Blob)
+	}
+}
+
+func readBlob(blob *object.Blob) ([]byte, bool) {
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// compare parses both sides of every changed path and reconciles them
+func (d *differ) compare() *Result {
+	paths := make([]string, 0, len(d.paths))
+	for path := range d.paths {
+		if d.scope != "" && !strings.HasPrefix(path, d.scope+"/") {
+			continue
+		}
+		if !d.parser.Ignored(filepath.Join(d.root, filepath.FromSlash(path))) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var before, after []parser.ParsedTODO
+	for _, path := range paths {
+		if content, ok := d.old(path); ok {
+			before = append(before, d.parser.ParseContent(path, content)...)
+		}
+		if content, ok := d.new(path); ok {
+			after = append(after, d.parser.ParseContent(path, content)...)
+		}
+	}
+
+	oldItems := make([]reconcile.Item, len(before))
+	for i, t := range before {
+		oldItems[i] = item(t)
+	}
+	newItems := make([]reconcile.Item, len(after))
+	for i, t := range after {
+		newItems[i] = item(t)
+	}
+	match := reconcile.Reconcile(oldItems, newItems, reconcile.Options{Renames: d.renames})
+
+	result := &Result{Root: d.root}
+	if d.base != nil {
+		result.Base = d.base.Hash.String()
+	}
+	for _, m := range match.Matches {
+		o, n := before[m.Old], after[m.New]
+		if o.Content != n.Content || o.Type != n.Type {
+			result.Changed = append(result.Changed, Change{Old: o, New: n})
+		}
+	}
+	for _, i := range match.Added {
+		result.Added = append(result.Added, after[i])
+	}
+	for _, i := range match.Missing {
+		result.Removed = append(result.Removed, before[i])
+	}
+
+	sortTODOs(result.Added)
+	sortTODOs(result.Removed)
+	sort.SliceStable(result.Changed, func(i, j int) bool {
+		return less(result.Changed[i].New, result.Changed[j].New)
+	})
+	return result
+}
+
+func sortTODOs(todos []parser.ParsedTODO) {
+	sort.SliceStable(todos, func(i, j int) bool { return less(todos[i], todos[j]) })
+}
+
+func less(a, b parser.ParsedTODO) bool {
+	if a.FilePath != b.FilePath {
+		return a.FilePath < b.FilePath
+	}
+	return a.LineNumber < b.LineNumber
+}
+
+func item(t parser.ParsedTODO) reconcile.Item {
+	return reconcile.Item{
+		FilePath:    t.FilePath,
+		LineNumber:  t.LineNumber,
+		Type:        t.Type,
+		Content:     t.Content,
+		ContextHash: t.ContextHash,
+	}
+}
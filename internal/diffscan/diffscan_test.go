@@ -0,0 +1,110 @@
+package diffscan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func commit(t *testing.T, repo *git.Repository, dir string, files map[string]string) {
+	t.Helper()
+	write(t, dir, files)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	for name := range files {
+		_, err := wt.Add(name)
+		require.NoError(t, err)
+	}
+	_, err = wt.Commit("change", &git.CommitOptions{
+		Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+}
+
+func newParser(dir string) *parser.Parser {
+	p := parser.New(nil, nil, nil)
+	p.SetRoot(dir)
+	return p
+}
+
+func TestDiffAgainstBranch(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	commit(t, repo, dir, map[string]string{
+		"a.go": "package main\n\n// TODO: handle errors\nfunc a() {}\n",
+		"b.go": "package main\n\n// FIXME: leaks handles\nfunc b() {}\n",
+		"c.go": "package main\n\n// TODO: untouched\nfunc c() {}\n",
+	})
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/base", head.Hash())))
+
+	// One committed change and some uncommitted ones on the branch
+	commit(t, repo, dir, map[string]string{
+		"a.go": "package main\n\n// TODO: handle all errors\nfunc a() {}\n",
+	})
+	write(t, dir, map[string]string{
+		"b.go": "package main\n\nfunc b() {}\n",
+		"d.go": "package main\n\n// TODO(bob)[P1]: new work\nfunc d() {}\n",
+	})
+
+	result, err := Diff(dir, newParser(dir), Options{Base: "base"})
+	require.NoError(t, err)
+	assert.Equal(t, head.Hash().String(), result.Base)
+
+	require.Len(t, result.Added, 1)
+	assert.Equal(t, "d.go", result.Added[0].FilePath)
+	assert.Equal(t, "bob", result.Added[0].Assignee)
+	assert.Equal(t, "P1", result.Added[0].Priority)
+
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, "handle errors", result.Changed[0].Old.Content)
+	assert.Equal(t, "handle all errors", result.Changed[0].New.Content)
+
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, "b.go", result.Removed[0].FilePath)
+}
+
+func TestDiffStaged(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	commit(t, repo, dir, map[string]string{
+		"a.go": "package main\n\n// TODO: handle errors\nfunc a() {}\n",
+	})
+
+	// Only the staged file is reported
+	write(t, dir, map[string]string{
+		"a.go": "package main\n\nfunc a() {}\n",
+		"b.go": "package main\n\n// TODO: staged\nfunc b() {}\n",
+		"c.go": "package main\n\n// TODO: not staged\nfunc c() {}\n",
+	})
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add("b.go")
+	require.NoError(t, err)
+
+	result, err := Diff(dir, newParser(dir), Options{Staged: true})
+	require.NoError(t, err)
+	require.Len(t, result.Added, 1)
+	assert.Equal(t, "staged", result.Added[0].Content)
+	assert.Empty(t, result.Changed)
+	assert.Empty(t, result.Removed)
+}
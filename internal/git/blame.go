@@ -0,0 +1,236 @@
+package git
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ErrNotRepository is returned when a path is not inside a git repository
+var ErrNotRepository = git.ErrRepositoryNotExists
+
+// Author represents git author information
+type Author struct {
+	Name  string
+	Email string
+	Date  time.Time
+}
+
+// Blamer attributes lines to the commits that last changed them, using
+// go-git so no git binary is needed. Results are cached per file for the
+// current HEAD commit, in memory and optionally on disk.
+type Blamer struct {
+	repo      *git.Repository
+	root      string
+	head      *object.Commit
+	cachePath string
+
+	mu    sync.Mutex
+	files map[string]map[int]Author // keyed by repository-relative path
+	dirty bool
+}
+
+// blameCacheFile is the on-disk form of a Blamer's cache
+type blameCacheFile struct {
+	Head  string
+	Files map[string]map[int]Author
+}
+
+// NewBlamer opens the repository containing path for blaming at HEAD. If
+// cachePath is not empty, blame results are loaded from and saved to it;
+// a cache written for a different HEAD is discarded.
+func NewBlamer(path, cachePath string) (*Blamer, error) {
+	repo, root, err := OpenRepo(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+
+	b := &Blamer{
+		repo:      repo,
+		root:      root,
+		head:      head,
+		cachePath: cachePath,
+		files:     make(map[string]map[int]Author),
+	}
+	if cachePath != "" {
+		b.load()
+	}
+	return b, nil
+}
+
+// Blame returns the author of each line of the working copy of filePath,
+// keyed by 1-based line number. Lines that are not committed yet, and files
+// that are not in HEAD at all, have no entry.
+func (b *Blamer) Blame(filePath string) (map[int]Author, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(b.root, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return nil, fmt.Errorf("%s is outside the repository at %s", filePath, b.root)
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	committed, err := b.blameHead(relPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(committed) == 0 {
+		return committed, nil
+	}
+
+	// Map working copy lines onto HEAD lines so uncommitted edits above a
+	// line do not shift its attribution
+	working, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := b.head.File(relPath)
+	if err != nil {
+		return nil, err
+	}
+	original, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	if original == string(working) {
+		return committed, nil
+	}
+
+	result := make(map[int]Author)
+	headLine, workLine := 1, 1
+	for _, d := range diff.Do(original, string(working)) {
+		n := strings.Count(d.Text, "\n")
+		if !strings.HasSuffix(d.Text, "\n") {
+			n++
+		}
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			for i := 0; i < n; i++ {
+				if a, ok := committed[headLine+i]; ok {
+					result[workLine+i] = a
+				}
+			}
+			headLine += n
+			workLine += n
+		case diffmatchpatch.DiffDelete:
+			headLine += n
+		case diffmatchpatch.DiffInsert:
+			workLine += n
+		}
+	}
+	return result, nil
+}
+
+// blameHead blames a file as it is at HEAD, using the cache when possible
+func (b *Blamer) blameHead(relPath string) (map[int]Author, error) {
+	b.mu.Lock()
+	cached, ok := b.files[relPath]
+	b.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	lines := make(map[int]Author)
+	result, err := git.Blame(b.head, relPath)
+	if errors.Is(err, object.ErrFileNotFound) {
+		// Untracked or newly added: nothing is committed yet
+		return lines, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to blame %s: %w", relPath, err)
+	}
+	for i, line := range result.Lines {
+		lines[i+1] = Author{Name: line.AuthorName, Email: line.Author, Date: line.Date}
+	}
+
+	b.mu.Lock()
+	b.files[relPath] = lines
+	b.dirty = true
+	b.mu.Unlock()
+	return lines, nil
+}
+
+// load reads the on-disk cache, keeping it only if it was written for HEAD
+func (b *Blamer) load() {
+	f, err := os.Open(b.cachePath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var cf blameCacheFile
+	if err := gob.NewDecoder(f).Decode(&cf); err != nil || cf.Head != b.head.Hash.String() {
+		return
+	}
+	if cf.Files != nil {
+		b.files = cf.Files
+	}
+}
+
+// Save writes the cache to disk if anything new was blamed
+func (b *Blamer) Save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cachePath == "" || !b.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(b.cachePath), 0755); err != nil {
+		return fmt.Errorf("failed to create blame cache directory: %w", err)
+	}
+
+	tmp := b.cachePath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write blame cache: %w", err)
+	}
+	cf := blameCacheFile{Head: b.head.Hash.String(), Files: b.files}
+	if err := gob.NewEncoder(f).Encode(&cf); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write blame cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write blame cache: %w", err)
+	}
+	if err := os.Rename(tmp, b.cachePath); err != nil {
+		return fmt.Errorf("failed to write blame cache: %w", err)
+	}
+	b.dirty = false
+	return nil
+}
+
+// GetBlame blames a file at HEAD and returns the author of each line of its
+// working copy
+func GetBlame(filePath string) (map[int]Author, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewBlamer(filepath.Dir(absPath), "")
+	if err != nil {
+		return nil, err
+	}
+	return b.Blame(absPath)
+}
@@ -1,141 +1,186 @@
 package git
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"regexp"
-	"strconv"
-	"strings"
+	"path/filepath"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// Author represents git author information
-type Author struct {
-	Name  string
-	Email string
-	Date  time.Time
-}
-
-// BlameResult contains blame information for a file
-type BlameResult struct {
-	Lines []BlameLine
-}
-
-// BlameLine represents a single line's blame info
-type BlameLine struct {
-	LineNumber int
-	Author     string
-	Email      string
-	Date       time.Time
-	CommitHash string
+// IsRepo checks if the current directory is a git repository
+func IsRepo() bool {
+	_, err := git.PlainOpen(".")
+	return err == nil
 }
 
-// GetBlame runs git blame on a file and returns author information
-func GetBlame(filePath string) (map[int]Author, error) {
-	result := make(map[int]Author)
-
-	// Try using go-git first
+// GetCurrentBranch returns the current git branch name
+func GetCurrentBranch() (string, error) {
 	repo, err := git.PlainOpen(".")
 	if err != nil {
-		// Fall back to command line
-		return getBlameCLI(filePath)
+		return "", err
 	}
 
-	if _, err := repo.Head(); err != nil {
-		return getBlameCLI(filePath)
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
 	}
 
-	// Use the correct go-git blame API
-	// For now, fall back to CLI as the go-git blame API is complex
-	return getBlameCLI(filePath)
-
-	return result, nil
+	return head.Name().Short(), nil
 }
 
-func getBlameCLI(filePath string) (map[int]Author, error) {
-	result := make(map[int]Author)
+// OpenRepo opens the git repository containing path and returns it along
+// with the absolute path of its worktree root
+func OpenRepo(path string) (*git.Repository, string, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, wt.Filesystem.Root(), nil
+}
 
-	cmd := exec.Command("git", "blame", "--line-porcelain", filePath)
-	output, err := cmd.Output()
+// DetectRenames walks recent history from HEAD and returns the files that
+// were renamed, keyed by their old absolute path. Commits older than since
+// are ignored unless since is zero; at most maxCommits commits are examined.
+// Chains of renames are collapsed so each key maps to the latest path.
+func DetectRenames(path string, since time.Time, maxCommits int) (map[string]string, error) {
+	repo, root, err := OpenRepo(path)
 	if err != nil {
-		return result, nil // Return empty result on failure
+		return nil, err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	var currentAuthor Author
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
 
-	authorRegex := regexp.MustCompile(`author (.+)`)
-	emailRegex := regexp.MustCompile(`author-mail <(.+)>`)
-	dateRegex := regexp.MustCompile(`author-time (\d+)`)
-	lineRegex := regexp.MustCompile(`^\t(\d+)$`)
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	// Collect commits newest first, then replay oldest first so chains resolve
+	var commits []*object.Commit
+	for len(commits) < maxCommits || maxCommits <= 0 {
+		c, err := iter.Next()
+		if err != nil {
+			break
+		}
+		if !since.IsZero() && c.Committer.When.Before(since) {
+			break
+		}
+		commits = append(commits, c)
+	}
 
-	for _, line := range lines {
-		if match := authorRegex.FindStringSubmatch(line); match != nil {
-			currentAuthor = Author{Name: match[1]}
+	renames := make(map[string]string)
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		if c.NumParents() == 0 {
+			continue
 		}
-		if match := emailRegex.FindStringSubmatch(line); match != nil {
-			currentAuthor.Email = match[1]
+		parent, err := c.Parent(0)
+		if err != nil {
+			continue
 		}
-		if match := dateRegex.FindStringSubmatch(line); match != nil {
-			ts, _ := strconv.ParseInt(match[1], 10, 64)
-			currentAuthor.Date = time.Unix(ts, 0)
+		from, err := parent.Tree()
+		if err != nil {
+			continue
 		}
-		if match := lineRegex.FindStringSubmatch(line); match != nil {
-			lineNum, _ := strconv.Atoi(match[1])
-			if currentAuthor.Name != "" {
-				result[lineNum] = currentAuthor
+		to, err := c.Tree()
+		if err != nil {
+			continue
+		}
+		changes, err := object.DiffTreeWithOptions(context.Background(), from, to, object.DefaultDiffTreeOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff commit %s: %w", c.Hash, err)
+		}
+		for _, ch := range changes {
+			if ch.From.Name == "" || ch.To.Name == "" || ch.From.Name == ch.To.Name {
+				continue
 			}
+			oldPath := filepath.Join(root, filepath.FromSlash(ch.From.Name))
+			newPath := filepath.Join(root, filepath.FromSlash(ch.To.Name))
+			// Re-point earlier renames that ended at this file
+			for k, v := range renames {
+				if v == oldPath {
+					renames[k] = newPath
+				}
+			}
+			renames[oldPath] = newPath
 		}
 	}
 
-	return result, nil
+	return renames, nil
 }
 
-// IsRepo checks if the current directory is a git repository
-func IsRepo() bool {
-	_, err := git.PlainOpen(".")
-	return err == nil
-}
+// FindRemovingCommits returns the commit that removed each of keys from
+// filePath, walking the commits that touched the file once, newest first.
+// keysOf reports the keys a version of the file holds. A key is removed by
+// the commit just newer than the first one still holding it; keys held at
+// HEAD map to "", as their removal is uncommitted, and keys not found
+// within maxCommits are left out.
+func FindRemovingCommits(filePath string, keys []string, maxCommits int, keysOf func(content []byte) map[string]bool) (map[string]string, error) {
+	repo, root, err := OpenRepo(filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
 
-// GetCurrentBranch returns the current git branch name
-func GetCurrentBranch() (string, error) {
-	repo, err := git.PlainOpen(".")
+	relPath, err := filepath.Rel(root, filePath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	relPath = filepath.ToSlash(relPath)
 
 	head, err := repo.Head()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return head.Name().Short(), nil
-}
-
-// GetCommits TODO appears in returns commits that modified a TODO
-func GetCommits(filePath string, lineNumber int) ([]string, error) {
-	cmd := exec.Command("git", "log", "-n", "10", "-p", "--follow",
-		"-S", fmt.Sprintf("TODO"), "--", filePath)
-	output, err := cmd.Output()
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash(), FileName: &relPath})
 	if err != nil {
 		return nil, err
 	}
-
-	lines := strings.Split(string(output), "\n")
-	var commits []string
-	commitRegex := regexp.MustCompile(`^commit ([a-f0-9]+)`)
-
-	for _, line := range lines {
-		if match := commitRegex.FindStringSubmatch(line); match != nil {
-			commits = append(commits, match[1])
+	defer iter.Close()
+
+	commits := make(map[string]string)
+	var newer string
+	for i := 0; (i < maxCommits || maxCommits <= 0) && len(commits) < len(keys); i++ {
+		c, err := iter.Next()
+		if err != nil {
+			break
+		}
+		held := keysOf(fileContents(c, relPath))
+		for _, key := range keys {
+			if _, done := commits[key]; !done && held[key] {
+				commits[key] = newer
+			}
 		}
+		newer = c.Hash.String()
 	}
-
 	return commits, nil
 }
 
+// fileContents returns the content of the file at path in commit c, or nil
+// if it does not exist there
+func fileContents(c *object.Commit, path string) []byte {
+	f, err := c.File(path)
+	if err != nil {
+		return nil
+	}
+	text, err := f.Contents()
+	if err != nil {
+		return nil
+	}
+	return []byte(text)
+}
+
 // InitRepo initializes a git repository if not already one
 func InitRepo() error {
 	_, err := git.PlainInit(".", false)
@@ -0,0 +1,155 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFile writes content to name inside the repository and commits it
+func commitFile(t *testing.T, repo *git.Repository, dir, name, content string) string {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := wt.Commit("update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash.String()
+}
+
+func TestFindRemovingCommits(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, repo, dir, "main.go", "package main\n\n// TODO: remove me\n// TODO: keep me\n")
+	commitFile(t, repo, dir, "other.go", "package main\n")
+	removal := commitFile(t, repo, dir, "main.go", "package main\n\n// TODO: keep me\n// remove me later\n")
+	commitFile(t, repo, dir, "other.go", "package main\n\nfunc x() {}\n")
+
+	// Keys are whole lines, so the comment left behind does not count
+	keysOf := func(content []byte) map[string]bool {
+		keys := make(map[string]bool)
+		for _, line := range strings.Split(string(content), "\n") {
+			keys[line] = true
+		}
+		return keys
+	}
+	commits, err := FindRemovingCommits(filepath.Join(dir, "main.go"),
+		[]string{"// TODO: remove me", "// TODO: keep me", "// TODO: never there"}, 0, keysOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"// TODO: remove me": removal, "// TODO: keep me": ""}
+	if !reflect.DeepEqual(commits, want) {
+		t.Errorf("expected %v, got %v", want, commits)
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "package main\n\n// TODO: keep me\nfunc main() {}\n"
+	commitFile(t, repo, dir, "a.go", content)
+
+	wt, _ := repo.Worktree()
+	if _, err := wt.Move("a.go", "b.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("rename", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	renames, err := DetectRenames(dir, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renames[filepath.Join(dir, "a.go")]; got != filepath.Join(dir, "b.go") {
+		t.Errorf("expected a.go to map to b.go, got %q", got)
+	}
+}
+
+func TestBlamer(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, dir, "main.go", "package main\n\n// TODO: handle errors\n")
+
+	// Uncommitted lines above the TODO shift it down in the working copy
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("// header\npackage main\n\n// TODO: handle errors\n// new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cachePath := filepath.Join(dir, ".todo", "blame-cache.gob")
+	b, err := NewBlamer(dir, cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := b.Blame(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := lines[4]; !ok || a.Name != "Test" || a.Email != "test@example.com" {
+		t.Errorf("expected line 4 blamed to Test, got %+v", lines)
+	}
+	if _, ok := lines[1]; ok {
+		t.Error("expected uncommitted line 1 to have no author")
+	}
+	if _, ok := lines[5]; ok {
+		t.Error("expected uncommitted line 5 to have no author")
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new blamer at the same HEAD reuses the cache
+	cached, err := NewBlamer(dir, cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.files["main.go"]) != 3 {
+		t.Errorf("expected cached blame of main.go, got %v", cached.files)
+	}
+
+	// Untracked files have no committed lines
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	untracked, err := b.Blame(filepath.Join(dir, "new.go"))
+	if err != nil || len(untracked) != 0 {
+		t.Errorf("expected no authors for untracked file, got %v, %v", untracked, err)
+	}
+
+	if _, err := NewBlamer(t.TempDir(), ""); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("expected ErrNotRepository, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Hooks are the git hooks managed by the CLI
+var Hooks = []string{"pre-commit", "post-commit", "post-merge"}
+
+// hookMarker identifies hook scripts written by InstallHooks
+const hookMarker = "# Installed by todo hooks install"
+
+// chainedSuffix is appended to the name of a hook that existed before ours;
+// our hook runs it first
+const chainedSuffix = ".todo-chained"
+
+// HookOptions configures installed hooks
+type HookOptions struct {
+	// Binary is the todo executable to run; "todo" on PATH is used when it
+	// no longer exists
+	Binary string
+	// Block makes the pre-commit hook fail commits that break the policy
+	Block bool
+}
+
+// HookState describes one hook in a repository
+type HookState struct {
+	Name      string
+	Installed bool // our hook is in place
+	Chained   bool // a previous hook is run by ours
+	Foreign   bool // a hook we did not write is in place
+}
+
+// HooksDir returns the hooks directory of the repository containing path,
+// honouring core.hooksPath
+func HooksDir(path string) (string, error) {
+	repo, root, err := OpenRepo(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+	if cfg, err := repo.Config(); err == nil {
+		if hooksPath := cfg.Raw.Section("core").Option("hooksPath"); hooksPath != "" {
+			if !filepath.IsAbs(hooksPath) {
+				hooksPath = filepath.Join(root, hooksPath)
+			}
+			return hooksPath, nil
+		}
+	}
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", fmt.Errorf("repository at %s has no hooks directory", root)
+	}
+	return filepath.Join(storage.Filesystem().Root(), "hooks"), nil
+}
+
+// InstallHooks writes the managed hooks into the repository containing
+// path. A hook that is already there is kept and run before ours.
+func InstallHooks(path string, opts HookOptions) ([]HookState, error) {
+	dir, err := HooksDir(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	var states []HookState
+	for _, name := range Hooks {
+		hookPath := filepath.Join(dir, name)
+		chainedPath := hookPath + chainedSuffix
+
+		ours, err := isOurHook(hookPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return states, fmt.Errorf("failed to read %s hook: %w", name, err)
+		}
+		if err == nil && !ours {
+			if _, err := os.Stat(chainedPath); err == nil {
+				return states, fmt.Errorf("cannot chain %s hook: %s already exists", name, chainedPath)
+			}
+			if err := os.Rename(hookPath, chainedPath); err != nil {
+				return states, fmt.Errorf("failed to keep existing %s hook: %w", name, err)
+			}
+		}
+
+		if err := os.WriteFile(hookPath, []byte(hookScript(name, opts)), 0755); err != nil {
+			return states, fmt.Errorf("failed to write %s hook: %w", name, err)
+		}
+		_, chainErr := os.Stat(chainedPath)
+		states = append(states, HookState{Name: name, Installed: true, Chained: chainErr == nil})
+	}
+	return states, nil
+}
+
+// UninstallHooks removes the managed hooks and restores the hooks they
+// chained to. Hooks written by others are left alone.
+func UninstallHooks(path string) ([]HookState, error) {
+	dir, err := HooksDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []HookState
+	for _, name := range Hooks {
+		hookPath := filepath.Join(dir, name)
+		chainedPath := hookPath + chainedSuffix
+
+		// Installed reports that our hook was removed, Chained that the
+		// previous hook was put back
+		state := HookState{Name: name}
+		ours, err := isOurHook(hookPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return states, fmt.Errorf("failed to read %s hook: %w", name, err)
+		case !ours:
+			states = append(states, HookState{Name: name, Foreign: true})
+			continue
+		default:
+			if err := os.Remove(hookPath); err != nil {
+				return states, fmt.Errorf("failed to remove %s hook: %w", name, err)
+			}
+			state.Installed = true
+		}
+
+		if _, err := os.Stat(chainedPath); err == nil {
+			if err := os.Rename(chainedPath, hookPath); err != nil {
+				return states, fmt.Errorf("failed to restore %s hook: %w", name, err)
+			}
+			state.Chained = true
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// HookStatus reports the state of each managed hook
+func HookStatus(path string) ([]HookState, error) {
+	dir, err := HooksDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []HookState
+	for _, name := range Hooks {
+		hookPath := filepath.Join(dir, name)
+		state := HookState{Name: name}
+		ours, err := isOurHook(hookPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return states, fmt.Errorf("failed to read %s hook: %w", name, err)
+		}
+		if err == nil {
+			state.Installed = ours
+			state.Foreign = !ours
+		}
+		if _, err := os.Stat(hookPath + chainedSuffix); err == nil {
+			state.Chained = true
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func isOurHook(path string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(content), hookMarker), nil
+}
+
+// hookScript returns the shell script for a hook. The chained hook runs
+// first and its failure is passed on; the scan itself never fails a commit.
+// Set TODO_SKIP_HOOKS=1 to skip our part.
+func hookScript(name string, opts HookOptions) string {
+	binary := opts.Binary
+	if binary == "" {
+		binary = "todo"
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString(hookMarker + "; remove with: todo hooks uninstall\n\n")
+	fmt.Fprintf(&b, "chained=\"$(dirname \"$0\")/%s%s\"\n", name, chainedSuffix)
+	b.WriteString("if [ -x \"$chained\" ]; then\n\t\"$chained\" \"$@\" || exit $?\nfi\n\n")
+	b.WriteString("[ \"$TODO_SKIP_HOOKS\" = 1 ] && exit 0\n")
+	fmt.Fprintf(&b, "todo=%s\n", shellQuote(binary))
+	b.WriteString("[ -x \"$todo\" ] || todo=todo\n")
+	b.WriteString("command -v \"$todo\" >/dev/null 2>&1 || exit 0\n\n")
+
+	if name == "pre-commit" && opts.Block {
+		b.WriteString("\"$todo\" check --staged || exit 1\n")
+	}
+	// Only the files the hook is about are rescanned. Renames are listed as
+	// both paths so TODOs follow the file.
+	list := changedFiles[name]
+	fmt.Fprintf(&b, "git %s --name-only --no-renames -z", list[0])
+	if list[1] != "" {
+		b.WriteString(" " + list[1])
+	}
+	b.WriteString(" | \"$todo\" scan --files-from - >/dev/null 2>&1\n")
+	b.WriteString("exit 0\n")
+	return b.String()
+}
+
+// changedFiles holds, for each hook, the git command and revisions listing
+// the files it concerns: those staged, those in the new commit, and those a
+// merge changed.
+// Post-commit and post-merge rescans refresh line numbers and authors now
+// that the commit exists.
+var changedFiles = map[string][2]string{
+	"pre-commit":  {"diff --cached", ""},
+	"post-commit": {"diff-tree -r --root --no-commit-id", "HEAD"},
+	"post-merge":  {"diff", "ORIG_HEAD HEAD"},
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
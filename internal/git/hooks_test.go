@@ -0,0 +1,65 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestInstallHooksChainsAndUninstalls(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	hooksDir := filepath.Join(dir, ".git", "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := "#!/bin/sh\necho lint\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-commit"), []byte(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := InstallHooks(dir, HookOptions{Binary: "/usr/local/bin/todo", Block: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != len(Hooks) || !states[0].Chained || states[1].Chained {
+		t.Errorf("unexpected install states: %+v", states)
+	}
+	content, err := os.ReadFile(filepath.Join(hooksDir, "pre-commit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "pre-commit.todo-chained") || !strings.Contains(string(content), "check --staged") {
+		t.Errorf("pre-commit hook does not chain and check:\n%s", content)
+	}
+	if !strings.Contains(string(content), "git diff --cached --name-only --no-renames -z | \"$todo\" scan --files-from -") {
+		t.Errorf("pre-commit hook does not rescan the staged files:\n%s", content)
+	}
+
+	// Installing again keeps the chained hook rather than chaining ourselves
+	if _, err := InstallHooks(dir, HookOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	chained, err := os.ReadFile(filepath.Join(hooksDir, "pre-commit.todo-chained"))
+	if err != nil || string(chained) != existing {
+		t.Errorf("expected the original hook to stay chained, got %q, %v", chained, err)
+	}
+
+	if _, err := UninstallHooks(dir); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := os.ReadFile(filepath.Join(hooksDir, "pre-commit"))
+	if err != nil || string(restored) != existing {
+		t.Errorf("expected the original hook restored, got %q, %v", restored, err)
+	}
+	for _, name := range []string{"post-commit", "post-merge", "pre-commit.todo-chained"} {
+		if _, err := os.Stat(filepath.Join(hooksDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package gitea keeps TODOs and Gitea issues in sync
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// labelColor is the color of labels a sync creates
+const labelColor = "#ededed"
+
+// Client talks to the issues API of one repository
+type Client struct {
+	BaseURL string
+	Token   string
+	Owner   string
+	Repo    string
+	HTTP    *http.Client
+
+	labels map[string]int64 // label IDs by name, once listed
+}
+
+// NewClient returns a client for owner/repo on the Gitea instance at
+// baseURL
+func NewClient(baseURL, token, owner, repo string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+		Owner:   owner,
+		Repo:    repo,
+		HTTP:    integrations.NewHTTPClient(),
+	}
+}
+
+// Issue is a Gitea issue as returned by the API
+type Issue struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"` // open, closed
+	Labels    []Label    `json:"labels"`
+	Assignees []User     `json:"assignees"`
+	DueDate   *time.Time `json:"due_date"`
+	HTMLURL   string     `json:"html_url"`
+}
+
+// Label is a repository label
+type Label struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// User is a Gitea account
+type User struct {
+	Login string `json:"login"`
+}
+
+// IssueRequest holds the fields written when creating or editing an issue
+type IssueRequest struct {
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	Assignees []string `json:"assignees"`
+	// Labels are label IDs; only used when creating, as ReplaceLabels
+	// changes them afterwards
+	Labels []int64 `json:"labels,omitempty"`
+	// State is open or closed; only used when editing
+	State   string     `json:"state,omitempty"`
+	DueDate *time.Time `json:"due_date,omitempty"`
+	// UnsetDueDate clears the due date when editing
+	UnsetDueDate bool `json:"unset_due_date,omitempty"`
+}
+
+// CreateIssue opens a new issue
+func (c *Client) CreateIssue(req IssueRequest) (*Issue, error) {
+	req.State, req.UnsetDueDate = "", false
+	var issue Issue
+	if err := c.do(http.MethodPost, c.repoURL()+"/issues", req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return &issue, nil
+}
+
+// UpdateIssue edits an issue, opening or closing it as req.State says
+func (c *Client) UpdateIssue(number int, req IssueRequest) (*Issue, error) {
+	req.Labels = nil
+	var issue Issue
+	if err := c.do(http.MethodPatch, fmt.Sprintf("%s/issues/%d", c.repoURL(), number), req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to update issue #%d: %w", number, err)
+	}
+	return &issue, nil
+}
+
+// ReplaceLabels sets the labels of an issue
+func (c *Client) ReplaceLabels(number int, ids []int64) error {
+	if ids == nil {
+		ids = []int64{}
+	}
+	var labels []Label
+	req := map[string][]int64{"labels": ids}
+	if err := c.do(http.MethodPut, fmt.Sprintf("%s/issues/%d/labels", c.repoURL(), number), req, &labels); err != nil {
+		return fmt.Errorf("failed to set labels of issue #%d: %w", number, err)
+	}
+	return nil
+}
+
+// CloseIssue closes an issue
+func (c *Client) CloseIssue(number int) error {
+	var issue Issue
+	req := map[string]string{"state": "closed"}
+	if err := c.do(http.MethodPatch, fmt.Sprintf("%s/issues/%d", c.repoURL(), number), req, &issue); err != nil {
+		return fmt.Errorf("failed to close issue #%d: %w", number, err)
+	}
+	return nil
+}
+
+// GetIssue returns an issue, or integrations.ErrIssueGone when it no longer
+// exists
+func (c *Client) GetIssue(number int) (*Issue, error) {
+	var issue Issue
+	err := c.do(http.MethodGet, fmt.Sprintf("%s/issues/%d", c.repoURL(), number), nil, &issue)
+	if integrations.HasStatus(err, http.StatusNotFound) {
+		err = integrations.ErrIssueGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue #%d: %w", number, err)
+	}
+	return &issue, nil
+}
+
+// LabelIDs returns the IDs of labels by name, creating the labels the
+// repository lacks
+func (c *Client) LabelIDs(names []string) ([]int64, error) {
+	if c.labels == nil {
+		labels := make(map[string]int64)
+		for page := 1; ; page++ {
+			var batch []Label
+			url := fmt.Sprintf("%s/labels?page=%d&limit=50", c.repoURL(), page)
+			if err := c.do(http.MethodGet, url, nil, &batch); err != nil {
+				return nil, fmt.Errorf("failed to list labels: %w", err)
+			}
+			for _, label := range batch {
+				labels[label.Name] = label.ID
+			}
+			if len(batch) < 50 {
+				break
+			}
+		}
+		c.labels = labels
+	}
+
+	ids := []int64{}
+	for _, name := range names {
+		id, ok := c.labels[name]
+		if !ok {
+			var label Label
+			req := map[string]string{"name": name, "color": labelColor}
+			if err := c.do(http.MethodPost, c.repoURL()+"/labels", req, &label); err != nil {
+				return nil, fmt.Errorf("failed to create label %s: %w", name, err)
+			}
+			id = label.ID
+			c.labels[name] = id
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (c *Client) repoURL() string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s", c.BaseURL, c.Owner, c.Repo)
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *Client) do(method, url string, in, out interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	return integrations.DoJSON(c.HTTP, req, in, out, decodeError)
+}
+
+func decodeError(status int, body io.Reader) error {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(body).Decode(&apiErr)
+	return &integrations.APIError{API: "Gitea", Status: status, Message: apiErr.Message}
+}
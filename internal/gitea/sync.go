@@ -0,0 +1,226 @@
+package gitea
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// maxTitle is the longest issue title written
+const maxTitle = 255
+
+var priorityLabel = regexp.MustCompile(`^P[0-4]$`)
+
+// Tracker adapts the issues of a Gitea repository for integrations.Syncer.
+// Priority, category and tags are kept as labels. Gitea does not say why an
+// issue was closed, so closed issues stand for any finished status.
+type Tracker struct {
+	Client *Client
+}
+
+func init() {
+	integrations.Register(integrations.Adapter{Name: "gitea", Title: "Gitea Issues", New: newTracker})
+}
+
+func newTracker(cfg *config.Config) (integrations.Tracker, error) {
+	c := cfg.Gitea
+	if c.URL == "" {
+		return nil, integrations.NotConfigured("Gitea URL", "integration.gitea.url", "url")
+	}
+	if c.Token == "" {
+		return nil, integrations.NotConfigured("Gitea token", "integration.gitea.token", "token")
+	}
+	if c.Owner == "" {
+		return nil, integrations.NotConfigured("Gitea owner", "integration.gitea.owner", "owner")
+	}
+	if c.Repo == "" {
+		return nil, integrations.NotConfigured("Gitea repo", "integration.gitea.repo", "repo")
+	}
+	return &Tracker{Client: NewClient(c.URL, c.Token, c.Owner, c.Repo)}, nil
+}
+
+// Name is the name under which links to this repository are stored
+func (t *Tracker) Name() string {
+	return "gitea:" + t.Client.Owner + "/" + t.Client.Repo
+}
+
+// Map renders a TODO as the issue that tracks it
+func (t *Tracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	return integrations.Fields{
+		Title:    integrations.Title(todo, maxTitle),
+		Status:   todo.Status,
+		Priority: todo.Priority,
+		Assignee: todo.Assignee,
+		Labels:   integrations.Normalize(append([]string{todo.Category}, tags...)),
+		DueDate:  integrations.DueDate(todo),
+		Body:     integrations.Body(todo),
+	}
+}
+
+// Fetch returns the issue with the number key
+func (t *Tracker) Fetch(key string) (*integrations.Issue, error) {
+	number, err := strconv.Atoi(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue number %q", key)
+	}
+	issue, err := t.Client.GetIssue(number)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(issue), nil
+}
+
+// Create opens an issue
+func (t *Tracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	want := written(fields)
+	ids, err := t.Client.LabelIDs(want.Labels)
+	if err != nil {
+		return nil, err
+	}
+	req := request(want)
+	req.Labels = ids
+	issue, err := t.Client.CreateIssue(req)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(issue), nil
+}
+
+// Update edits an issue unless it already matches. Labels are replaced
+// separately, as edits cannot change them.
+func (t *Tracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	want := written(fields)
+	have := issueFields(issue.Raw.(*Issue))
+	if integrations.Fingerprint(want) == integrations.Fingerprint(have) {
+		return nil, nil
+	}
+
+	number, _ := strconv.Atoi(issue.Key)
+	if integrations.Fingerprint(want.Labels) != integrations.Fingerprint(have.Labels) {
+		ids, err := t.Client.LabelIDs(want.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.Client.ReplaceLabels(number, ids); err != nil {
+			return nil, err
+		}
+	}
+	want.Labels, have.Labels = nil, nil
+	if integrations.Fingerprint(want) != integrations.Fingerprint(have) {
+		req := request(want)
+		req.UnsetDueDate = want.DueDate == "" && have.DueDate != ""
+		if _, err := t.Client.UpdateIssue(number, req); err != nil {
+			return nil, err
+		}
+	}
+	return t.Fetch(issue.Key)
+}
+
+// Close closes an issue
+func (t *Tracker) Close(key string) error {
+	number, err := strconv.Atoi(key)
+	if err != nil {
+		return fmt.Errorf("invalid issue number %q", key)
+	}
+	return t.Client.CloseIssue(number)
+}
+
+// snapshot holds the parts of an issue a sync writes
+type snapshot struct {
+	Title     string
+	Body      string
+	State     string
+	DueDate   string
+	Labels    []string
+	Assignees []string
+}
+
+// written returns the issue fields to write
+func written(f integrations.Fields) snapshot {
+	w := snapshot{
+		Title:     f.Title,
+		Body:      f.Body,
+		State:     "open",
+		DueDate:   f.DueDate,
+		Labels:    integrations.Normalize(append([]string{f.Priority}, f.Labels...)),
+		Assignees: []string{},
+	}
+	if f.Assignee != "" {
+		w.Assignees = append(w.Assignees, f.Assignee)
+	}
+	if database.IsDone(f.Status) {
+		w.State = "closed"
+	}
+	return w
+}
+
+// request returns the request that writes w
+func request(w snapshot) IssueRequest {
+	req := IssueRequest{Title: w.Title, Body: w.Body, Assignees: w.Assignees, State: w.State}
+	if due, err := time.Parse("2006-01-02", w.DueDate); err == nil {
+		req.DueDate = &due
+	}
+	return req
+}
+
+// toIssue reads an issue in TODO terms. The last P0-P4 label sets the
+// priority.
+func toIssue(issue *Issue) *integrations.Issue {
+	read := issueFields(issue)
+	out := &integrations.Issue{
+		Key: strconv.Itoa(issue.Number),
+		URL: issue.HTMLURL,
+		Fields: integrations.Fields{
+			Title:   issue.Title,
+			Body:    issue.Body,
+			DueDate: read.DueDate,
+		},
+		Raw: issue,
+	}
+	if read.State == "closed" {
+		out.Statuses = []string{"resolved", "closed", "wontfix"}
+	} else {
+		out.Statuses = []string{"open", "in_progress", "blocked"}
+	}
+	out.Status = out.Statuses[0]
+	for _, label := range read.Labels {
+		if priorityLabel.MatchString(label) {
+			out.Priority = label
+		} else {
+			out.Labels = append(out.Labels, label)
+		}
+	}
+	if len(read.Assignees) > 0 {
+		out.Assignee = read.Assignees[0]
+	}
+
+	// Edits to the body on Gitea are not synced back
+	read.Body = ""
+	out.Revision = integrations.Fingerprint(read)
+	return out
+}
+
+// issueFields returns the fields of an issue that a sync writes. Due dates
+// are read as the date they fall on for the server.
+func issueFields(issue *Issue) snapshot {
+	f := snapshot{Title: issue.Title, Body: issue.Body, State: issue.State, Assignees: []string{}}
+	if issue.DueDate != nil {
+		f.DueDate = issue.DueDate.Format("2006-01-02")
+	}
+	var labels []string
+	for _, label := range issue.Labels {
+		labels = append(labels, label.Name)
+	}
+	f.Labels = integrations.Normalize(labels)
+	for _, user := range issue.Assignees {
+		f.Assignees = append(f.Assignees, user.Login)
+	}
+	sort.Strings(f.Assignees)
+	return f
+}
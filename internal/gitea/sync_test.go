@@ -0,0 +1,277 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitea is a stand-in for the issues API of the repository acme/app.
+// Due dates come back at the end of the day in the server's time zone, as
+// Gitea stores them.
+type fakeGitea struct {
+	mu         sync.Mutex
+	issues     map[int]*Issue
+	labels     []Label
+	next       int
+	creates    int
+	edits      int
+	labelLists int
+}
+
+var serverZone = time.FixedZone("UTC-5", -5*60*60)
+
+func newFakeGitea(t *testing.T) (*fakeGitea, *Client) {
+	f := &fakeGitea{issues: make(map[int]*Issue), next: 1, labels: []Label{{ID: 1, Name: "backend"}}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL+"/", "secret", "acme", "app")
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message":"token is required"}`)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/repos/acme/app")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case rest == "/labels" && r.Method == http.MethodGet:
+		f.labelLists++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		start := (page - 1) * limit
+		batch := []Label{}
+		for i := start; i < len(f.labels) && i < start+limit; i++ {
+			batch = append(batch, f.labels[i])
+		}
+		json.NewEncoder(w).Encode(batch)
+	case rest == "/labels" && r.Method == http.MethodPost:
+		var label Label
+		json.NewDecoder(r.Body).Decode(&label)
+		label.ID = int64(len(f.labels) + 1)
+		f.labels = append(f.labels, label)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(label)
+	case rest == "/issues" && r.Method == http.MethodPost:
+		var req IssueRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		issue := &Issue{Number: f.next, State: "open"}
+		f.next++
+		f.apply(issue, req)
+		issue.Labels = f.labelsOf(req.Labels)
+		f.issues[issue.Number] = issue
+		f.creates++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(issue)
+	case strings.HasPrefix(rest, "/issues/"):
+		path := strings.Split(strings.TrimPrefix(rest, "/issues/"), "/")
+		number, _ := strconv.Atoi(path[0])
+		issue, ok := f.issues[number]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"issue does not exist"}`)
+			return
+		}
+		switch {
+		case len(path) == 2 && path[1] == "labels" && r.Method == http.MethodPut:
+			var req struct{ Labels []int64 }
+			json.NewDecoder(r.Body).Decode(&req)
+			issue.Labels = f.labelsOf(req.Labels)
+			f.edits++
+			json.NewEncoder(w).Encode(issue.Labels)
+		case len(path) == 1 && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(issue)
+		case len(path) == 1 && r.Method == http.MethodPatch:
+			var req IssueRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Title == "" {
+				// A partial edit, such as closing
+				req = IssueRequest{Title: issue.Title, Body: issue.Body, Assignees: logins(*issue), State: req.State, DueDate: issue.DueDate}
+			}
+			f.apply(issue, req)
+			f.edits++
+			json.NewEncoder(w).Encode(issue)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitea) apply(issue *Issue, req IssueRequest) {
+	issue.Title, issue.Body = req.Title, req.Body
+	if req.State != "" {
+		issue.State = req.State
+	}
+	issue.Assignees = nil
+	for _, login := range req.Assignees {
+		issue.Assignees = append(issue.Assignees, User{Login: login})
+	}
+	if req.DueDate != nil {
+		y, m, d := req.DueDate.Date()
+		due := time.Date(y, m, d, 23, 59, 59, 0, serverZone)
+		issue.DueDate = &due
+	}
+	if req.UnsetDueDate {
+		issue.DueDate = nil
+	}
+	issue.HTMLURL = fmt.Sprintf("https://git.example.com/acme/app/issues/%d", issue.Number)
+}
+
+func (f *fakeGitea) labelsOf(ids []int64) []Label {
+	var labels []Label
+	for _, id := range ids {
+		labels = append(labels, f.labels[id-1])
+	}
+	return labels
+}
+
+// edit changes an issue as a person on Gitea would
+func (f *fakeGitea) edit(number int, change func(*Issue)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f.issues[number])
+}
+
+func (f *fakeGitea) issue(number int) Issue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.issues[number]
+}
+
+func (f *fakeGitea) Body(key string) string {
+	number, _ := strconv.Atoi(key)
+	return f.issue(number).Body
+}
+
+func (f *fakeGitea) Delete(key string) {
+	number, _ := strconv.Atoi(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.issues, number)
+}
+
+func (f *fakeGitea) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates + f.edits
+}
+
+func labelNames(issue Issue) []string {
+	var names []string
+	for _, label := range issue.Labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+func logins(issue Issue) []string {
+	var names []string
+	for _, user := range issue.Assignees {
+		names = append(names, user.Login)
+	}
+	return names
+}
+
+func newTestSyncer(t *testing.T) (*integrations.Syncer, *fakeGitea, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P2", Category: "backend", Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser", Status: "in_progress", Priority: "P1", Assignee: "alice", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Priority: "P3", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	fake, client := newFakeGitea(t)
+	return &integrations.Syncer{Tracker: &Tracker{Client: client}, DB: db}, fake, todos
+}
+
+func runSync(t *testing.T, s *integrations.Syncer) *integrations.Result {
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		fake, client := newFakeGitea(t)
+		return &Tracker{Client: client}, fake
+	})
+}
+
+func TestSyncCreatesLabels(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	assert.Equal(t, []string{"P2", "backend"}, labelNames(fake.issue(1)))
+	assert.Equal(t, []Label{{ID: 1, Name: "backend"}, {ID: 2, Name: "P2"}, {ID: 3, Name: "P1"}}, fake.labels,
+		"missing labels are created once")
+	assert.Equal(t, 1, fake.labelLists)
+
+	tag, err := s.DB.GetOrCreateTag("security")
+	require.NoError(t, err)
+	require.NoError(t, s.DB.AddTagToTODO(todos[1].ID, tag.ID))
+	assert.Equal(t, 1, runSync(t, s).Updated)
+	assert.Equal(t, []string{"P1", "security"}, labelNames(fake.issue(2)))
+}
+
+func TestSyncDueDatesInServerZone(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	due := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	todos[0].DueDate = &due
+	require.NoError(t, s.DB.UpdateTODO(&todos[0]))
+	runSync(t, s)
+	issue := fake.issue(1)
+	require.NotNil(t, issue.DueDate)
+	assert.Equal(t, serverZone, issue.DueDate.Location())
+	assert.Equal(t, "2025-03-01", issue.DueDate.Format("2006-01-02"))
+	assert.Equal(t, 2, runSync(t, s).Unchanged, "the end of the day on the server is the same date")
+
+	fake.edit(1, func(i *Issue) {
+		due := time.Date(2025, 6, 30, 23, 59, 59, 0, serverZone)
+		i.DueDate = &due
+	})
+	assert.Equal(t, 1, runSync(t, s).Imported)
+	todo, err := s.DB.GetTODOByID(todos[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, todo.DueDate)
+	assert.Equal(t, "2025-06-30", todo.DueDate.Format("2006-01-02"))
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newFakeGitea(t)
+	client.Token = "wrong"
+
+	_, err := client.CreateIssue(IssueRequest{Title: "x"})
+	var apiErr *integrations.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+	assert.Equal(t, "token is required", apiErr.Message)
+}
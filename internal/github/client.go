@@ -0,0 +1,153 @@
+// Package github keeps TODOs and GitHub Issues in sync
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// DefaultBaseURL is the API of github.com; GitHub Enterprise serves it at
+// https://<host>/api/v3
+const DefaultBaseURL = "https://api.github.com"
+
+// Client talks to the issues API of one repository
+type Client struct {
+	BaseURL string
+	Token   string
+	Owner   string
+	Repo    string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for owner/repo; an empty baseURL means
+// github.com
+func NewClient(baseURL, token, owner, repo string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+		Owner:   owner,
+		Repo:    repo,
+		HTTP:    integrations.NewHTTPClient(),
+	}
+}
+
+// Issue is a GitHub issue as returned by the API
+type Issue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`                  // open, closed
+	StateReason string    `json:"state_reason,omitempty"` // completed, not_planned, reopened
+	Labels      []Label   `json:"labels"`
+	Assignees   []User    `json:"assignees"`
+	HTMLURL     string    `json:"html_url"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Label is an issue label
+type Label struct {
+	Name string `json:"name"`
+}
+
+// User is a GitHub account
+type User struct {
+	Login string `json:"login"`
+}
+
+// IssueRequest holds the fields written when creating or editing an issue
+type IssueRequest struct {
+	Title       string   `json:"title"`
+	Body        string   `json:"body"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+	Labels      []string `json:"labels"`
+	Assignees   []string `json:"assignees"`
+}
+
+// CreateIssue opens a new issue. GitHub ignores the state on creation.
+func (c *Client) CreateIssue(req IssueRequest) (*Issue, error) {
+	req.State, req.StateReason = "", ""
+	var issue Issue
+	if err := c.do(http.MethodPost, c.issuesURL(), req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return &issue, nil
+}
+
+// UpdateIssue edits an issue, opening or closing it as req.State says
+func (c *Client) UpdateIssue(number int, req IssueRequest) (*Issue, error) {
+	var issue Issue
+	if err := c.do(http.MethodPatch, fmt.Sprintf("%s/%d", c.issuesURL(), number), req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to update issue #%d: %w", number, err)
+	}
+	return &issue, nil
+}
+
+// CloseIssue closes an issue as completed or not_planned
+func (c *Client) CloseIssue(number int, reason string) error {
+	req := map[string]string{"state": "closed", "state_reason": reason}
+	var issue Issue
+	if err := c.do(http.MethodPatch, fmt.Sprintf("%s/%d", c.issuesURL(), number), req, &issue); err != nil {
+		return fmt.Errorf("failed to close issue #%d: %w", number, err)
+	}
+	return nil
+}
+
+// GetIssue returns an issue, or integrations.ErrIssueGone when it no longer
+// exists. GitHub answers 404 for a repository the token cannot read as
+// well, so an issue is only gone when its repository can still be read.
+func (c *Client) GetIssue(number int) (*Issue, error) {
+	var issue Issue
+	err := c.do(http.MethodGet, fmt.Sprintf("%s/%d", c.issuesURL(), number), nil, &issue)
+	if integrations.HasStatus(err, http.StatusNotFound) {
+		var repo struct{}
+		if repoErr := c.do(http.MethodGet, c.repoURL(), nil, &repo); repoErr != nil {
+			return nil, fmt.Errorf("failed to read repository %s/%s: %w", c.Owner, c.Repo, repoErr)
+		}
+		err = integrations.ErrIssueGone
+	} else if integrations.HasStatus(err, http.StatusGone) {
+		err = integrations.ErrIssueGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue #%d: %w", number, err)
+	}
+	return &issue, nil
+}
+
+func (c *Client) repoURL() string {
+	return fmt.Sprintf("%s/repos/%s/%s", c.BaseURL, c.Owner, c.Repo)
+}
+
+func (c *Client) issuesURL() string {
+	return c.repoURL() + "/issues"
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *Client) do(method, url string, in, out interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "todo-tracker")
+	return integrations.DoJSON(c.HTTP, req, in, out, decodeError)
+}
+
+func decodeError(status int, body io.Reader) error {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(body).Decode(&apiErr)
+	return &integrations.APIError{API: "GitHub", Status: status, Message: apiErr.Message}
+}
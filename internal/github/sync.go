@@ -0,0 +1,186 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// maxTitle is the longest issue title GitHub accepts
+const maxTitle = 256
+
+var priorityLabel = regexp.MustCompile(`^P[0-4]$`)
+
+// Tracker adapts the issues of a repository for integrations.Syncer.
+// Priority, category and tags are kept as labels.
+type Tracker struct {
+	Client *Client
+}
+
+func init() {
+	integrations.Register(integrations.Adapter{Name: "github", Title: "GitHub Issues", New: newTracker})
+}
+
+func newTracker(cfg *config.Config) (integrations.Tracker, error) {
+	c := cfg.GitHub
+	if c.Token == "" {
+		return nil, integrations.NotConfigured("GitHub token", "integration.github.token", "token")
+	}
+	if c.Owner == "" {
+		return nil, integrations.NotConfigured("GitHub owner", "integration.github.owner", "owner")
+	}
+	if c.Repo == "" {
+		return nil, integrations.NotConfigured("GitHub repo", "integration.github.repo", "repo")
+	}
+	return &Tracker{Client: NewClient(c.APIURL, c.Token, c.Owner, c.Repo)}, nil
+}
+
+// Name is the name under which links to this repository are stored
+func (t *Tracker) Name() string {
+	return "github:" + t.Client.Owner + "/" + t.Client.Repo
+}
+
+// Map renders a TODO as the issue that tracks it. GitHub issues have no due
+// date, so it is only mentioned in the body.
+func (t *Tracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	return integrations.Fields{
+		Title:    integrations.Title(todo, maxTitle),
+		Status:   todo.Status,
+		Priority: todo.Priority,
+		Assignee: todo.Assignee,
+		Labels:   integrations.Normalize(append([]string{todo.Category}, tags...)),
+		Body:     integrations.Body(todo),
+	}
+}
+
+// Fetch returns the issue with the number key
+func (t *Tracker) Fetch(key string) (*integrations.Issue, error) {
+	number, err := strconv.Atoi(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue number %q", key)
+	}
+	issue, err := t.Client.GetIssue(number)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(issue), nil
+}
+
+// Create opens an issue
+func (t *Tracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	issue, err := t.Client.CreateIssue(request(fields))
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(issue), nil
+}
+
+// Update edits an issue unless it already matches
+func (t *Tracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	want := request(fields)
+	if integrations.Fingerprint(want) == integrations.Fingerprint(issueFields(issue.Raw.(*Issue))) {
+		return nil, nil
+	}
+	number, _ := strconv.Atoi(issue.Key)
+	updated, err := t.Client.UpdateIssue(number, want)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(updated), nil
+}
+
+// Close closes an issue as not planned
+func (t *Tracker) Close(key string) error {
+	number, err := strconv.Atoi(key)
+	if err != nil {
+		return fmt.Errorf("invalid issue number %q", key)
+	}
+	return t.Client.CloseIssue(number, "not_planned")
+}
+
+// request returns the issue fields to write
+func request(f integrations.Fields) IssueRequest {
+	assignees := []string{}
+	if f.Assignee != "" {
+		assignees = append(assignees, f.Assignee)
+	}
+	req := IssueRequest{
+		Title:     f.Title,
+		Body:      f.Body,
+		State:     "open",
+		Labels:    integrations.Normalize(append([]string{f.Priority}, f.Labels...)),
+		Assignees: assignees,
+	}
+	if database.IsDone(f.Status) {
+		req.State, req.StateReason = "closed", "completed"
+		if f.Status == "wontfix" {
+			req.StateReason = "not_planned"
+		}
+	}
+	return req
+}
+
+// toIssue reads an issue in TODO terms. The last P0-P4 label sets the
+// priority.
+func toIssue(issue *Issue) *integrations.Issue {
+	read := issueFields(issue)
+	out := &integrations.Issue{
+		Key: strconv.Itoa(issue.Number),
+		URL: issue.HTMLURL,
+		Fields: integrations.Fields{
+			Title: issue.Title,
+			Body:  issue.Body,
+		},
+		Raw: issue,
+	}
+	switch read.StateReason {
+	case "completed":
+		out.Statuses = []string{"resolved", "closed"}
+	case "not_planned":
+		out.Statuses = []string{"wontfix"}
+	default:
+		out.Statuses = []string{"open", "in_progress", "blocked"}
+	}
+	out.Status = out.Statuses[0]
+	for _, label := range read.Labels {
+		if priorityLabel.MatchString(label) {
+			out.Priority = label
+		} else {
+			out.Labels = append(out.Labels, label)
+		}
+	}
+	if len(read.Assignees) > 0 {
+		out.Assignee = read.Assignees[0]
+	}
+
+	// Edits to the body on GitHub are not synced back
+	read.Body = ""
+	out.Revision = integrations.Fingerprint(read)
+	return out
+}
+
+// issueFields returns the fields of an issue that a sync writes
+func issueFields(issue *Issue) IssueRequest {
+	req := IssueRequest{Title: issue.Title, Body: issue.Body, State: issue.State, Assignees: []string{}}
+	if issue.State == "closed" {
+		req.StateReason = "completed"
+		if issue.StateReason == "not_planned" {
+			req.StateReason = "not_planned"
+		}
+	}
+	var labels []string
+	for _, label := range issue.Labels {
+		labels = append(labels, label.Name)
+	}
+	req.Labels = integrations.Normalize(labels)
+	for _, user := range issue.Assignees {
+		req.Assignees = append(req.Assignees, user.Login)
+	}
+	sort.Strings(req.Assignees)
+	return req
+}
@@ -0,0 +1,236 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub is a stand-in for the issues API of one repository
+type fakeGitHub struct {
+	mu      sync.Mutex
+	issues  map[int]*Issue
+	next    int
+	creates int
+	edits   int
+	// hidden makes the repository answer 404 throughout, as GitHub does
+	// for repositories a token cannot read
+	hidden bool
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *Client) {
+	f := &fakeGitHub{issues: make(map[int]*Issue), next: 1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL, "secret", "acme", "app")
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.hidden {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+		return
+	}
+	if r.URL.Path == "/repos/acme/app" && r.Method == http.MethodGet {
+		fmt.Fprint(w, `{"full_name":"acme/app"}`)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/repos/acme/app/issues")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if rest == "" && r.Method == http.MethodPost {
+		var req IssueRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		issue := &Issue{Number: f.next, State: "open"}
+		f.next++
+		f.apply(issue, req)
+		f.issues[issue.Number] = issue
+		f.creates++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(issue)
+		return
+	}
+
+	number, _ := strconv.Atoi(strings.TrimPrefix(rest, "/"))
+	issue, ok := f.issues[number]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		var req IssueRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Title == "" {
+			// A partial edit, such as closing
+			req = IssueRequest{Title: issue.Title, Body: issue.Body, State: req.State, StateReason: req.StateReason,
+				Labels: labelNames(*issue), Assignees: logins(*issue)}
+		}
+		f.apply(issue, req)
+		f.edits++
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(issue)
+}
+
+func (f *fakeGitHub) apply(issue *Issue, req IssueRequest) {
+	issue.Title, issue.Body = req.Title, req.Body
+	if req.State != "" {
+		issue.State, issue.StateReason = req.State, req.StateReason
+	}
+	issue.Labels, issue.Assignees = nil, nil
+	for _, name := range req.Labels {
+		issue.Labels = append(issue.Labels, Label{Name: name})
+	}
+	for _, login := range req.Assignees {
+		issue.Assignees = append(issue.Assignees, User{Login: login})
+	}
+	issue.HTMLURL = fmt.Sprintf("https://github.com/acme/app/issues/%d", issue.Number)
+	issue.UpdatedAt = time.Now()
+}
+
+func (f *fakeGitHub) issue(number int) Issue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.issues[number]
+}
+
+func (f *fakeGitHub) Body(key string) string {
+	number, _ := strconv.Atoi(key)
+	return f.issue(number).Body
+}
+
+func (f *fakeGitHub) Delete(key string) {
+	number, _ := strconv.Atoi(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.issues, number)
+}
+
+func (f *fakeGitHub) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates + f.edits
+}
+
+func labelNames(issue Issue) []string {
+	var names []string
+	for _, label := range issue.Labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+func logins(issue Issue) []string {
+	var names []string
+	for _, user := range issue.Assignees {
+		names = append(names, user.Login)
+	}
+	return names
+}
+
+func newTestSyncer(t *testing.T) (*integrations.Syncer, *fakeGitHub, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P2", Category: "backend", Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser", Status: "in_progress", Priority: "P1", Assignee: "alice", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Priority: "P3", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	fake, client := newFakeGitHub(t)
+	return &integrations.Syncer{Tracker: &Tracker{Client: client}, DB: db}, fake, todos
+}
+
+func runSync(t *testing.T, s *integrations.Syncer) *integrations.Result {
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		fake, client := newFakeGitHub(t)
+		return &Tracker{Client: client}, fake
+	})
+}
+
+func TestSyncIssueFormat(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	issue := fake.issue(1)
+	assert.Equal(t, []string{"P2", "backend"}, labelNames(issue))
+	assert.Equal(t, "https://github.com/acme/app/issues/1", issue.HTMLURL)
+	assert.Equal(t, []string{"alice"}, logins(fake.issue(2)))
+
+	// Finished statuses are told apart by the reason an issue was closed
+	todos[0].Status = "resolved"
+	require.NoError(t, s.DB.UpdateTODO(&todos[0]))
+	todos[1].Status = "wontfix"
+	require.NoError(t, s.DB.UpdateTODO(&todos[1]))
+	runSync(t, s)
+	issue = fake.issue(1)
+	assert.Equal(t, "closed", issue.State)
+	assert.Equal(t, "completed", issue.StateReason)
+	assert.Equal(t, "not_planned", fake.issue(2).StateReason)
+}
+
+func TestSyncKeepsLinksWhenRepositoryIsUnreadable(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	fake.mu.Lock()
+	fake.hidden = true
+	fake.mu.Unlock()
+
+	result, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	require.Len(t, result.Errors, 2)
+	assert.NotErrorIs(t, result.Errors[0], integrations.ErrIssueGone)
+	assert.ErrorContains(t, result.Errors[0], "failed to read repository acme/app")
+	links, err := s.DB.GetIssueLinksForTODO(todos[0].ID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "1", links[0].Key)
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newFakeGitHub(t)
+	client.Token = "wrong"
+
+	_, err := client.CreateIssue(IssueRequest{Title: "x"})
+	var apiErr *integrations.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+}
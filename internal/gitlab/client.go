@@ -0,0 +1,169 @@
+// Package gitlab keeps TODOs and GitLab issues in sync
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// DefaultURL is gitlab.com; self-managed instances serve the same API under
+// their own URL
+const DefaultURL = "https://gitlab.com"
+
+// Client talks to the REST API (v4) of one GitLab project
+type Client struct {
+	BaseURL string
+	Token   string
+	Project string // path, such as group/app, or numeric ID
+	HTTP    *http.Client
+
+	users map[string]int // usernames looked up so far
+}
+
+// NewClient returns a client for a project; an empty baseURL means
+// gitlab.com
+func NewClient(baseURL, token, project string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+		Project: project,
+		HTTP:    integrations.NewHTTPClient(),
+		users:   make(map[string]int),
+	}
+}
+
+// Issue is a GitLab issue as returned by the API
+type Issue struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	State       string   `json:"state"` // opened, closed
+	Labels      []string `json:"labels"`
+	Assignees   []User   `json:"assignees"`
+	DueDate     *string  `json:"due_date"` // YYYY-MM-DD
+	WebURL      string   `json:"web_url"`
+}
+
+// User is a GitLab account
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// IssueRequest holds the fields written when creating or editing an issue
+type IssueRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Labels is a comma-separated list that replaces the issue's labels
+	Labels      string `json:"labels"`
+	AssigneeIDs []int  `json:"assignee_ids"`
+	// DueDate is YYYY-MM-DD, or "" to clear it
+	DueDate string `json:"due_date"`
+	// StateEvent is close or reopen; only used when editing
+	StateEvent string `json:"state_event,omitempty"`
+}
+
+// CreateIssue opens a new issue. New issues are always open.
+func (c *Client) CreateIssue(req IssueRequest) (*Issue, error) {
+	req.StateEvent = ""
+	var issue Issue
+	if err := c.do(http.MethodPost, c.issuesURL(), req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return &issue, nil
+}
+
+// UpdateIssue edits an issue, closing or reopening it as req.StateEvent says
+func (c *Client) UpdateIssue(iid int, req IssueRequest) (*Issue, error) {
+	var issue Issue
+	if err := c.do(http.MethodPut, fmt.Sprintf("%s/%d", c.issuesURL(), iid), req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to update issue #%d: %w", iid, err)
+	}
+	return &issue, nil
+}
+
+// CloseIssue closes an issue
+func (c *Client) CloseIssue(iid int) error {
+	var issue Issue
+	req := map[string]string{"state_event": "close"}
+	if err := c.do(http.MethodPut, fmt.Sprintf("%s/%d", c.issuesURL(), iid), req, &issue); err != nil {
+		return fmt.Errorf("failed to close issue #%d: %w", iid, err)
+	}
+	return nil
+}
+
+// GetIssue returns an issue, or integrations.ErrIssueGone when it no longer
+// exists
+func (c *Client) GetIssue(iid int) (*Issue, error) {
+	var issue Issue
+	err := c.do(http.MethodGet, fmt.Sprintf("%s/%d", c.issuesURL(), iid), nil, &issue)
+	// A missing project is a 404 too, but says so
+	var apiErr *integrations.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound && !strings.Contains(apiErr.Message, "Project") {
+		err = integrations.ErrIssueGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue #%d: %w", iid, err)
+	}
+	return &issue, nil
+}
+
+// UserID returns the ID of the user with a username, or 0 when there is
+// none
+func (c *Client) UserID(username string) (int, error) {
+	if id, ok := c.users[username]; ok {
+		return id, nil
+	}
+	var users []User
+	if err := c.do(http.MethodGet, c.BaseURL+"/api/v4/users?username="+url.QueryEscape(username), nil, &users); err != nil {
+		return 0, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	id := 0
+	if len(users) > 0 {
+		id = users[0].ID
+	}
+	c.users[username] = id
+	return id, nil
+}
+
+func (c *Client) issuesURL() string {
+	return fmt.Sprintf("%s/api/v4/projects/%s/issues", c.BaseURL, url.PathEscape(c.Project))
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *Client) do(method, url string, in, out interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.Token)
+	req.Header.Set("Accept", "application/json")
+	return integrations.DoJSON(c.HTTP, req, in, out, decodeError)
+}
+
+// decodeError reads an error response. GitLab puts a string, or fields and
+// their errors, in message.
+func decodeError(status int, body io.Reader) error {
+	var apiErr struct {
+		Message json.RawMessage `json:"message"`
+		Error   string          `json:"error"`
+	}
+	json.NewDecoder(body).Decode(&apiErr)
+	message := apiErr.Error
+	if len(apiErr.Message) > 0 {
+		if err := json.Unmarshal(apiErr.Message, &message); err != nil {
+			message = string(apiErr.Message)
+		}
+	}
+	return &integrations.APIError{API: "GitLab", Status: status, Message: message}
+}
@@ -0,0 +1,202 @@
+package gitlab
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// maxTitle is the longest issue title GitLab accepts
+const maxTitle = 255
+
+var priorityLabel = regexp.MustCompile(`^P[0-4]$`)
+
+// Tracker adapts the issues of a GitLab project for integrations.Syncer.
+// Priority, category and tags are kept as labels. GitLab does not say why
+// an issue was closed, so closed issues stand for any finished status.
+type Tracker struct {
+	Client *Client
+}
+
+func init() {
+	integrations.Register(integrations.Adapter{Name: "gitlab", Title: "GitLab Issues", New: newTracker})
+}
+
+func newTracker(cfg *config.Config) (integrations.Tracker, error) {
+	c := cfg.GitLab
+	if c.Token == "" {
+		return nil, integrations.NotConfigured("GitLab token", "integration.gitlab.token", "token")
+	}
+	if c.Project == "" {
+		return nil, integrations.NotConfigured("GitLab project", "integration.gitlab.project", "group/project")
+	}
+	return &Tracker{Client: NewClient(c.URL, c.Token, c.Project)}, nil
+}
+
+// Name is the name under which links to this project are stored
+func (t *Tracker) Name() string {
+	return "gitlab:" + t.Client.Project
+}
+
+// Map renders a TODO as the issue that tracks it
+func (t *Tracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	// Labels are written as a comma-separated list
+	labels := []string{strings.ReplaceAll(todo.Category, ",", " ")}
+	for _, tag := range tags {
+		labels = append(labels, strings.ReplaceAll(tag, ",", " "))
+	}
+	return integrations.Fields{
+		Title:    integrations.Title(todo, maxTitle),
+		Status:   todo.Status,
+		Priority: todo.Priority,
+		Assignee: todo.Assignee,
+		Labels:   integrations.Normalize(labels),
+		DueDate:  integrations.DueDate(todo),
+		Body:     integrations.Body(todo),
+	}
+}
+
+// Fetch returns the issue with the IID key
+func (t *Tracker) Fetch(key string) (*integrations.Issue, error) {
+	iid, err := strconv.Atoi(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue number %q", key)
+	}
+	issue, err := t.Client.GetIssue(iid)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(issue), nil
+}
+
+// Create opens an issue
+func (t *Tracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	req, err := t.request(fields)
+	if err != nil {
+		return nil, err
+	}
+	issue, err := t.Client.CreateIssue(req)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(issue), nil
+}
+
+// Update edits an issue unless it already matches
+func (t *Tracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	want, err := t.request(fields)
+	if err != nil {
+		return nil, err
+	}
+	if integrations.Fingerprint(want) == integrations.Fingerprint(issueFields(issue.Raw.(*Issue))) {
+		return nil, nil
+	}
+	iid, _ := strconv.Atoi(issue.Key)
+	updated, err := t.Client.UpdateIssue(iid, want)
+	if err != nil {
+		return nil, err
+	}
+	return toIssue(updated), nil
+}
+
+// Close closes an issue
+func (t *Tracker) Close(key string) error {
+	iid, err := strconv.Atoi(key)
+	if err != nil {
+		return fmt.Errorf("invalid issue number %q", key)
+	}
+	return t.Client.CloseIssue(iid)
+}
+
+// request returns the issue fields to write. Assignees without a GitLab
+// account are left out.
+func (t *Tracker) request(f integrations.Fields) (IssueRequest, error) {
+	req := IssueRequest{
+		Title:       f.Title,
+		Description: f.Body,
+		Labels:      strings.Join(integrations.Normalize(append([]string{f.Priority}, f.Labels...)), ","),
+		AssigneeIDs: []int{},
+		DueDate:     f.DueDate,
+		StateEvent:  "reopen",
+	}
+	if f.Assignee != "" {
+		id, err := t.Client.UserID(f.Assignee)
+		if err != nil {
+			return req, err
+		}
+		if id != 0 {
+			req.AssigneeIDs = append(req.AssigneeIDs, id)
+		}
+	}
+	if database.IsDone(f.Status) {
+		req.StateEvent = "close"
+	}
+	return req, nil
+}
+
+// toIssue reads an issue in TODO terms. The last P0-P4 label sets the
+// priority.
+func toIssue(issue *Issue) *integrations.Issue {
+	out := &integrations.Issue{
+		Key: strconv.Itoa(issue.IID),
+		URL: issue.WebURL,
+		Fields: integrations.Fields{
+			Title: issue.Title,
+			Body:  issue.Description,
+		},
+		Raw: issue,
+	}
+	if issue.State == "closed" {
+		out.Statuses = []string{"resolved", "closed", "wontfix"}
+	} else {
+		out.Statuses = []string{"open", "in_progress", "blocked"}
+	}
+	out.Status = out.Statuses[0]
+	for _, label := range integrations.Normalize(issue.Labels) {
+		if priorityLabel.MatchString(label) {
+			out.Priority = label
+		} else {
+			out.Labels = append(out.Labels, label)
+		}
+	}
+	if len(issue.Assignees) > 0 {
+		out.Assignee = issue.Assignees[0].Username
+	}
+	if issue.DueDate != nil {
+		out.DueDate = *issue.DueDate
+	}
+
+	// Edits to the description on GitLab are not synced back
+	read := issueFields(issue)
+	read.Description = ""
+	out.Revision = integrations.Fingerprint(read)
+	return out
+}
+
+// issueFields returns the fields of an issue that a sync writes
+func issueFields(issue *Issue) IssueRequest {
+	req := IssueRequest{
+		Title:       issue.Title,
+		Description: issue.Description,
+		Labels:      strings.Join(integrations.Normalize(issue.Labels), ","),
+		AssigneeIDs: []int{},
+		StateEvent:  "reopen",
+	}
+	for _, user := range issue.Assignees {
+		req.AssigneeIDs = append(req.AssigneeIDs, user.ID)
+	}
+	sort.Ints(req.AssigneeIDs)
+	if issue.DueDate != nil {
+		req.DueDate = *issue.DueDate
+	}
+	if issue.State == "closed" {
+		req.StateEvent = "close"
+	}
+	return req
+}
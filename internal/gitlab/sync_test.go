@@ -0,0 +1,233 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// users are the accounts of the fake instance
+var users = map[string]int{"alice": 1, "bob": 2, "carol": 3}
+
+// fakeGitLab is a stand-in for the issues API of the project group/app
+type fakeGitLab struct {
+	mu      sync.Mutex
+	issues  map[int]*Issue
+	next    int
+	creates int
+	edits   int
+}
+
+func newFakeGitLab(t *testing.T) (*fakeGitLab, *Client) {
+	f := &fakeGitLab{issues: make(map[int]*Issue), next: 1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL, "glpat-secret", "group/app")
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "glpat-secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message":"401 Unauthorized"}`)
+		return
+	}
+	if r.URL.Path == "/api/v4/users" {
+		var found []User
+		if id, ok := users[r.URL.Query().Get("username")]; ok {
+			found = append(found, User{ID: id, Username: r.URL.Query().Get("username")})
+		}
+		json.NewEncoder(w).Encode(found)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/api/v4/projects/group%2Fapp/issues")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"404 Project Not Found"}`)
+		return
+	}
+
+	if rest == "" && r.Method == http.MethodPost {
+		var req IssueRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.StateEvent != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"state_event is not allowed"}`)
+			return
+		}
+		issue := &Issue{IID: f.next, State: "opened"}
+		f.next++
+		f.apply(issue, req)
+		f.issues[issue.IID] = issue
+		f.creates++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(issue)
+		return
+	}
+
+	iid, _ := strconv.Atoi(strings.TrimPrefix(rest, "/"))
+	issue, ok := f.issues[iid]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"404 Not found"}`)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var fields map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&fields)
+		if _, ok := fields["title"]; !ok {
+			// A partial edit, such as closing
+			var event string
+			json.Unmarshal(fields["state_event"], &event)
+			req := issueFields(issue)
+			req.StateEvent = event
+			f.apply(issue, req)
+		} else {
+			data, _ := json.Marshal(fields)
+			var req IssueRequest
+			json.Unmarshal(data, &req)
+			f.apply(issue, req)
+		}
+		f.edits++
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(issue)
+}
+
+func (f *fakeGitLab) apply(issue *Issue, req IssueRequest) {
+	issue.Title, issue.Description = req.Title, req.Description
+	switch req.StateEvent {
+	case "close":
+		issue.State = "closed"
+	case "reopen":
+		issue.State = "opened"
+	}
+	issue.Labels = nil
+	if req.Labels != "" {
+		issue.Labels = strings.Split(req.Labels, ",")
+	}
+	issue.Assignees = nil
+	for _, id := range req.AssigneeIDs {
+		for name, uid := range users {
+			if uid == id {
+				issue.Assignees = append(issue.Assignees, User{ID: id, Username: name})
+			}
+		}
+	}
+	issue.DueDate = nil
+	if req.DueDate != "" {
+		due := req.DueDate
+		issue.DueDate = &due
+	}
+	issue.WebURL = fmt.Sprintf("https://gitlab.example.com/group/app/-/issues/%d", issue.IID)
+}
+
+func (f *fakeGitLab) issue(iid int) Issue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.issues[iid]
+}
+
+func (f *fakeGitLab) Body(key string) string {
+	iid, _ := strconv.Atoi(key)
+	return f.issue(iid).Description
+}
+
+func (f *fakeGitLab) Delete(key string) {
+	iid, _ := strconv.Atoi(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.issues, iid)
+}
+
+func (f *fakeGitLab) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates + f.edits
+}
+
+func usernames(issue Issue) []string {
+	var names []string
+	for _, user := range issue.Assignees {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+func newTestSyncer(t *testing.T) (*integrations.Syncer, *fakeGitLab, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P2", Category: "backend", Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser", Status: "in_progress", Priority: "P1", Assignee: "alice", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Priority: "P3", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	fake, client := newFakeGitLab(t)
+	return &integrations.Syncer{Tracker: &Tracker{Client: client}, DB: db}, fake, todos
+}
+
+func runSync(t *testing.T, s *integrations.Syncer) *integrations.Result {
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		fake, client := newFakeGitLab(t)
+		return &Tracker{Client: client}, fake
+	})
+}
+
+func TestSyncIssueFormat(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	issue := fake.issue(1)
+	assert.Equal(t, []string{"P2", "backend"}, issue.Labels)
+	assert.Equal(t, "https://gitlab.example.com/group/app/-/issues/1", issue.WebURL)
+	assert.Equal(t, []string{"alice"}, usernames(fake.issue(2)))
+
+	todos[1].Assignee = "dave"
+	require.NoError(t, s.DB.UpdateTODO(&todos[1]))
+	runSync(t, s)
+	assert.Empty(t, fake.issue(2).Assignees, "dave has no account")
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newFakeGitLab(t)
+	client.Token = "wrong"
+
+	_, err := client.CreateIssue(IssueRequest{Title: "x"})
+	var apiErr *integrations.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+	assert.Equal(t, "401 Unauthorized", apiErr.Message)
+
+	client.Token = "glpat-secret"
+	client.Project = "group/missing"
+	_, err = client.GetIssue(1)
+	require.ErrorAs(t, err, &apiErr, "a missing project is not a deleted issue")
+	assert.Equal(t, "404 Project Not Found", apiErr.Message)
+}
@@ -0,0 +1,266 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/git"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/reconcile"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxBlobSize is the largest file version parsed while walking history
+const maxBlobSize = 10 * 1024 * 1024
+
+// Event is a change to a TODO made by a commit
+type Event struct {
+	Type       string // one of the database.Event* constants
+	Commit     string
+	Author     string
+	Email      string
+	When       time.Time
+	FilePath   string // relative to the repository root, slash separated
+	LineNumber int
+	Content    string
+}
+
+// Lifecycle is the history of one TODO comment
+type Lifecycle struct {
+	Events []Event
+	// Last is the TODO as of the newest commit containing it
+	Last parser.ParsedTODO
+	// Removed is set when the TODO no longer exists at HEAD
+	Removed bool
+}
+
+// Introduced returns the event that introduced the TODO, if it was seen
+func (l *Lifecycle) Introduced() *Event {
+	if len(l.Events) > 0 && l.Events[0].Type == database.EventIntroduced {
+		return &l.Events[0]
+	}
+	return nil
+}
+
+// Options limits how much history is walked
+type Options struct {
+	// MaxCommits bounds the number of commits examined; 0 means all.
+	// TODOs present before the oldest examined commit have no
+	// introduced event.
+	MaxCommits int
+}
+
+// Result is the outcome of walking a repository's history
+type Result struct {
+	Root       string // repository root
+	Commits    int
+	Lifecycles []*Lifecycle
+}
+
+// Walk replays the first-parent history of the repository containing root,
+// oldest commit first, and follows every TODO through the commits that
+// introduce, edit, move and remove it. TODOs are matched between commits in
+// the same way scans are reconciled, so edits and renames keep a TODO's
+// identity.
+func Walk(root string, p *parser.Parser, opts Options) (*Result, error) {
+	repo, repoRoot, err := git.OpenRepo(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	c, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+
+	// Collect the first-parent chain, newest first; c ends as the base
+	// commit just before the walked range, or nil at the root
+	var chain []*object.Commit
+	for c != nil && (opts.MaxCommits <= 0 || len(chain) < opts.MaxCommits) {
+		chain = append(chain, c)
+		if c.NumParents() == 0 {
+			c = nil
+			break
+		}
+		if c, err = c.Parent(0); err != nil {
+			// Shallow clones end without their parents
+			c = nil
+		}
+	}
+
+	w := &walker{
+		parser: p,
+		root:   repoRoot,
+		live:   make(map[string][]*Lifecycle),
+	}
+	if c != nil {
+		if err := w.seed(c); err != nil {
+			return nil, err
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if err := w.apply(chain[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{Root: repoRoot, Commits: len(chain), Lifecycles: w.done}
+	for _, lcs := range w.live {
+		result.Lifecycles = append(result.Lifecycles, lcs...)
+	}
+	sort.SliceStable(result.Lifecycles, func(i, j int) bool {
+		a, b := result.Lifecycles[i].Last, result.Lifecycles[j].Last
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.LineNumber < b.LineNumber
+	})
+	return result, nil
+}
+
+// walker holds the TODOs alive at the commit being replayed
+type walker struct {
+	parser *parser.Parser
+	root   string
+	live   map[string][]*Lifecycle // keyed by file path
+	done   []*Lifecycle
+}
+
+// seed records the TODOs that already exist at the base commit
+func (w *walker) seed(c *object.Commit) error {
+	tree, err := c.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %w", c.Hash, err)
+	}
+	return tree.Files().ForEach(func(f *object.File) error {
+		for _, t := range w.parse(f) {
+			w.live[t.FilePath] = append(w.live[t.FilePath], &Lifecycle{Last: t})
+		}
+		return nil
+	})
+}
+
+// apply replays one commit against its first parent
+func (w *walker) apply(c *object.Commit) error {
+	tree, err := c.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %w", c.Hash, err)
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err == nil {
+			parentTree, _ = parent.Tree()
+		}
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return fmt.Errorf("failed to diff commit %s: %w", c.Hash, err)
+	}
+
+	var before []*Lifecycle
+	var after []parser.ParsedTODO
+	renames := make(map[string]string)
+	for _, ch := range changes {
+		if ch.From.Name != "" {
+			before = append(before, w.live[ch.From.Name]...)
+			delete(w.live, ch.From.Name)
+		}
+		if ch.To.Name != "" {
+			if _, to, err := ch.Files(); err == nil && to != nil {
+				after = append(after, w.parse(to)...)
+			}
+		}
+		if ch.From.Name != "" && ch.To.Name != "" && ch.From.Name != ch.To.Name {
+			renames[ch.From.Name] = ch.To.Name
+		}
+	}
+	if len(before) == 0 && len(after) == 0 {
+		return nil
+	}
+
+	oldItems := make([]reconcile.Item, len(before))
+	for i, lc := range before {
+		oldItems[i] = item(lc.Last)
+	}
+	newItems := make([]reconcile.Item, len(after))
+	for i, t := range after {
+		newItems[i] = item(t)
+	}
+	match := reconcile.Reconcile(oldItems, newItems, reconcile.Options{Renames: renames})
+
+	event := func(typ string, t parser.ParsedTODO) Event {
+		return Event{
+			Type:       typ,
+			Commit:     c.Hash.String(),
+			Author:     c.Author.Name,
+			Email:      c.Author.Email,
+			When:       c.Author.When,
+			FilePath:   t.FilePath,
+			LineNumber: t.LineNumber,
+			Content:    t.Content,
+		}
+	}
+
+	for _, m := range match.Matches {
+		lc := before[m.Old]
+		t := after[m.New]
+		switch {
+		case lc.Last.Content != t.Content || lc.Last.Type != t.Type:
+			lc.Events = append(lc.Events, event(database.EventModified, t))
+		case lc.Last.FilePath != t.FilePath:
+			lc.Events = append(lc.Events, event(database.EventMoved, t))
+		}
+		lc.Last = t
+		w.live[t.FilePath] = append(w.live[t.FilePath], lc)
+	}
+	for _, i := range match.Added {
+		t := after[i]
+		lc := &Lifecycle{Events: []Event{event(database.EventIntroduced, t)}, Last: t}
+		w.live[t.FilePath] = append(w.live[t.FilePath], lc)
+	}
+	for _, i := range match.Missing {
+		lc := before[i]
+		lc.Events = append(lc.Events, event(database.EventRemoved, lc.Last))
+		lc.Removed = true
+		w.done = append(w.done, lc)
+	}
+
+	return nil
+}
+
+// parse extracts the TODOs from one version of a file, skipping files the
+// parser is configured to ignore
+func (w *walker) parse(f *object.File) []parser.ParsedTODO {
+	if f.Size > maxBlobSize || !f.Mode.IsFile() || f.Mode == filemode.Symlink {
+		return nil
+	}
+	if w.parser.Ignored(filepath.Join(w.root, filepath.FromSlash(f.Name))) {
+		return nil
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil
+	}
+	return w.parser.ParseContent(f.Name, []byte(content))
+}
+
+func item(t parser.ParsedTODO) reconcile.Item {
+	return reconcile.Item{
+		FilePath:    t.FilePath,
+		LineNumber:  t.LineNumber,
+		Type:        t.Type,
+		Content:     t.Content,
+		ContextHash: t.ContextHash,
+	}
+}
@@ -0,0 +1,142 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/scanner"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commit writes files (an empty content removes the file) and commits them
+func commit(t *testing.T, repo *git.Repository, dir string, when time.Time, files map[string]string) string {
+	t.Helper()
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	for name, content := range files {
+		if content == "" {
+			_, err := wt.Remove(name)
+			require.NoError(t, err)
+			continue
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		_, err := wt.Add(name)
+		require.NoError(t, err)
+	}
+	hash, err := wt.Commit("change", &git.CommitOptions{
+		Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: when},
+	})
+	require.NoError(t, err)
+	return hash.String()
+}
+
+func newParser(dir string) *parser.Parser {
+	p := parser.New(nil, nil, nil)
+	p.SetRoot(dir)
+	return p
+}
+
+func TestWalkFollowsLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	intro := commit(t, repo, dir, start, map[string]string{
+		"a.go": "package main\n\n// TODO: handle errors\nfunc a() {}\n",
+	})
+	edit := commit(t, repo, dir, start.Add(24*time.Hour), map[string]string{
+		"a.go": "package main\n\n// TODO: handle all errors\nfunc a() {}\n",
+	})
+	move := commit(t, repo, dir, start.Add(48*time.Hour), map[string]string{
+		"a.go": "",
+		"b.go": "package main\n\n// TODO: handle all errors\nfunc a() {}\n",
+	})
+	remove := commit(t, repo, dir, start.Add(72*time.Hour), map[string]string{
+		"b.go": "package main\n\nfunc a() {}\n",
+	})
+
+	result, err := Walk(dir, newParser(dir), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Commits)
+	require.Len(t, result.Lifecycles, 1)
+
+	lc := result.Lifecycles[0]
+	assert.True(t, lc.Removed)
+	require.Len(t, lc.Events, 4)
+	assert.Equal(t, database.EventIntroduced, lc.Events[0].Type)
+	assert.Equal(t, intro, lc.Events[0].Commit)
+	assert.Equal(t, database.EventModified, lc.Events[1].Type)
+	assert.Equal(t, edit, lc.Events[1].Commit)
+	assert.Equal(t, database.EventMoved, lc.Events[2].Type)
+	assert.Equal(t, move, lc.Events[2].Commit)
+	assert.Equal(t, "b.go", lc.Events[2].FilePath)
+	assert.Equal(t, database.EventRemoved, lc.Events[3].Type)
+	assert.Equal(t, remove, lc.Events[3].Commit)
+}
+
+func TestWalkMaxCommitsSeedsExistingTODOs(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	commit(t, repo, dir, start, map[string]string{"a.go": "package main\n\n// TODO: old\n"})
+	commit(t, repo, dir, start.Add(time.Hour), map[string]string{"b.go": "package main\n\n// FIXME: new\n"})
+
+	result, err := Walk(dir, newParser(dir), Options{MaxCommits: 1})
+	require.NoError(t, err)
+	require.Len(t, result.Lifecycles, 2)
+	assert.Nil(t, result.Lifecycles[0].Introduced(), "TODO older than the window has no introduction")
+	require.NotNil(t, result.Lifecycles[1].Introduced())
+}
+
+func TestRecordStoresEvents(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	commit(t, repo, dir, start, map[string]string{"main.go": "package main\n\n// TODO: cache results\nfunc main() {}\n"})
+	commit(t, repo, dir, start.Add(time.Hour), map[string]string{"main.go": "package main\n\n// TODO: cache results per user\nfunc main() {}\n"})
+
+	db, err := database.New(dir)
+	require.NoError(t, err)
+	todos, err := newParser(dir).ParseDir(dir)
+	require.NoError(t, err)
+	scanned, err := scanner.Sync(db, dir, todos, scanner.Options{})
+	require.NoError(t, err)
+	require.Len(t, scanned.New, 1)
+	id := scanned.New[0].ID
+
+	result, err := Walk(dir, newParser(dir), Options{})
+	require.NoError(t, err)
+	summary, err := Record(db, result)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Matched)
+	assert.Equal(t, 1, summary.Introduced)
+
+	todo, err := db.GetTODOByID(id)
+	require.NoError(t, err)
+	assert.True(t, todo.CreatedAt.Equal(start), "created_at should be the introducing commit time, got %s", todo.CreatedAt)
+
+	events, err := db.GetTODOEvents(id)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, database.EventIntroduced, events[0].Type)
+	assert.Equal(t, database.EventModified, events[1].Type)
+
+	// Recording again replaces rather than duplicates events
+	_, err = Record(db, result)
+	require.NoError(t, err)
+	events, err = db.GetTODOEvents(id)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
@@ -0,0 +1,134 @@
+package history
+
+import (
+	"path/filepath"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/reconcile"
+	"gorm.io/gorm"
+)
+
+// Summary counts what Record stored
+type Summary struct {
+	Matched    int // tracked TODOs whose history was found
+	Introduced int // of those, TODOs whose introducing commit is known
+	Removed    int // of those, TODOs whose removing commit is known
+}
+
+// Record matches walked lifecycles to tracked TODOs and stores their events.
+// A tracked TODO takes its creation time and, if unknown, its author from
+// the commit that introduced it; removed TODOs take the removing commit.
+// Running Record again replaces the stored events.
+func Record(db *database.DB, result *Result) (*Summary, error) {
+	todos, err := db.GetTODOs(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Present and removed TODOs are matched separately so a TODO that was
+	// removed and later re-added does not inherit the old lifecycle
+	var present, removed []database.TODO
+	for _, t := range todos {
+		if t.RemovedAt == nil {
+			present = append(present, t)
+		} else {
+			removed = append(removed, t)
+		}
+	}
+	var alive, gone []*Lifecycle
+	for _, lc := range result.Lifecycles {
+		if lc.Removed {
+			gone = append(gone, lc)
+		} else {
+			alive = append(alive, lc)
+		}
+	}
+
+	summary := &Summary{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txdb := &database.DB{DB: tx}
+		for _, group := range []struct {
+			todos      []database.TODO
+			lifecycles []*Lifecycle
+		}{{present, alive}, {removed, gone}} {
+			match := reconcile.Reconcile(todoItems(group.todos), lifecycleItems(result.Root, group.lifecycles), reconcile.Options{})
+			for _, m := range match.Matches {
+				if err := record(txdb, group.todos[m.Old], group.lifecycles[m.New], summary); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// record stores the lifecycle of one tracked TODO
+func record(db *database.DB, t database.TODO, lc *Lifecycle, summary *Summary) error {
+	events := make([]database.TODOEvent, len(lc.Events))
+	for i, e := range lc.Events {
+		events[i] = database.TODOEvent{
+			Type:        e.Type,
+			Commit:      e.Commit,
+			Author:      e.Author,
+			Email:       e.Email,
+			FilePath:    e.FilePath,
+			LineNumber:  e.LineNumber,
+			Content:     e.Content,
+			CommittedAt: e.When,
+		}
+	}
+	if err := db.ReplaceTODOEvents(t.ID, events); err != nil {
+		return err
+	}
+	summary.Matched++
+
+	fields := make(map[string]interface{})
+	if intro := lc.Introduced(); intro != nil {
+		summary.Introduced++
+		fields["created_at"] = intro.When
+		if t.Author == "" {
+			fields["author"] = intro.Author
+			fields["email"] = intro.Email
+		}
+	}
+	if n := len(lc.Events); lc.Removed && n > 0 {
+		summary.Removed++
+		last := lc.Events[n-1]
+		fields["removed_commit"] = last.Commit
+		fields["removed_at"] = last.When
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return db.Model(&database.TODO{}).Where("id = ?", t.ID).UpdateColumns(fields).Error
+}
+
+func todoItems(todos []database.TODO) []reconcile.Item {
+	items := make([]reconcile.Item, len(todos))
+	for i, t := range todos {
+		items[i] = reconcile.Item{
+			FilePath:    t.FilePath,
+			LineNumber:  t.LineNumber,
+			Type:        t.Type,
+			Content:     t.Content,
+			ContextHash: t.ContextHash,
+		}
+	}
+	return items
+}
+
+// lifecycleItems describes lifecycles by their last state, with paths made
+// absolute to match those stored by scans
+func lifecycleItems(root string, lifecycles []*Lifecycle) []reconcile.Item {
+	items := make([]reconcile.Item, len(lifecycles))
+	for i, lc := range lifecycles {
+		it := item(lc.Last)
+		it.FilePath = filepath.Join(root, filepath.FromSlash(it.FilePath))
+		items[i] = it
+	}
+	return items
+}
@@ -0,0 +1,207 @@
+package integrations
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry is an http.RoundTripper that retries requests a tracker turned
+// away. Rate-limited requests (429, or GitHub's 403 with no requests left)
+// are retried after the wait the server asks for; failed connections and
+// 502, 503 and 504 responses are retried with exponential backoff, but only
+// for methods that are safe to repeat.
+type Retry struct {
+	// Base sends the requests; nil means http.DefaultTransport
+	Base http.RoundTripper
+	// Attempts is how often a request is tried; 0 means 4
+	Attempts int
+	// Backoff is the first wait, doubled on every retry; 0 means 1 second
+	Backoff time.Duration
+	// MaxWait is the longest wait honoured; a server asking for longer gets
+	// its response returned. 0 means 1 minute.
+	MaxWait time.Duration
+}
+
+// NewHTTPClient returns the client adapters talk to their tracker with
+func NewHTTPClient() *http.Client {
+	return &http.Client{Timeout: 30 * time.Second, Transport: &Retry{}}
+}
+
+// APIError is an unsuccessful response from the API of a tracker
+type APIError struct {
+	API     string // such as GitHub
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s API returned HTTP %d", e.API, e.Status)
+	}
+	return fmt.Sprintf("%s API returned HTTP %d: %s", e.API, e.Status, e.Message)
+}
+
+// HasStatus reports whether err is an APIError with the HTTP status
+func HasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == status
+}
+
+// DoJSON sends req with in, unless nil, as its JSON body and decodes the
+// JSON response, if any, into out. Unsuccessful responses are turned into
+// an error by decodeErr, which reads the start of their body.
+func DoJSON(client *http.Client, req *http.Request, in, out interface{}, decodeErr func(status int, body io.Reader) error) error {
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return decodeErr(resp.StatusCode, io.LimitReader(resp.Body, 1<<16))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip sends a request, retrying it as described on Retry
+func (r *Retry) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	attempts := r.Attempts
+	if attempts <= 0 {
+		attempts = 4
+	}
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxWait := r.MaxWait
+	if maxWait <= 0 {
+		maxWait = time.Minute
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := base.RoundTrip(req)
+		if attempt == attempts {
+			return resp, err
+		}
+
+		wait := backoff << (attempt - 1)
+		switch {
+		case err != nil:
+			if !idempotent(req.Method) {
+				return nil, err
+			}
+		case rateLimited(resp):
+			if after, ok := retryAfter(resp, time.Now()); ok {
+				wait = after
+			}
+		case retryable(resp.StatusCode) && idempotent(req.Method):
+			if after, ok := retryAfter(resp, time.Now()); ok {
+				wait = after
+			}
+		default:
+			return resp, nil
+		}
+		if wait > maxWait {
+			return resp, err
+		}
+
+		// Bodies are sent again from the start
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimited reports whether a response turned a request away for going
+// over the rate limit
+func rateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+func retryable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// idempotent reports whether a request may be sent twice without harm.
+// A POST that failed half way may already have created an issue.
+func idempotent(method string) bool {
+	return method != http.MethodPost
+}
+
+// retryAfter returns how long a response asks to wait, from Retry-After in
+// seconds or as a date, or from the X-RateLimit-Reset time GitHub and
+// GitLab send
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return nonNegative(at.Sub(now)), true
+		}
+	}
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return nonNegative(time.Unix(unix, 0).Sub(now)), true
+		}
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
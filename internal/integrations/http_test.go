@@ -0,0 +1,148 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRetryClient() *http.Client {
+	return &http.Client{Transport: &Retry{Backoff: time.Millisecond, MaxWait: time.Second}}
+}
+
+func TestRetryRateLimits(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch len(bodies) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "0")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := newRetryClient().Post(srv.URL, "application/json", strings.NewReader(`{"title":"x"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, []string{`{"title":"x"}`, `{"title":"x"}`, `{"title":"x"}`}, bodies, "bodies are sent again")
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	client := newRetryClient()
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 4, calls, "gives up after the last attempt")
+
+	// A POST may have gone through, so it is not sent twice
+	calls = 0
+	resp, err = client.Post(srv.URL, "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryLongWaits(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	resp, err := newRetryClient().Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 1, calls, "waits past MaxWait are not honoured")
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	header := func(key, value string) *http.Response {
+		return &http.Response{Header: http.Header{key: []string{value}}}
+	}
+
+	wait, ok := retryAfter(header("Retry-After", "30"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+
+	wait, ok = retryAfter(header("Retry-After", now.Add(time.Minute).Format(http.TimeFormat)), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, wait)
+
+	wait, ok = retryAfter(header("X-Ratelimit-Reset", "1735732810"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+
+	_, ok = retryAfter(header("Retry-After", "soon"), now)
+	assert.False(t, ok)
+}
+
+func TestDoJSON(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Type")+" "+string(body))
+		switch r.URL.Path {
+		case "/busy":
+			if len(bodies) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{"number":7}`)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		}
+	}))
+	defer srv.Close()
+	decodeErr := func(status int, body io.Reader) error {
+		var e struct{ Message string }
+		json.NewDecoder(body).Decode(&e)
+		return &APIError{API: "Test", Status: status, Message: e.Message}
+	}
+
+	req, _ := http.NewRequest(http.MethodPatch, srv.URL+"/busy", nil)
+	var out struct{ Number int }
+	require.NoError(t, DoJSON(newRetryClient(), req, map[string]string{"title": "x"}, &out, decodeErr))
+	assert.Equal(t, 7, out.Number)
+	assert.Equal(t, []string{`application/json {"title":"x"}`, `application/json {"title":"x"}`}, bodies, "bodies are sent again")
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/empty", nil)
+	assert.NoError(t, DoJSON(newRetryClient(), req, nil, &out, decodeErr))
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/missing", nil)
+	err := DoJSON(newRetryClient(), req, nil, &out, decodeErr)
+	assert.True(t, HasStatus(err, http.StatusNotFound))
+	assert.EqualError(t, err, "Test API returned HTTP 404: Not Found")
+}
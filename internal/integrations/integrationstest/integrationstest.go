@@ -0,0 +1,376 @@
+// Package integrationstest checks that an issue tracker adapter syncs the
+// way integrations.Syncer expects, run against a fake of the tracker's API
+package integrationstest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Remote is the fake API an adapter talks to
+type Remote interface {
+	// Body returns the text of the body of an issue
+	Body(key string) string
+	// Delete deletes an issue, as a person on the tracker would
+	Delete(key string)
+	// Writes counts the requests that created or changed issues
+	Writes() int
+}
+
+// Setup returns an adapter talking to a new fake, on which the users
+// alice and bob can be assigned
+type Setup func(t *testing.T) (integrations.Tracker, Remote)
+
+// Run runs the conformance tests against the adapter setup returns. Fields
+// the adapter leaves out in Map are not checked.
+func Run(t *testing.T, setup Setup) {
+	tests := []struct {
+		name string
+		test func(*testing.T, *env)
+	}{
+		{"CreatesIssuesOnce", testCreatesIssuesOnce},
+		{"PushesLocalChanges", testPushesLocalChanges},
+		{"ClosesIssuesOfDeletedTODOs", testClosesIssuesOfDeletedTODOs},
+		{"ImportsRemoteChanges", testImportsRemoteChanges},
+		{"Conflicts", testConflicts},
+		{"RecreatesDeletedIssues", testRecreatesDeletedIssues},
+		{"DryRun", testDryRun},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newEnv(t, setup))
+		})
+	}
+}
+
+// env is a sync of three TODOs, the last of them finished, with a fake
+type env struct {
+	*integrations.Syncer
+	remote Remote
+	todos  []database.TODO
+	// holds is what Map keeps of a TODO with every field set
+	holds integrations.Fields
+}
+
+func newEnv(t *testing.T, setup Setup) *env {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	due := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P2", Category: "backend", Assignee: "alice", DueDate: &due, Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser", Status: "in_progress", Priority: "P1", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Priority: "P3", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	tracker, remote := setup(t)
+	return &env{
+		Syncer: &integrations.Syncer{Tracker: tracker, DB: db},
+		remote: remote,
+		todos:  todos,
+		holds:  tracker.Map(&todos[0], []string{"security"}),
+	}
+}
+
+// run syncs, failing on any error
+func (e *env) run(t *testing.T) *integrations.Result {
+	t.Helper()
+	result, err := e.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+// update saves a changed TODO
+func (e *env) update(t *testing.T, i int) {
+	t.Helper()
+	require.NoError(t, e.DB.UpdateTODO(&e.todos[i]))
+}
+
+// todo reads a TODO back from the database
+func (e *env) todo(t *testing.T, i int) *database.TODO {
+	t.Helper()
+	todo, err := e.DB.GetTODOByID(e.todos[i].ID)
+	require.NoError(t, err)
+	return todo
+}
+
+// key returns the key of the issue a TODO is linked to
+func (e *env) key(t *testing.T, i int) string {
+	t.Helper()
+	links, err := e.DB.GetIssueLinksForTODO(e.todos[i].ID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	return links[0].Key
+}
+
+// fetch reads the issue of a TODO from the fake
+func (e *env) fetch(t *testing.T, i int) *integrations.Issue {
+	t.Helper()
+	issue, err := e.Tracker.Fetch(e.key(t, i))
+	require.NoError(t, err)
+	return issue
+}
+
+// edit changes the issue of a TODO as a person on the tracker would,
+// through the adapter
+func (e *env) edit(t *testing.T, i int, change func(*integrations.Fields)) {
+	t.Helper()
+	issue := e.fetch(t, i)
+	fields := issue.Fields
+	if fields.Labels != nil {
+		fields.Labels = append([]string{}, fields.Labels...)
+	}
+	change(&fields)
+	_, err := e.Tracker.Update(issue, e.todo(t, i), fields)
+	require.NoError(t, err)
+}
+
+func (e *env) tags(t *testing.T, i int) []string {
+	t.Helper()
+	tags, err := e.DB.GetTagsForTODO(e.todos[i].ID)
+	require.NoError(t, err)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func location(todo database.TODO) string {
+	return fmt.Sprintf("%s:%d", todo.FilePath, todo.LineNumber)
+}
+
+func testCreatesIssuesOnce(t *testing.T, e *env) {
+	result := e.run(t)
+	assert.Equal(t, 2, result.Created, "finished TODOs get no issue")
+
+	for i, todo := range e.todos[:2] {
+		links, err := e.DB.GetIssueLinksForTODO(todo.ID)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, e.Tracker.Name(), links[0].Tracker)
+		assert.NotEmpty(t, links[0].URL)
+
+		issue := e.fetch(t, i)
+		assert.Equal(t, todo.Content, issue.Title)
+		assert.Contains(t, issue.Statuses, todo.Status)
+		if e.holds.Priority != "" {
+			assert.Equal(t, todo.Priority, issue.Priority)
+		}
+		if e.holds.Assignee != "" {
+			assert.Equal(t, todo.Assignee, issue.Assignee)
+		}
+		if e.holds.Body != "" {
+			assert.Contains(t, e.remote.Body(issue.Key), location(todo))
+		}
+	}
+	if e.holds.DueDate != "" {
+		assert.Equal(t, "2025-07-01", e.fetch(t, 0).DueDate)
+	}
+
+	// Nothing changed, so nothing is written
+	writes := e.remote.Writes()
+	result = e.run(t)
+	assert.Equal(t, 2, result.Unchanged)
+	assert.Equal(t, writes, e.remote.Writes())
+}
+
+func testPushesLocalChanges(t *testing.T, e *env) {
+	e.run(t)
+
+	e.todos[0].Status = "resolved"
+	e.todos[0].LineNumber = 5
+	e.todos[0].Priority = "P3"
+	e.todos[0].Assignee = ""
+	due := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	e.todos[0].DueDate = &due
+	e.update(t, 0)
+	e.todos[1].Status = "wontfix"
+	e.update(t, 1)
+	tag, err := e.DB.GetOrCreateTag("security")
+	require.NoError(t, err)
+	require.NoError(t, e.DB.AddTagToTODO(e.todos[1].ID, tag.ID))
+
+	result := e.run(t)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, 0, result.Imported)
+
+	issue := e.fetch(t, 0)
+	assert.Contains(t, issue.Statuses, "resolved")
+	if e.holds.Priority != "" {
+		assert.Equal(t, "P3", issue.Priority)
+	}
+	if e.holds.Assignee != "" {
+		assert.Empty(t, issue.Assignee)
+	}
+	if e.holds.DueDate != "" {
+		assert.Equal(t, "2025-03-01", issue.DueDate)
+	}
+	if e.holds.Body != "" {
+		assert.Contains(t, e.remote.Body(issue.Key), location(e.todos[0]))
+	}
+	issue = e.fetch(t, 1)
+	assert.Contains(t, issue.Statuses, "wontfix")
+	if len(e.holds.Labels) > 0 {
+		assert.Contains(t, issue.Labels, "security")
+	}
+
+	// Both now match
+	assert.Equal(t, 2, e.run(t).Unchanged)
+
+	if e.holds.DueDate != "" {
+		e.todos[0].DueDate = nil
+		e.update(t, 0)
+		e.run(t)
+		assert.Empty(t, e.fetch(t, 0).DueDate)
+		assert.Equal(t, 2, e.run(t).Unchanged)
+	}
+
+	// A finished TODO keeps its status while its issue stays finished
+	e.edit(t, 1, func(f *integrations.Fields) { f.Priority = "P0" })
+	assert.Equal(t, 1, e.run(t).Imported)
+	todo := e.todo(t, 1)
+	assert.Equal(t, "wontfix", todo.Status)
+	if e.holds.Priority != "" {
+		assert.Equal(t, "P0", todo.Priority)
+	}
+}
+
+func testClosesIssuesOfDeletedTODOs(t *testing.T, e *env) {
+	e.run(t)
+	key := e.key(t, 1)
+
+	require.NoError(t, e.DB.DeleteTODO(e.todos[1].ID))
+	result := e.run(t)
+	links, err := e.DB.GetIssueLinks(e.Tracker.Name())
+	require.NoError(t, err)
+	if result.Closed == 0 {
+		// Trackers that cannot close issues leave them, and their link, alone
+		assert.ErrorIs(t, e.Tracker.Close(key), integrations.ErrNotSupported)
+		assert.Len(t, links, 2)
+		return
+	}
+	assert.Equal(t, 1, result.Closed)
+	assert.Len(t, links, 1)
+	issue, err := e.Tracker.Fetch(key)
+	if err == nil {
+		assert.True(t, database.IsDone(issue.Status), "issue %s is %s", key, issue.Status)
+	} else {
+		assert.ErrorIs(t, err, integrations.ErrIssueGone)
+	}
+}
+
+func testImportsRemoteChanges(t *testing.T, e *env) {
+	e.run(t)
+
+	e.edit(t, 0, func(f *integrations.Fields) {
+		f.Status = "resolved"
+		f.Priority = "P0"
+		f.Assignee = "bob"
+		f.Labels = append(f.Labels, "security")
+		f.DueDate = "2025-06-30"
+	})
+	e.edit(t, 1, func(f *integrations.Fields) { f.Priority = "P3" })
+
+	result := e.run(t)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 0, result.Updated)
+
+	todo := e.todo(t, 0)
+	assert.Equal(t, "resolved", todo.Status)
+	assert.Equal(t, "backend", todo.Category)
+	if e.holds.Priority != "" {
+		assert.Equal(t, "P0", todo.Priority)
+	}
+	if e.holds.Assignee != "" {
+		assert.Equal(t, "bob", todo.Assignee)
+	}
+	if len(e.holds.Labels) > 0 {
+		assert.Equal(t, []string{"security"}, e.tags(t, 0))
+	}
+	if e.holds.DueDate != "" {
+		require.NotNil(t, todo.DueDate)
+		assert.Equal(t, "2025-06-30", todo.DueDate.Format("2006-01-02"))
+	}
+	assert.Equal(t, "in_progress", e.todo(t, 1).Status, "open issues keep the local status")
+
+	// Reopening the issue reopens the TODO
+	e.edit(t, 0, func(f *integrations.Fields) { f.Status = "open" })
+	e.run(t)
+	assert.Equal(t, "open", e.todo(t, 0).Status)
+	assert.Equal(t, 0, e.run(t).Imported)
+}
+
+func testConflicts(t *testing.T, e *env) {
+	if e.holds.Priority == "" {
+		t.Skip("priorities are not synced")
+	}
+	e.run(t)
+
+	e.todos[0].Priority = "P0"
+	e.update(t, 0)
+	e.edit(t, 0, func(f *integrations.Fields) { f.Priority = "P3" })
+
+	result := e.run(t)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, e.todos[0].ID, result.Conflicts[0].TODO.ID)
+	assert.Equal(t, e.key(t, 0), result.Conflicts[0].Issue.Key)
+	assert.Equal(t, "P3", e.fetch(t, 0).Priority, "conflicts are left alone")
+
+	e.Prefer = integrations.PreferRemote
+	assert.Empty(t, e.run(t).Conflicts)
+	assert.Equal(t, "P3", e.todo(t, 0).Priority)
+
+	e.todos[0].Priority = "P0"
+	e.update(t, 0)
+	e.edit(t, 0, func(f *integrations.Fields) { f.Priority = "P4" })
+	e.Prefer = integrations.PreferLocal
+	e.run(t)
+	assert.Equal(t, "P0", e.fetch(t, 0).Priority)
+}
+
+func testRecreatesDeletedIssues(t *testing.T, e *env) {
+	e.run(t)
+	key := e.key(t, 0)
+
+	e.remote.Delete(key)
+	result := e.run(t)
+	assert.Equal(t, 1, result.Created)
+	assert.NotEqual(t, key, e.key(t, 0))
+}
+
+func testDryRun(t *testing.T, e *env) {
+	e.DryRun = true
+	result := e.run(t)
+	assert.Equal(t, 2, result.Created)
+	assert.Zero(t, e.remote.Writes())
+	links, err := e.DB.GetIssueLinks(e.Tracker.Name())
+	require.NoError(t, err)
+	assert.Empty(t, links)
+
+	e.DryRun = false
+	e.run(t)
+	e.DryRun = true
+
+	e.todos[0].Content = "add retries with backoff"
+	e.update(t, 0)
+	e.edit(t, 1, func(f *integrations.Fields) { f.Status = "resolved" })
+	writes := e.remote.Writes()
+	result = e.run(t)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, "add retries", e.fetch(t, 0).Title)
+	assert.Equal(t, "in_progress", e.todo(t, 1).Status)
+	assert.Equal(t, writes, e.remote.Writes())
+
+	// Nothing was written, so the same changes are found again
+	assert.Equal(t, result, e.run(t))
+}
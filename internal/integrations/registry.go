@@ -0,0 +1,75 @@
+package integrations
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+)
+
+// Adapter is a kind of tracker TODOs can be synced with. Adapter packages
+// register theirs when they are imported.
+type Adapter struct {
+	// Name identifies the adapter, as in todo sync <name>
+	Name string
+	// Title names the tracker to people, e.g. "GitHub Issues"
+	Title string
+	// New returns a tracker set up from the config, or an error saying
+	// which setting is missing
+	New func(cfg *config.Config) (Tracker, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Adapter)
+)
+
+// Register makes an adapter available by name. It panics if the name is
+// taken, as two adapters of one name are a programming error.
+func Register(a Adapter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if a.Name == "" || a.New == nil {
+		panic("integrations: adapter needs a name and a constructor")
+	}
+	if _, ok := registry[a.Name]; ok {
+		panic("integrations: adapter " + a.Name + " registered twice")
+	}
+	registry[a.Name] = a
+}
+
+// Lookup returns the adapter registered under name
+func Lookup(name string) (Adapter, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	a, ok := registry[name]
+	return a, ok
+}
+
+// Adapters returns the registered adapters sorted by name
+func Adapters() []Adapter {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	adapters := make([]Adapter, 0, len(registry))
+	for _, a := range registry {
+		adapters = append(adapters, a)
+	}
+	sort.Slice(adapters, func(i, j int) bool { return adapters[i].Name < adapters[j].Name })
+	return adapters
+}
+
+// New returns the tracker of the adapter registered under name
+func New(name string, cfg *config.Config) (Tracker, error) {
+	a, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown issue tracker %q", name)
+	}
+	return a.New(cfg)
+}
+
+// NotConfigured is the error adapters return for a missing setting; key is
+// the one todo config set takes
+func NotConfigured(what, key, value string) error {
+	return fmt.Errorf("%s not configured. Run: todo config set %s <%s>", what, key, value)
+}
@@ -0,0 +1,33 @@
+package integrations_test
+
+import (
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	integrations.Register(integrations.Adapter{Name: "zz-fake", Title: "Fake", New: func(cfg *config.Config) (integrations.Tracker, error) {
+		if cfg.GitHub.Token == "" {
+			return nil, integrations.NotConfigured("GitHub token", "integration.github.token", "token")
+		}
+		return newFakeTracker(), nil
+	}})
+
+	a, ok := integrations.Lookup("zz-fake")
+	require.True(t, ok)
+	assert.Equal(t, "Fake", a.Title)
+	assert.Equal(t, "zz-fake", integrations.Adapters()[len(integrations.Adapters())-1].Name)
+
+	_, err := integrations.New("zz-fake", &config.Config{})
+	assert.EqualError(t, err, "GitHub token not configured. Run: todo config set integration.github.token <token>")
+	_, err = integrations.New("missing", &config.Config{})
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		integrations.Register(integrations.Adapter{Name: "zz-fake", New: a.New})
+	})
+}
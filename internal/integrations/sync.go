@@ -0,0 +1,388 @@
+package integrations
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+)
+
+// Ways to settle a conflict, where both a TODO and its issue changed since
+// the last sync
+const (
+	PreferLocal  = "local"
+	PreferRemote = "remote"
+)
+
+// Syncer keeps the TODOs of a project and the issues of a tracker in sync.
+// TODOs get an issue when first synced; afterwards changes on either side
+// are carried to the other.
+type Syncer struct {
+	Tracker Tracker
+	DB      *database.DB
+	// Prefer settles conflicts: PreferLocal, PreferRemote, or "" to report
+	// them and leave both sides alone
+	Prefer string
+	// DryRun reads both sides and reports what a sync would do without
+	// changing either. Issues of deleted TODOs are counted as closed even on
+	// trackers that keep them.
+	DryRun bool
+}
+
+// Result reports what a sync did
+type Result struct {
+	Created   int
+	Updated   int // issues changed to match their TODO
+	Imported  int // TODOs changed to match their issue
+	Closed    int // issues closed because their TODO was deleted
+	Unchanged int
+	Conflicts []Conflict
+	// Errors are failures of single TODOs; the sync carries on past them
+	Errors []error
+}
+
+// Conflict is a TODO and an issue that both changed since the last sync
+type Conflict struct {
+	TODO  database.TODO
+	Issue Issue
+}
+
+// Sync syncs every TODO of the project
+func (s *Syncer) Sync() (*Result, error) {
+	todos, err := s.DB.GetTODOs(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TODOs: %w", err)
+	}
+	links, err := s.DB.GetIssueLinks(s.Tracker.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue links: %w", err)
+	}
+	linked := make(map[string]*database.IssueLink, len(links))
+	for i := range links {
+		linked[links[i].TODOID] = &links[i]
+	}
+
+	result := &Result{}
+	for i := range todos {
+		todo := &todos[i]
+		link := linked[todo.ID]
+		delete(linked, todo.ID)
+		if err := s.syncTODO(todo, link, result); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("TODO %s: %w", shortID(todo.ID), err))
+		}
+	}
+
+	// Links left over belong to TODOs deleted from the database
+	for _, link := range linked {
+		if s.DryRun {
+			result.Closed++
+			continue
+		}
+		err := s.Tracker.Close(link.Key)
+		if errors.Is(err, ErrNotSupported) {
+			continue
+		}
+		if err == nil || errors.Is(err, ErrIssueGone) {
+			err = s.DB.DeleteIssueLink(link)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("issue %s: %w", link.Key, err))
+			continue
+		}
+		result.Closed++
+	}
+	return result, nil
+}
+
+func (s *Syncer) syncTODO(todo *database.TODO, link *database.IssueLink, result *Result) error {
+	tags, err := s.tags(todo.ID)
+	if err != nil {
+		return err
+	}
+	want := s.Tracker.Map(todo, tags)
+
+	var issue *Issue
+	if link != nil {
+		issue, err = s.Tracker.Fetch(link.Key)
+		if errors.Is(err, ErrIssueGone) {
+			// Start over with a new issue
+			if s.DryRun {
+				link = nil
+			} else if err := s.DB.DeleteIssueLink(link); err != nil {
+				return fmt.Errorf("failed to unlink issue %s: %w", link.Key, err)
+			}
+			link = nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if link == nil {
+		// Finished work needs no issue
+		if database.IsDone(todo.Status) {
+			return nil
+		}
+		if s.DryRun {
+			result.Created++
+			return nil
+		}
+		issue, err := s.Tracker.Create(todo, want)
+		if issue == nil {
+			return err
+		}
+		result.Created++
+		link := &database.IssueLink{TODOID: todo.ID, Tracker: s.Tracker.Name()}
+		if err != nil {
+			// Link the issue all the same, with no fields written, so the
+			// next sync updates it rather than opening another
+			if linkErr := s.saveLink(link, Fields{}, issue); linkErr != nil {
+				return linkErr
+			}
+			return err
+		}
+		return s.saveLink(link, want, issue)
+	}
+
+	localChanged := Fingerprint(want) != link.LocalHash
+	remoteChanged := issue.Revision != link.RemoteHash
+	if localChanged && remoteChanged && s.Prefer == "" {
+		result.Conflicts = append(result.Conflicts, Conflict{TODO: *todo, Issue: *issue})
+		return nil
+	}
+	if s.DryRun {
+		s.plan(localChanged, remoteChanged, result)
+		return nil
+	}
+
+	if remoteChanged && (!localChanged || s.Prefer == PreferRemote) {
+		if tags, err = s.pull(todo, tags, want, issue); err != nil {
+			return err
+		}
+		want = s.Tracker.Map(todo, tags)
+		result.Imported++
+	}
+
+	// The body is not read back, so it is only written when the TODO changed
+	push := want
+	if !localChanged {
+		push.Body = issue.Body
+	}
+	updated, err := s.Tracker.Update(issue, todo, push)
+	switch {
+	case err != nil:
+		return err
+	case updated != nil:
+		issue = updated
+		result.Updated++
+	case !remoteChanged:
+		result.Unchanged++
+		if !localChanged {
+			return nil
+		}
+	}
+	return s.saveLink(link, want, issue)
+}
+
+// plan counts what syncing a linked TODO would do. Whether an issue needs
+// writing is up to its tracker, so it is taken to when its TODO changed.
+func (s *Syncer) plan(localChanged, remoteChanged bool, result *Result) {
+	pulls := remoteChanged && (!localChanged || s.Prefer == PreferRemote)
+	if pulls {
+		result.Imported++
+	}
+	switch {
+	case localChanged && (!remoteChanged || s.Prefer == PreferLocal):
+		result.Updated++
+	case !pulls:
+		result.Unchanged++
+	}
+}
+
+// pull applies the fields of an issue that differ from its TODO and returns
+// the TODO's tags
+func (s *Syncer) pull(todo *database.TODO, tags []string, want Fields, issue *Issue) ([]string, error) {
+	if len(issue.Statuses) > 0 && !contains(issue.Statuses, todo.Status) {
+		todo.Status = issue.Statuses[0]
+	}
+	if issue.Priority != "" && issue.Priority != want.Priority {
+		todo.Priority = issue.Priority
+	}
+	if issue.Assignee != want.Assignee {
+		todo.Assignee = issue.Assignee
+	}
+	if issue.Category != want.Category {
+		todo.Category = issue.Category
+	}
+	if issue.DueDate != want.DueDate {
+		todo.DueDate = nil
+		if due, err := time.Parse("2006-01-02", issue.DueDate); err == nil {
+			todo.DueDate = &due
+		}
+	}
+	if err := s.DB.UpdateTODO(todo); err != nil {
+		return nil, fmt.Errorf("failed to update TODO: %w", err)
+	}
+
+	// Labels other than the category become the TODO's tags
+	if sameLabels(want.Labels, issue.Labels) {
+		return tags, nil
+	}
+	wanted := make(map[string]string)
+	for _, label := range issue.Labels {
+		if labelKey(label) != labelKey(todo.Category) {
+			wanted[labelKey(label)] = label
+		}
+	}
+	var kept []string
+	for _, name := range tags {
+		if _, ok := wanted[labelKey(name)]; ok {
+			kept = append(kept, name)
+			delete(wanted, labelKey(name))
+			continue
+		}
+		tag, err := s.DB.GetOrCreateTag(name)
+		if err == nil {
+			err = s.DB.RemoveTagFromTODO(todo.ID, tag.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove tag %s: %w", name, err)
+		}
+	}
+	for _, name := range wanted {
+		tag, err := s.DB.GetOrCreateTag(name)
+		if err == nil {
+			err = s.DB.AddTagToTODO(todo.ID, tag.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to add tag %s: %w", name, err)
+		}
+		kept = append(kept, name)
+	}
+	sort.Strings(kept)
+	return kept, nil
+}
+
+// saveLink records both sides as they are after a sync
+func (s *Syncer) saveLink(link *database.IssueLink, want Fields, issue *Issue) error {
+	link.Key = issue.Key
+	link.URL = issue.URL
+	link.LocalHash = Fingerprint(want)
+	link.RemoteHash = issue.Revision
+	link.SyncedAt = time.Now()
+	if err := s.DB.SaveIssueLink(link); err != nil {
+		return fmt.Errorf("failed to save link to issue %s: %w", issue.Key, err)
+	}
+	return nil
+}
+
+func (s *Syncer) tags(todoID string) ([]string, error) {
+	tags, err := s.DB.GetTagsForTODO(todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Fingerprint hashes a value, such as the fields of an issue
+func Fingerprint(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum[:16])
+}
+
+// Body renders a TODO as the Markdown description of its issue
+func Body(todo *database.TODO) string {
+	body := fmt.Sprintf("**Source:** %s:%d\n", todo.FilePath, todo.LineNumber)
+	body += fmt.Sprintf("**Type:** %s\n", todo.Type)
+	if todo.Author != "" {
+		body += fmt.Sprintf("**Author:** %s\n", todo.Author)
+	}
+	if todo.DueDate != nil {
+		body += fmt.Sprintf("**Due Date:** %s\n", todo.DueDate.Format("2006-01-02"))
+	}
+	return body + "\n---\n\n" + todo.Content
+}
+
+// Title renders a TODO as a one-line title of at most max bytes, cut
+// between characters
+func Title(todo *database.TODO, max int) string {
+	title := strings.Join(strings.Fields(todo.Content), " ")
+	if len(title) > max {
+		cut := max - 3
+		for cut > 0 && !utf8.RuneStart(title[cut]) {
+			cut--
+		}
+		title = title[:cut] + "..."
+	}
+	return title
+}
+
+// DueDate formats the due date of a TODO, or returns "" without one
+func DueDate(todo *database.TODO) string {
+	if todo.DueDate == nil {
+		return ""
+	}
+	return todo.DueDate.Format("2006-01-02")
+}
+
+// Normalize drops empty labels, sorts the rest and drops duplicates
+func Normalize(labels []string) []string {
+	sorted := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label != "" {
+			sorted = append(sorted, label)
+		}
+	}
+	sort.Strings(sorted)
+	out := sorted[:0]
+	for i, label := range sorted {
+		if i == 0 || label != sorted[i-1] {
+			out = append(out, label)
+		}
+	}
+	return out
+}
+
+// labelKey compares labels regardless of case and of the dashes some
+// trackers put in place of spaces
+func labelKey(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(label, "-", " ")), " "))
+}
+
+func sameLabels(a, b []string) bool {
+	keys := func(labels []string) string {
+		var out []string
+		for _, label := range labels {
+			out = append(out, labelKey(label))
+		}
+		return strings.Join(Normalize(out), "\n")
+	}
+	return keys(a) == keys(b)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
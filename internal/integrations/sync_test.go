@@ -0,0 +1,149 @@
+package integrations_test
+
+import (
+	"fmt"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTracker keeps issues in memory. It holds every field but the body,
+// which it only writes.
+type fakeTracker struct {
+	issues  map[string]*integrations.Fields
+	next    int
+	writes  int
+	noClose bool
+}
+
+func newFakeTracker() *fakeTracker {
+	return &fakeTracker{issues: make(map[string]*integrations.Fields), next: 1}
+}
+
+func (f *fakeTracker) Name() string { return "fake" }
+
+func (f *fakeTracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	return integrations.Fields{
+		Title:    integrations.Title(todo, 40),
+		Status:   todo.Status,
+		Priority: todo.Priority,
+		Assignee: todo.Assignee,
+		Labels:   integrations.Normalize(append([]string{todo.Category}, tags...)),
+		DueDate:  integrations.DueDate(todo),
+		Body:     integrations.Body(todo),
+	}
+}
+
+func (f *fakeTracker) Fetch(key string) (*integrations.Issue, error) {
+	fields, ok := f.issues[key]
+	if !ok {
+		return nil, integrations.ErrIssueGone
+	}
+	read := *fields
+	read.Body = ""
+	return &integrations.Issue{
+		Key:      key,
+		URL:      "https://tracker/" + key,
+		Fields:   read,
+		Statuses: []string{read.Status},
+		Revision: integrations.Fingerprint(read),
+	}, nil
+}
+
+func (f *fakeTracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	key := fmt.Sprintf("T-%d", f.next)
+	f.next++
+	f.issues[key] = &fields
+	f.writes++
+	return f.Fetch(key)
+}
+
+func (f *fakeTracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	have := f.issues[issue.Key]
+	if fields.Body == issue.Body {
+		// The body was not read, so keep it
+		fields.Body = have.Body
+	}
+	if integrations.Fingerprint(fields) == integrations.Fingerprint(*have) {
+		return nil, nil
+	}
+	*have = fields
+	f.writes++
+	return f.Fetch(issue.Key)
+}
+
+func (f *fakeTracker) Close(key string) error {
+	if f.noClose {
+		return integrations.ErrNotSupported
+	}
+	f.issues[key].Status = "closed"
+	f.writes++
+	return nil
+}
+
+func (f *fakeTracker) Body(key string) string { return f.issues[key].Body }
+
+func (f *fakeTracker) Delete(key string) { delete(f.issues, key) }
+
+func (f *fakeTracker) Writes() int { return f.writes }
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		tracker := newFakeTracker()
+		return tracker, tracker
+	})
+}
+
+func TestSyncTrackersThatCannotClose(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		tracker := newFakeTracker()
+		tracker.noClose = true
+		return tracker, tracker
+	})
+}
+
+func TestSyncMatchesTagsToLabels(t *testing.T) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todo := database.TODO{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Category: "backend", Hash: "a"}
+	require.NoError(t, db.CreateTODO(&todo))
+	tracker := newFakeTracker()
+	s := &integrations.Syncer{Tracker: tracker, DB: db}
+	_, err = s.Sync()
+	require.NoError(t, err)
+
+	// A tag matches the label a tracker made of it
+	tracker.issues["T-1"].Labels = []string{"backend", "needs-review"}
+	_, err = s.Sync()
+	require.NoError(t, err)
+	tracker.issues["T-1"].Labels = []string{"backend", "needs review"}
+	result, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	tags, err := db.GetTagsForTODO(todo.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "needs-review", tags[0].Name)
+}
+
+func TestTitle(t *testing.T) {
+	todo := &database.TODO{Content: "fix  the\n cache"}
+	assert.Equal(t, "fix the cache", integrations.Title(todo, 13))
+	assert.Equal(t, "fix the c...", integrations.Title(todo, 12))
+
+	// "é" takes two bytes and would be split at byte 9
+	todo.Content = "12345678é cache"
+	title := integrations.Title(todo, 12)
+	assert.Equal(t, "12345678...", title)
+	assert.True(t, utf8.ValidString(title))
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, integrations.Normalize([]string{"b", "", "a", "b"}))
+	assert.Equal(t, []string{}, integrations.Normalize(nil))
+}
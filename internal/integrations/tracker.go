@@ -0,0 +1,63 @@
+// Package integrations syncs TODOs with issue trackers. Each tracker is an
+// adapter implementing Tracker; Syncer drives any of them.
+package integrations
+
+import (
+	"errors"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+)
+
+// ErrIssueGone is returned by Fetch for issues that were deleted or moved
+// out of reach
+var ErrIssueGone = errors.New("issue no longer exists")
+
+// ErrNotSupported is returned by trackers for operations they cannot do
+var ErrNotSupported = errors.New("not supported by this tracker")
+
+// Tracker is an issue tracker that TODOs are synced with
+type Tracker interface {
+	// Name identifies the tracker in issue links, such as github:acme/app
+	Name() string
+	// Map renders a TODO as the fields of its issue, leaving out what the
+	// tracker cannot hold
+	Map(todo *database.TODO, tags []string) Fields
+	// Fetch returns an issue, or ErrIssueGone
+	Fetch(key string) (*Issue, error)
+	// Create opens an issue for a TODO. An issue returned with an error
+	// was opened but not fully written.
+	Create(todo *database.TODO, fields Fields) (*Issue, error)
+	// Update writes the fields that differ from an issue and returns the
+	// issue as it now is, or nil when nothing needed writing
+	Update(issue *Issue, todo *database.TODO, fields Fields) (*Issue, error)
+	// Close closes the issue of a deleted TODO
+	Close(key string) error
+}
+
+// Fields are what an issue says about its TODO, in TODO terms
+type Fields struct {
+	Title    string
+	Status   string
+	Priority string // P0-P4, or "" when unknown
+	Assignee string
+	Category string
+	Labels   []string
+	DueDate  string // 2006-01-02
+	// Body is written but not read back, so trackers may leave it empty
+	// when reading
+	Body string
+}
+
+// Issue is an issue read from a tracker
+type Issue struct {
+	Key string
+	URL string
+	Fields
+	// Statuses are the TODO statuses the state of the issue stands for,
+	// the likeliest first. A TODO in one of them keeps its status.
+	Statuses []string
+	// Revision fingerprints what was read, to tell later edits apart
+	Revision string
+	// Raw is the tracker's own representation of the issue
+	Raw interface{}
+}
@@ -0,0 +1,61 @@
+package jira
+
+import "strings"
+
+// Node is a node of the Atlassian Document Format, which API v3 requires
+// for rich text fields such as the description
+type Node struct {
+	Type    string `json:"type"`
+	Version int    `json:"version,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Marks   []Mark `json:"marks,omitempty"`
+	Content []Node `json:"content,omitempty"`
+}
+
+// Mark styles a text node
+type Mark struct {
+	Type string `json:"type"` // strong, em, code, ...
+}
+
+// Doc returns a document made of blocks
+func Doc(blocks ...Node) Node {
+	return Node{Type: "doc", Version: 1, Content: blocks}
+}
+
+// Paragraph returns a paragraph of inline nodes. Text nodes may not be
+// empty, so empty ones are dropped.
+func Paragraph(inline ...Node) Node {
+	p := Node{Type: "paragraph"}
+	for _, n := range inline {
+		if n.Type != "text" || n.Text != "" {
+			p.Content = append(p.Content, n)
+		}
+	}
+	return p
+}
+
+// Lines returns a paragraph of text with hard breaks between its lines
+func Lines(text string) Node {
+	var inline []Node
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			inline = append(inline, Node{Type: "hardBreak"})
+		}
+		inline = append(inline, Text(line))
+	}
+	return Paragraph(inline...)
+}
+
+// Text returns a text node with the given marks
+func Text(text string, marks ...string) Node {
+	n := Node{Type: "text", Text: text}
+	for _, m := range marks {
+		n.Marks = append(n.Marks, Mark{Type: m})
+	}
+	return n
+}
+
+// Rule returns a horizontal rule
+func Rule() Node {
+	return Node{Type: "rule"}
+}
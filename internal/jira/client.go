@@ -0,0 +1,159 @@
+// Package jira keeps TODOs and Jira issues in sync through the REST API v3
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// issueFields are the fields read from issues
+const issueFields = "summary,status,resolution,priority,labels,assignee,duedate"
+
+// Client talks to the REST API of a Jira site
+type Client struct {
+	BaseURL string
+	Email   string
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for the site at baseURL, authenticating with
+// an email address and API token
+func NewClient(baseURL, email, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Email:   email,
+		Token:   token,
+		HTTP:    integrations.NewHTTPClient(),
+	}
+}
+
+// Issue is a Jira issue as returned by the API
+type Issue struct {
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	Fields Fields `json:"fields"`
+}
+
+// Fields are the fields of an issue that a sync reads
+type Fields struct {
+	Summary    string   `json:"summary"`
+	Status     *Status  `json:"status"`
+	Resolution *Named   `json:"resolution"`
+	Priority   *Named   `json:"priority"`
+	Labels     []string `json:"labels"`
+	Assignee   *Account `json:"assignee"`
+	DueDate    string   `json:"duedate"`
+}
+
+// Status is the workflow status of an issue
+type Status struct {
+	Name     string `json:"name"`
+	Category struct {
+		Key string `json:"key"` // new, indeterminate, done
+	} `json:"statusCategory"`
+}
+
+// Named is a field value identified by name, such as a priority
+type Named struct {
+	Name string `json:"name"`
+}
+
+// Account is a Jira user
+type Account struct {
+	AccountID   string `json:"accountId"`
+	DisplayName string `json:"displayName"`
+}
+
+// Transition moves an issue to another status
+type Transition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   Status `json:"to"`
+}
+
+// BrowseURL returns the web page of an issue
+func (c *Client) BrowseURL(key string) string {
+	return c.BaseURL + "/browse/" + key
+}
+
+// CreateIssue creates an issue from a map of field values
+func (c *Client) CreateIssue(fields map[string]interface{}) (*Issue, error) {
+	var issue Issue
+	if err := c.do(http.MethodPost, "/rest/api/3/issue", map[string]interface{}{"fields": fields}, &issue); err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return &issue, nil
+}
+
+// UpdateIssue sets fields of an issue
+func (c *Client) UpdateIssue(key string, fields map[string]interface{}) error {
+	if err := c.do(http.MethodPut, "/rest/api/3/issue/"+url.PathEscape(key), map[string]interface{}{"fields": fields}, nil); err != nil {
+		return fmt.Errorf("failed to update %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetIssue returns an issue, or integrations.ErrIssueGone when it no longer
+// exists
+func (c *Client) GetIssue(key string) (*Issue, error) {
+	var issue Issue
+	err := c.do(http.MethodGet, "/rest/api/3/issue/"+url.PathEscape(key)+"?fields="+issueFields, nil, &issue)
+	if integrations.HasStatus(err, http.StatusNotFound) {
+		err = integrations.ErrIssueGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return &issue, nil
+}
+
+// Transitions returns the transitions available to an issue
+func (c *Client) Transitions(key string) ([]Transition, error) {
+	var resp struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	if err := c.do(http.MethodGet, "/rest/api/3/issue/"+url.PathEscape(key)+"/transitions", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get transitions of %s: %w", key, err)
+	}
+	return resp.Transitions, nil
+}
+
+// Transition moves an issue along a transition
+func (c *Client) Transition(key, transitionID string) error {
+	body := map[string]interface{}{"transition": map[string]string{"id": transitionID}}
+	if err := c.do(http.MethodPost, "/rest/api/3/issue/"+url.PathEscape(key)+"/transitions", body, nil); err != nil {
+		return fmt.Errorf("failed to transition %s: %w", key, err)
+	}
+	return nil
+}
+
+// do sends a JSON request and decodes the JSON response, if any, into out
+func (c *Client) do(method, path string, in, out interface{}) error {
+	req, err := http.NewRequest(method, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.Email, c.Token)
+	req.Header.Set("Accept", "application/json")
+	return integrations.DoJSON(c.HTTP, req, in, out, decodeError)
+}
+
+func decodeError(status int, body io.Reader) error {
+	var apiErr struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	json.NewDecoder(body).Decode(&apiErr)
+	messages := apiErr.ErrorMessages
+	for field, msg := range apiErr.Errors {
+		messages = append(messages, field+": "+msg)
+	}
+	return &integrations.APIError{API: "Jira", Status: status, Message: strings.Join(messages, "; ")}
+}
@@ -0,0 +1,358 @@
+package jira
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// maxSummary is the longest summary Jira accepts
+const maxSummary = 255
+
+// statuses are the TODO statuses in the order they are matched against
+// Jira statuses
+var statuses = []string{"open", "in_progress", "blocked", "resolved", "closed", "wontfix"}
+
+// Tracker adapts the issues of a Jira project for integrations.Syncer,
+// moving issues through the workflow with the transitions configured for
+// each status
+type Tracker struct {
+	Client *Client
+	// Config holds the project key and the status, priority, type and user
+	// mappings
+	Config config.JiraConfig
+}
+
+func init() {
+	integrations.Register(integrations.Adapter{Name: "jira", Title: "Jira", New: newTracker})
+}
+
+func newTracker(cfg *config.Config) (integrations.Tracker, error) {
+	c := cfg.Jira
+	if c.URL == "" {
+		return nil, integrations.NotConfigured("Jira URL", "integration.jira.url", "url")
+	}
+	if c.Email == "" {
+		return nil, integrations.NotConfigured("Jira email", "integration.jira.email", "email")
+	}
+	if c.APIToken == "" {
+		return nil, integrations.NotConfigured("Jira API token", "integration.jira.api-token", "token")
+	}
+	if c.Project == "" {
+		return nil, integrations.NotConfigured("Jira project", "integration.jira.project", "project-key")
+	}
+	return &Tracker{Client: NewClient(c.URL, c.Email, c.APIToken), Config: c}, nil
+}
+
+// Name is the name under which links to this Jira project are stored
+func (t *Tracker) Name() string {
+	return "jira:" + t.Config.Project
+}
+
+// Map renders a TODO as the fields of its issue
+func (t *Tracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	f := integrations.Fields{
+		Title:    integrations.Title(todo, maxSummary),
+		Status:   todo.Status,
+		Assignee: todo.Assignee,
+		Labels:   labels(todo, tags),
+		DueDate:  integrations.DueDate(todo),
+		Body:     integrations.Body(todo),
+	}
+	if t.Config.Priority(todo.Priority) != "" {
+		f.Priority = todo.Priority
+	}
+	return f
+}
+
+// Fetch returns the issue with a key such as OPS-12
+func (t *Tracker) Fetch(key string) (*integrations.Issue, error) {
+	issue, err := t.Client.GetIssue(key)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(issue), nil
+}
+
+// Create creates an issue and moves it to the status of its TODO. When the
+// move fails the new issue is returned with the error.
+func (t *Tracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	created, err := t.Client.CreateIssue(t.createFields(todo, fields))
+	if err != nil {
+		return nil, err
+	}
+	issue, err := t.Client.GetIssue(created.Key)
+	if err != nil {
+		return &integrations.Issue{Key: created.Key, URL: t.Client.BrowseURL(created.Key)}, err
+	}
+	if err := t.moveTo(fields.Status, issue); err != nil {
+		return t.toIssue(issue), err
+	}
+	return t.Fetch(created.Key)
+}
+
+// Update edits the fields that differ and transitions the issue when its
+// status does not stand for the TODO's
+func (t *Tracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	native := issue.Raw.(*Issue)
+	edits := t.changedFields(fields, native)
+	if fields.Body != issue.Body {
+		edits["description"] = description(todo)
+	}
+	if len(edits) == 0 && t.sameStatus(fields.Status, native) {
+		return nil, nil
+	}
+	if len(edits) > 0 {
+		if err := t.Client.UpdateIssue(native.Key, edits); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.moveTo(fields.Status, native); err != nil {
+		return nil, err
+	}
+	return t.Fetch(native.Key)
+}
+
+// Close leaves the issues of deleted TODOs as they are: Jira has no state
+// for abandoned issues that fits every workflow
+func (t *Tracker) Close(key string) error {
+	return integrations.ErrNotSupported
+}
+
+// toIssue reads an issue in TODO terms
+func (t *Tracker) toIssue(issue *Issue) *integrations.Issue {
+	f := issue.Fields
+	out := &integrations.Issue{
+		Key: issue.Key,
+		URL: t.Client.BrowseURL(issue.Key),
+		Fields: integrations.Fields{
+			Title:    f.Summary,
+			Assignee: t.assignee(f.Assignee),
+			Labels:   integrations.Normalize(f.Labels),
+			DueDate:  f.DueDate,
+		},
+		Statuses: t.statusesOf(issue),
+		Revision: remoteHash(issue),
+		Raw:      issue,
+	}
+	if len(out.Statuses) > 0 {
+		out.Status = out.Statuses[0]
+	}
+	if f.Priority != nil {
+		for _, p := range []string{"P0", "P1", "P2", "P3", "P4"} {
+			if strings.EqualFold(t.Config.Priority(p), f.Priority.Name) {
+				out.Priority = p
+				break
+			}
+		}
+	}
+	return out
+}
+
+// createFields returns the fields of a new issue for a TODO
+func (t *Tracker) createFields(todo *database.TODO, f integrations.Fields) map[string]interface{} {
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": t.Config.Project},
+		"issuetype":   map[string]string{"name": t.Config.IssueType(todo.Type)},
+		"summary":     f.Title,
+		"description": description(todo),
+		"labels":      f.Labels,
+	}
+	if priority := t.Config.Priority(f.Priority); priority != "" {
+		fields["priority"] = map[string]string{"name": priority}
+	}
+	if account := t.Config.AccountID(f.Assignee); account != "" {
+		fields["assignee"] = map[string]string{"accountId": account}
+	}
+	if f.DueDate != "" {
+		fields["duedate"] = f.DueDate
+	}
+	return fields
+}
+
+// changedFields returns the fields of an issue that differ from f.
+// Assignees without a Jira account are left alone.
+func (t *Tracker) changedFields(f integrations.Fields, issue *Issue) map[string]interface{} {
+	fields := make(map[string]interface{})
+	have := issue.Fields
+	if f.Title != have.Summary {
+		fields["summary"] = f.Title
+	}
+	if want := t.Config.Priority(f.Priority); want != "" && (have.Priority == nil || !strings.EqualFold(want, have.Priority.Name)) {
+		fields["priority"] = map[string]string{"name": want}
+	}
+	if strings.Join(f.Labels, " ") != strings.Join(integrations.Normalize(have.Labels), " ") {
+		fields["labels"] = f.Labels
+	}
+	if f.DueDate != have.DueDate {
+		fields["duedate"] = nil
+		if f.DueDate != "" {
+			fields["duedate"] = f.DueDate
+		}
+	}
+	account := ""
+	if have.Assignee != nil {
+		account = have.Assignee.AccountID
+	}
+	switch want := t.Config.AccountID(f.Assignee); {
+	case f.Assignee == "" && account != "":
+		fields["assignee"] = nil
+	case want != "" && want != account:
+		fields["assignee"] = map[string]string{"accountId": want}
+	}
+	return fields
+}
+
+// assignee returns the TODO assignee for a Jira account: the user mapped to
+// it, or else its display name
+func (t *Tracker) assignee(account *Account) string {
+	if account == nil {
+		return ""
+	}
+	for user, id := range t.Config.Users {
+		if id == account.AccountID {
+			return user
+		}
+	}
+	return account.DisplayName
+}
+
+// sameStatus reports whether the status of an issue stands for a TODO
+// status. A status configured for another TODO status never does; other
+// statuses match when both are unfinished or both done.
+func (t *Tracker) sameStatus(status string, issue *Issue) bool {
+	jira := issue.Fields.Status
+	if jira == nil {
+		return true
+	}
+	if strings.EqualFold(t.Config.Transition(status), jira.Name) {
+		return true
+	}
+	if status == "wontfix" && t.abandoned(issue) {
+		return true
+	}
+	for _, other := range statuses {
+		if strings.EqualFold(t.Config.Transition(other), jira.Name) {
+			return false
+		}
+	}
+	return database.IsDone(status) == (jira.Category.Key == "done")
+}
+
+// statusesOf returns the TODO statuses the status of an issue stands for,
+// the likeliest first: Won't Do for abandoned issues, then the statuses
+// configured for it, then the one its category suggests
+func (t *Tracker) statusesOf(issue *Issue) []string {
+	jira := issue.Fields.Status
+	if jira == nil {
+		return nil
+	}
+	var candidates []string
+	if t.abandoned(issue) {
+		candidates = append(candidates, "wontfix")
+	}
+	for _, status := range statuses {
+		if strings.EqualFold(t.Config.Transition(status), jira.Name) {
+			candidates = append(candidates, status)
+		}
+	}
+	switch jira.Category.Key {
+	case "done":
+		candidates = append(candidates, "resolved")
+	case "indeterminate":
+		candidates = append(candidates, "in_progress")
+	default:
+		candidates = append(candidates, "open")
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, status := range append(candidates, statuses...) {
+		if !seen[status] && t.sameStatus(status, issue) {
+			out = append(out, status)
+		}
+		seen[status] = true
+	}
+	return out
+}
+
+// abandoned reports whether an issue is done with a resolution saying the
+// work will not be done. Most workflows have a single done status and tell
+// abandoned work apart by the resolution.
+func (t *Tracker) abandoned(issue *Issue) bool {
+	r := issue.Fields.Resolution
+	if r == nil || issue.Fields.Status == nil || issue.Fields.Status.Category.Key != "done" {
+		return false
+	}
+	return strings.EqualFold(r.Name, t.Config.Transition("wontfix")) || strings.EqualFold(r.Name, "Won't Fix")
+}
+
+// moveTo transitions an issue to the Jira status of a TODO status, if it
+// is not there yet
+func (t *Tracker) moveTo(status string, issue *Issue) error {
+	if t.sameStatus(status, issue) {
+		return nil
+	}
+	transitions, err := t.Client.Transitions(issue.Key)
+	if err != nil {
+		return err
+	}
+	target := t.Config.Transition(status)
+	for _, tr := range transitions {
+		matches := strings.EqualFold(tr.Name, target) || strings.EqualFold(tr.To.Name, target)
+		if target == "" {
+			// Without a mapping any transition between unfinished and done will do
+			matches = database.IsDone(status) == (tr.To.Category.Key == "done")
+		}
+		if matches {
+			return t.Client.Transition(issue.Key, tr.ID)
+		}
+	}
+	if target == "" {
+		target = status
+	}
+	return fmt.Errorf("no transition to %q from %q for %s; set jira.transitions.%s", target, issue.Fields.Status.Name, issue.Key, status)
+}
+
+// remoteHash fingerprints the fields of an issue that a sync reads
+func remoteHash(issue *Issue) string {
+	f := issue.Fields
+	f.Labels = integrations.Normalize(f.Labels)
+	return integrations.Fingerprint(f)
+}
+
+// description renders a TODO as the description of its issue
+func description(todo *database.TODO) Node {
+	field := func(name, value string, marks ...string) Node {
+		return Paragraph(Text(name+": ", "strong"), Text(value, marks...))
+	}
+	blocks := []Node{
+		field("Source", fmt.Sprintf("%s:%d", todo.FilePath, todo.LineNumber), "code"),
+		field("Type", todo.Type),
+	}
+	if todo.Author != "" {
+		blocks = append(blocks, field("Author", todo.Author))
+	}
+	if todo.DueDate != nil {
+		blocks = append(blocks, field("Due Date", todo.DueDate.Format("2006-01-02")))
+	}
+	blocks = append(blocks, Rule(), Lines(todo.Content))
+	return Doc(blocks...)
+}
+
+// labels returns the labels of an issue: its TODO's category and tags
+func labels(todo *database.TODO, tags []string) []string {
+	var labels []string
+	for _, name := range append([]string{todo.Category}, tags...) {
+		labels = append(labels, labelName(name))
+	}
+	return integrations.Normalize(labels)
+}
+
+// labelName makes a name usable as a label, which may not contain spaces
+func labelName(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
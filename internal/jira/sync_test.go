@@ -0,0 +1,327 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIssue is an issue stored by fakeJira
+type fakeIssue struct {
+	Fields      Fields
+	IssueType   string
+	Description Node
+}
+
+// fakeJira is a stand-in for the REST API of a Jira site with the default
+// workflow: To Do, In Progress and Done, with a Won't Do transition that
+// resolves issues as Won't Do
+type fakeJira struct {
+	mu          sync.Mutex
+	issues      map[string]*fakeIssue
+	next        int
+	creates     int
+	edits       int
+	transitions int
+}
+
+var workflow = []Transition{
+	{ID: "11", Name: "To Do", To: status("To Do", "new")},
+	{ID: "21", Name: "Start Progress", To: status("In Progress", "indeterminate")},
+	{ID: "31", Name: "Done", To: status("Done", "done")},
+	{ID: "41", Name: "Won't Do", To: status("Done", "done")},
+}
+
+func status(name, category string) Status {
+	s := Status{Name: name}
+	s.Category.Key = category
+	return s
+}
+
+func newFakeJira(t *testing.T) (*fakeJira, *Client) {
+	f := &fakeJira{issues: make(map[string]*fakeIssue), next: 1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL, "me@example.com", "secret")
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if email, token, _ := r.BasicAuth(); email != "me@example.com" || token != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/rest/api/3/issue")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Fields     map[string]json.RawMessage `json:"fields"`
+		Transition struct {
+			ID string `json:"id"`
+		} `json:"transition"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if rest == "" && r.Method == http.MethodPost {
+		var project struct{ Key string }
+		json.Unmarshal(body.Fields["project"], &project)
+		key := fmt.Sprintf("%s-%d", project.Key, f.next)
+		f.next++
+		todo := status("To Do", "new")
+		issue := &fakeIssue{Fields: Fields{Status: &todo, Labels: []string{}}}
+		var issueType struct{ Name string }
+		json.Unmarshal(body.Fields["issuetype"], &issueType)
+		issue.IssueType = issueType.Name
+		if err := f.apply(issue, body.Fields); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"errors":{"fields":%q}}`, err.Error())
+			return
+		}
+		f.issues[key] = issue
+		f.creates++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":"%d","key":%q}`, f.next, key)
+		return
+	}
+
+	key, action, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+	issue, ok := f.issues[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errorMessages":["Issue does not exist or you do not have permission to see it."]}`)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(Issue{Key: key, Fields: issue.Fields})
+	case action == "" && r.Method == http.MethodPut:
+		if err := f.apply(issue, body.Fields); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.edits++
+		w.WriteHeader(http.StatusNoContent)
+	case action == "transitions" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"transitions": workflow})
+	case action == "transitions" && r.Method == http.MethodPost:
+		for _, t := range workflow {
+			if t.ID == body.Transition.ID {
+				to := t.To
+				issue.Fields.Status = &to
+				issue.Fields.Resolution = nil
+				if to.Category.Key == "done" {
+					issue.Fields.Resolution = &Named{Name: t.Name}
+				}
+				f.transitions++
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// apply sets the fields of a create or edit request
+func (f *fakeJira) apply(issue *fakeIssue, fields map[string]json.RawMessage) error {
+	for name, raw := range fields {
+		var err error
+		switch name {
+		case "summary":
+			err = json.Unmarshal(raw, &issue.Fields.Summary)
+		case "description":
+			// API v3 refuses plain strings
+			err = json.Unmarshal(raw, &issue.Description)
+		case "labels":
+			err = json.Unmarshal(raw, &issue.Fields.Labels)
+		case "duedate":
+			issue.Fields.DueDate = ""
+			err = json.Unmarshal(raw, &issue.Fields.DueDate)
+		case "priority":
+			err = json.Unmarshal(raw, &issue.Fields.Priority)
+		case "assignee":
+			var account *Account
+			if err = json.Unmarshal(raw, &account); err == nil && account != nil {
+				account.DisplayName = strings.TrimPrefix(account.AccountID, "acc-")
+			}
+			issue.Fields.Assignee = account
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (f *fakeJira) issue(key string) fakeIssue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.issues[key]
+}
+
+func (f *fakeJira) Body(key string) string {
+	return plainText(f.issue(key).Description)
+}
+
+func (f *fakeJira) Delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.issues, key)
+}
+
+func (f *fakeJira) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates + f.edits + f.transitions
+}
+
+// plainText returns the text of a document, without its formatting
+func plainText(n Node) string {
+	text := n.Text
+	for _, child := range n.Content {
+		text += plainText(child)
+	}
+	return text
+}
+
+func newTestSyncer(t *testing.T) (*integrations.Syncer, *fakeJira, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P1", Category: "backend", Assignee: "alice", Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser\nit drops the last line", Status: "in_progress", Priority: "P0", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	tracker, fake := newTestTracker(t)
+	return &integrations.Syncer{Tracker: tracker, DB: db}, fake, todos
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *fakeJira) {
+	fake, client := newFakeJira(t)
+	cfg := config.JiraConfig{
+		Project:     "OPS",
+		Transitions: map[string]string{"in_progress": "Start Progress"},
+		Users:       map[string]string{"alice": "acc-alice", "bob": "acc-bob"},
+	}
+	return &Tracker{Client: client, Config: cfg}, fake
+}
+
+func runSync(t *testing.T, s *integrations.Syncer) *integrations.Result {
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		return newTestTracker(t)
+	})
+}
+
+func TestSyncIssueFormat(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	issue := fake.issue("OPS-1")
+	assert.Equal(t, "Task", issue.IssueType)
+	assert.Equal(t, "High", issue.Fields.Priority.Name)
+	assert.Equal(t, "acc-alice", issue.Fields.Assignee.AccountID)
+	assert.Equal(t, "To Do", issue.Fields.Status.Name)
+
+	// The description is a document, with the location as code
+	assert.Equal(t, "doc", issue.Description.Type)
+	assert.Equal(t, 1, issue.Description.Version)
+	source := issue.Description.Content[0].Content[1]
+	assert.Equal(t, "/p/a.go:3", source.Text)
+	assert.Equal(t, []Mark{{Type: "code"}}, source.Marks)
+
+	issue = fake.issue("OPS-2")
+	assert.Equal(t, "fix parser it drops the last line", issue.Fields.Summary)
+	assert.Equal(t, "Bug", issue.IssueType)
+	assert.Equal(t, "In Progress", issue.Fields.Status.Name, "moved with the configured transition")
+	last := issue.Description.Content[len(issue.Description.Content)-1]
+	assert.Equal(t, "hardBreak", last.Content[1].Type)
+
+	// Abandoned TODOs are resolved as Won't Do, and match it
+	todos[1].Status = "wontfix"
+	require.NoError(t, s.DB.UpdateTODO(&todos[1]))
+	assert.Equal(t, 1, runSync(t, s).Updated)
+	issue = fake.issue("OPS-2")
+	assert.Equal(t, "Done", issue.Fields.Status.Name)
+	assert.Equal(t, "Won't Do", issue.Fields.Resolution.Name)
+	assert.Equal(t, 2, runSync(t, s).Unchanged)
+}
+
+func TestSyncMissingTransition(t *testing.T) {
+	s, _, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	s.Tracker.(*Tracker).Config.Transitions["blocked"] = "Blocked"
+	todos[0].Status = "blocked"
+	require.NoError(t, s.DB.UpdateTODO(&todos[0]))
+
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.ErrorContains(t, result.Errors[0], `no transition to "Blocked"`)
+	assert.ErrorContains(t, result.Errors[0], "jira.transitions.blocked")
+}
+
+func TestSyncMissingTransitionOnCreate(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	tracker := s.Tracker.(*Tracker)
+	tracker.Config.Transitions["in_progress"] = "Begin"
+
+	result, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	require.Len(t, result.Errors, 1)
+	assert.ErrorContains(t, result.Errors[0], `no transition to "Begin"`)
+
+	// The issue is linked despite the error, so it is not opened again
+	links, err := s.DB.GetIssueLinksForTODO(todos[1].ID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "OPS-2", links[0].Key)
+
+	tracker.Config.Transitions["in_progress"] = "Start Progress"
+	result = runSync(t, s)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 2, fake.creates)
+	assert.Equal(t, "In Progress", fake.issue("OPS-2").Fields.Status.Name)
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newFakeJira(t)
+
+	_, err := client.GetIssue("OPS-9")
+	assert.ErrorIs(t, err, integrations.ErrIssueGone)
+
+	client.Token = "wrong"
+	_, err = client.CreateIssue(map[string]interface{}{"summary": "x"})
+	var apiErr *integrations.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+}
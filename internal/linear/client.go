@@ -0,0 +1,223 @@
+// Package linear keeps TODOs and Linear issues in sync through the GraphQL
+// API
+package linear
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// DefaultURL is the GraphQL endpoint of Linear
+const DefaultURL = "https://api.linear.app/graphql"
+
+// issueFields are the fields read from issues
+const issueFields = `id identifier url title description priority dueDate
+	state { id name type }
+	assignee { id name displayName }
+	labels { nodes { id name } }`
+
+// Client talks to the GraphQL API of Linear
+type Client struct {
+	URL    string
+	APIKey string
+	HTTP   *http.Client
+}
+
+// NewClient returns a client authenticating with a personal API key; an
+// empty url means DefaultURL
+func NewClient(url, apiKey string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{
+		URL:    url,
+		APIKey: apiKey,
+		HTTP:   integrations.NewHTTPClient(),
+	}
+}
+
+// Issue is a Linear issue as returned by the API
+type Issue struct {
+	ID          string  `json:"id"`
+	Identifier  string  `json:"identifier"` // ENG-12
+	URL         string  `json:"url"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Priority    int     `json:"priority"` // 0 none, 1 urgent, 2 high, 3 medium, 4 low
+	DueDate     *string `json:"dueDate"`
+	State       State   `json:"state"`
+	Assignee    *User   `json:"assignee"`
+	Labels      struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+}
+
+// State is a workflow state of a team
+type State struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type"` // triage, backlog, unstarted, started, completed, canceled
+	Position float64 `json:"position,omitempty"`
+}
+
+// User is a Linear user
+type User struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// Label is an issue label
+type Label struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Team holds the workflow states and labels of a team
+type Team struct {
+	ID     string
+	States []State
+	Labels []Label
+}
+
+// CreateIssue creates an issue from IssueCreateInput fields
+func (c *Client) CreateIssue(input map[string]interface{}) (*Issue, error) {
+	var resp struct {
+		IssueCreate struct {
+			Issue Issue `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	query := `mutation IssueCreate($input: IssueCreateInput!) {
+		issueCreate(input: $input) { success issue { ` + issueFields + ` } }
+	}`
+	if err := c.do(query, map[string]interface{}{"input": input}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return &resp.IssueCreate.Issue, nil
+}
+
+// UpdateIssue sets IssueUpdateInput fields of an issue
+func (c *Client) UpdateIssue(id string, input map[string]interface{}) (*Issue, error) {
+	var resp struct {
+		IssueUpdate struct {
+			Issue Issue `json:"issue"`
+		} `json:"issueUpdate"`
+	}
+	query := `mutation IssueUpdate($id: String!, $input: IssueUpdateInput!) {
+		issueUpdate(id: $id, input: $input) { success issue { ` + issueFields + ` } }
+	}`
+	if err := c.do(query, map[string]interface{}{"id": id, "input": input}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", id, err)
+	}
+	return &resp.IssueUpdate.Issue, nil
+}
+
+// GetIssue returns an issue by ID or identifier, or
+// integrations.ErrIssueGone when it no longer exists
+func (c *Client) GetIssue(id string) (*Issue, error) {
+	var resp struct {
+		Issue *Issue `json:"issue"`
+	}
+	query := `query Issue($id: String!) { issue(id: $id) { ` + issueFields + ` } }`
+	err := c.do(query, map[string]interface{}{"id": id}, &resp)
+	var apiErr *integrations.APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "Entity not found") || err == nil && resp.Issue == nil {
+		err = integrations.ErrIssueGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", id, err)
+	}
+	return resp.Issue, nil
+}
+
+// Team returns the workflow states, in board order, and labels of a team
+func (c *Client) Team(id string) (*Team, error) {
+	var resp struct {
+		Team struct {
+			ID     string `json:"id"`
+			States struct {
+				Nodes []State `json:"nodes"`
+			} `json:"states"`
+			Labels struct {
+				Nodes []Label `json:"nodes"`
+			} `json:"labels"`
+		} `json:"team"`
+	}
+	query := `query Team($id: String!) {
+		team(id: $id) { id states { nodes { id name type position } } labels { nodes { id name } } }
+	}`
+	if err := c.do(query, map[string]interface{}{"id": id}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get team %s: %w", id, err)
+	}
+	team := &Team{ID: resp.Team.ID, States: resp.Team.States.Nodes, Labels: resp.Team.Labels.Nodes}
+	sort.SliceStable(team.States, func(i, j int) bool { return team.States[i].Position < team.States[j].Position })
+	return team, nil
+}
+
+// CreateLabel creates a label in a team
+func (c *Client) CreateLabel(teamID, name string) (*Label, error) {
+	var resp struct {
+		IssueLabelCreate struct {
+			IssueLabel Label `json:"issueLabel"`
+		} `json:"issueLabelCreate"`
+	}
+	query := `mutation LabelCreate($input: IssueLabelCreateInput!) {
+		issueLabelCreate(input: $input) { success issueLabel { id name } }
+	}`
+	input := map[string]interface{}{"teamId": teamID, "name": name}
+	if err := c.do(query, map[string]interface{}{"input": input}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create label %s: %w", name, err)
+	}
+	return &resp.IssueLabelCreate.IssueLabel, nil
+}
+
+// do sends a GraphQL request and decodes its data into out. GraphQL errors
+// come back as an APIError even when the HTTP status is 200.
+func (c *Client) do(query string, variables map[string]interface{}, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, c.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", c.APIKey)
+	var body graphQLResponse
+	in := map[string]interface{}{"query": query, "variables": variables}
+	if err := integrations.DoJSON(c.HTTP, req, in, &body, decodeError); err != nil {
+		return err
+	}
+	if len(body.Errors) > 0 {
+		return body.err(http.StatusOK)
+	}
+	if err := json.Unmarshal(body.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// graphQLResponse is the body of every response
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (r *graphQLResponse) err(status int) error {
+	messages := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		messages = append(messages, e.Message)
+	}
+	return &integrations.APIError{API: "Linear", Status: status, Message: strings.Join(messages, "; ")}
+}
+
+func decodeError(status int, body io.Reader) error {
+	var resp graphQLResponse
+	json.NewDecoder(body).Decode(&resp)
+	return resp.err(status)
+}
@@ -0,0 +1,336 @@
+package linear
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// maxTitle is the longest title written to an issue
+const maxTitle = 255
+
+// statuses are the TODO statuses in the order they are matched against
+// workflow states
+var statuses = []string{"open", "in_progress", "blocked", "resolved", "closed", "wontfix"}
+
+// stateTypes are the workflow state types TODO statuses are written as when
+// no state is configured for them
+var stateTypes = map[string]string{
+	"open":        "unstarted",
+	"in_progress": "started",
+	"blocked":     "unstarted",
+	"resolved":    "completed",
+	"closed":      "completed",
+	"wontfix":     "canceled",
+}
+
+// typeStatuses are the TODO statuses each state type stands for
+var typeStatuses = map[string][]string{
+	"triage":    {"open", "blocked"},
+	"backlog":   {"open", "blocked"},
+	"unstarted": {"open", "blocked"},
+	"started":   {"in_progress", "blocked"},
+	"completed": {"resolved", "closed"},
+	"canceled":  {"wontfix"},
+}
+
+// Linear priorities: 0 is none, then 1 urgent to 4 low
+var priorities = map[string]int{"P0": 1, "P1": 2, "P2": 3, "P3": 4, "P4": 4}
+
+// Tracker adapts the issues of a Linear team for integrations.Syncer
+type Tracker struct {
+	Client *Client
+	// Config holds the team and the state and user mappings
+	Config config.LinearConfig
+
+	team *Team
+}
+
+func init() {
+	integrations.Register(integrations.Adapter{Name: "linear", Title: "Linear", New: newTracker})
+}
+
+func newTracker(cfg *config.Config) (integrations.Tracker, error) {
+	c := cfg.Linear
+	if c.APIKey == "" {
+		return nil, integrations.NotConfigured("Linear API key", "integration.linear.api-key", "key")
+	}
+	if c.TeamID == "" {
+		return nil, integrations.NotConfigured("Linear team", "integration.linear.team-id", "team-id")
+	}
+	return &Tracker{Client: NewClient(c.APIURL, c.APIKey), Config: c}, nil
+}
+
+// Name is the name under which links to this team are stored
+func (t *Tracker) Name() string {
+	return "linear:" + t.Config.TeamID
+}
+
+// Map renders a TODO as the fields of its issue. Linear has four
+// priorities, so P3 and P4 both become low.
+func (t *Tracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	return integrations.Fields{
+		Title:    integrations.Title(todo, maxTitle),
+		Status:   todo.Status,
+		Priority: priorityOf(priorities[todo.Priority]),
+		Assignee: todo.Assignee,
+		Labels:   integrations.Normalize(append([]string{todo.Category}, tags...)),
+		DueDate:  integrations.DueDate(todo),
+		Body:     integrations.Body(todo),
+	}
+}
+
+// Fetch returns the issue with an identifier such as ENG-12
+func (t *Tracker) Fetch(key string) (*integrations.Issue, error) {
+	issue, err := t.Client.GetIssue(key)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(issue), nil
+}
+
+// Create creates an issue in the state of its TODO
+func (t *Tracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	state, err := t.stateFor(fields.Status)
+	if err != nil {
+		return nil, err
+	}
+	labelIDs, err := t.labelIDs(fields.Labels)
+	if err != nil {
+		return nil, err
+	}
+	input := map[string]interface{}{
+		"teamId":      t.Config.TeamID,
+		"title":       fields.Title,
+		"description": fields.Body,
+		"priority":    priorities[fields.Priority],
+		"stateId":     state.ID,
+		"labelIds":    labelIDs,
+	}
+	if fields.DueDate != "" {
+		input["dueDate"] = fields.DueDate
+	}
+	if user := t.Config.UserID(fields.Assignee); user != "" {
+		input["assigneeId"] = user
+	}
+	issue, err := t.Client.CreateIssue(input)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(issue), nil
+}
+
+// Update sets the fields that differ. Assignees without a Linear user are
+// left alone.
+func (t *Tracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	native := issue.Raw.(*Issue)
+	input := make(map[string]interface{})
+	if fields.Title != native.Title {
+		input["title"] = fields.Title
+	}
+	if fields.Body != issue.Body {
+		input["description"] = fields.Body
+	}
+	if want := priorities[fields.Priority]; want != native.Priority {
+		input["priority"] = want
+	}
+	if fields.DueDate != issue.DueDate {
+		input["dueDate"] = nil
+		if fields.DueDate != "" {
+			input["dueDate"] = fields.DueDate
+		}
+	}
+	if !contains(t.statusesOf(native.State), fields.Status) {
+		state, err := t.stateFor(fields.Status)
+		if err != nil {
+			return nil, err
+		}
+		input["stateId"] = state.ID
+	}
+	if !strings.EqualFold(strings.Join(fields.Labels, "\n"), strings.Join(issue.Labels, "\n")) {
+		labelIDs, err := t.labelIDs(fields.Labels)
+		if err != nil {
+			return nil, err
+		}
+		input["labelIds"] = labelIDs
+	}
+	assignee := ""
+	if native.Assignee != nil {
+		assignee = native.Assignee.ID
+	}
+	switch user := t.Config.UserID(fields.Assignee); {
+	case fields.Assignee == "" && assignee != "":
+		input["assigneeId"] = nil
+	case user != "" && user != assignee:
+		input["assigneeId"] = user
+	}
+
+	if len(input) == 0 {
+		return nil, nil
+	}
+	updated, err := t.Client.UpdateIssue(native.ID, input)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(updated), nil
+}
+
+// Close cancels an issue
+func (t *Tracker) Close(key string) error {
+	state, err := t.stateFor("wontfix")
+	if err != nil {
+		return err
+	}
+	_, err = t.Client.UpdateIssue(key, map[string]interface{}{"stateId": state.ID})
+	return err
+}
+
+// toIssue reads an issue in TODO terms
+func (t *Tracker) toIssue(issue *Issue) *integrations.Issue {
+	read := integrations.Fields{
+		Title:    issue.Title,
+		Priority: priorityOf(issue.Priority),
+		Assignee: t.assignee(issue.Assignee),
+	}
+	var labels []string
+	for _, label := range issue.Labels.Nodes {
+		labels = append(labels, label.Name)
+	}
+	read.Labels = integrations.Normalize(labels)
+	if issue.DueDate != nil {
+		read.DueDate = *issue.DueDate
+	}
+	statuses := t.statusesOf(issue.State)
+	if len(statuses) > 0 {
+		read.Status = statuses[0]
+	}
+
+	// Edits to the description in Linear are not synced back
+	revision := integrations.Fingerprint(struct {
+		integrations.Fields
+		State string
+	}{read, issue.State.ID})
+	read.Body = issue.Description
+	return &integrations.Issue{
+		Key:      issue.Identifier,
+		URL:      issue.URL,
+		Fields:   read,
+		Statuses: statuses,
+		Revision: revision,
+		Raw:      issue,
+	}
+}
+
+// statusesOf returns the TODO statuses a workflow state stands for: those
+// configured for it, then those its type suggests unless they are
+// configured for another state
+func (t *Tracker) statusesOf(state State) []string {
+	var out []string
+	for _, status := range statuses {
+		if strings.EqualFold(t.Config.State(status), state.Name) {
+			out = append(out, status)
+		}
+	}
+	for _, status := range typeStatuses[state.Type] {
+		if t.Config.State(status) == "" && !contains(out, status) {
+			out = append(out, status)
+		}
+	}
+	return out
+}
+
+// stateFor returns the workflow state a TODO status is written as
+func (t *Tracker) stateFor(status string) (*State, error) {
+	team, err := t.loadTeam()
+	if err != nil {
+		return nil, err
+	}
+	name := t.Config.State(status)
+	for i, state := range team.States {
+		if name != "" && strings.EqualFold(state.Name, name) || name == "" && state.Type == stateTypes[status] {
+			return &team.States[i], nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("team has no %s state for %s; set linear.states.%s", stateTypes[status], status, status)
+	}
+	return nil, fmt.Errorf("team has no state %q; check linear.states.%s", name, status)
+}
+
+// labelIDs returns the IDs of team labels, creating those missing
+func (t *Tracker) labelIDs(names []string) ([]string, error) {
+	team, err := t.loadTeam()
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, name := range names {
+		id := ""
+		for _, label := range team.Labels {
+			if strings.EqualFold(label.Name, name) {
+				id = label.ID
+				break
+			}
+		}
+		if id == "" {
+			label, err := t.Client.CreateLabel(t.Config.TeamID, name)
+			if err != nil {
+				return nil, err
+			}
+			team.Labels = append(team.Labels, *label)
+			id = label.ID
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// loadTeam returns the team, reading it on first use
+func (t *Tracker) loadTeam() (*Team, error) {
+	if t.team == nil {
+		team, err := t.Client.Team(t.Config.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		t.team = team
+	}
+	return t.team, nil
+}
+
+// assignee returns the TODO assignee for a Linear user: the name mapped to
+// it, or else its display name
+func (t *Tracker) assignee(user *User) string {
+	if user == nil {
+		return ""
+	}
+	for name, id := range t.Config.Users {
+		if id == user.ID {
+			return name
+		}
+	}
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Name
+}
+
+// priorityOf returns the TODO priority for a Linear priority, or "" for
+// none
+func priorityOf(priority int) string {
+	if priority < 1 || priority > 4 {
+		return ""
+	}
+	return fmt.Sprintf("P%d", priority-1)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package linear
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLinear is a stand-in for the GraphQL API of a workspace with one
+// team, ENG
+type fakeLinear struct {
+	mu      sync.Mutex
+	issues  map[string]*Issue // by identifier
+	labels  []Label
+	users   map[string]User
+	next    int
+	creates int
+	updates int
+}
+
+var states = []State{
+	{ID: "s-backlog", Name: "Backlog", Type: "backlog", Position: 0},
+	{ID: "s-done", Name: "Done", Type: "completed", Position: 3},
+	{ID: "s-todo", Name: "Todo", Type: "unstarted", Position: 1},
+	{ID: "s-progress", Name: "In Progress", Type: "started", Position: 2},
+	{ID: "s-review", Name: "In Review", Type: "started", Position: 2.5},
+	{ID: "s-canceled", Name: "Canceled", Type: "canceled", Position: 4},
+}
+
+func newFakeLinear(t *testing.T) (*fakeLinear, *Client) {
+	f := &fakeLinear{
+		issues: make(map[string]*Issue),
+		labels: []Label{{ID: "l-backend", Name: "Backend"}},
+		users: map[string]User{
+			"u-alice": {ID: "u-alice", Name: "alice", DisplayName: "alice"},
+			"u-bob":   {ID: "u-bob", Name: "Bob Smith", DisplayName: "bob"},
+		},
+		next: 1,
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL, "lin_api_secret")
+}
+
+func (f *fakeLinear) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "lin_api_secret" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":[{"message":"Authentication required, not authenticated"}]}`)
+		return
+	}
+	var req struct {
+		Query     string `json:"query"`
+		Variables struct {
+			ID    string                     `json:"id"`
+			Input map[string]json.RawMessage `json:"input"`
+		} `json:"variables"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	vars := req.Variables
+
+	var data interface{}
+	switch {
+	case strings.HasPrefix(req.Query, "query Team"):
+		data = map[string]interface{}{"team": map[string]interface{}{
+			"id":     "team-1",
+			"states": map[string]interface{}{"nodes": states},
+			"labels": map[string]interface{}{"nodes": f.labels},
+		}}
+	case strings.HasPrefix(req.Query, "mutation LabelCreate"):
+		var name string
+		json.Unmarshal(vars.Input["name"], &name)
+		label := Label{ID: "l-" + strings.ToLower(name), Name: name}
+		f.labels = append(f.labels, label)
+		data = map[string]interface{}{"issueLabelCreate": map[string]interface{}{"success": true, "issueLabel": label}}
+	case strings.HasPrefix(req.Query, "mutation IssueCreate"):
+		issue := &Issue{ID: fmt.Sprintf("id-%d", f.next), Identifier: fmt.Sprintf("ENG-%d", f.next)}
+		issue.URL = "https://linear.app/acme/issue/" + issue.Identifier
+		f.next++
+		f.apply(issue, vars.Input)
+		f.issues[issue.Identifier] = issue
+		f.creates++
+		data = map[string]interface{}{"issueCreate": map[string]interface{}{"success": true, "issue": issue}}
+	case strings.HasPrefix(req.Query, "mutation IssueUpdate"), strings.HasPrefix(req.Query, "query Issue"):
+		issue := f.find(vars.ID)
+		if issue == nil {
+			fmt.Fprint(w, `{"data":null,"errors":[{"message":"Entity not found: Issue","extensions":{"code":"INPUT_ERROR"}}]}`)
+			return
+		}
+		if strings.HasPrefix(req.Query, "query") {
+			data = map[string]interface{}{"issue": issue}
+			break
+		}
+		f.apply(issue, vars.Input)
+		f.updates++
+		data = map[string]interface{}{"issueUpdate": map[string]interface{}{"success": true, "issue": issue}}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"errors":[{"message":"unknown query %q"}]}`, req.Query)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// find looks an issue up by ID or identifier
+func (f *fakeLinear) find(id string) *Issue {
+	if issue, ok := f.issues[id]; ok {
+		return issue
+	}
+	for _, issue := range f.issues {
+		if issue.ID == id {
+			return issue
+		}
+	}
+	return nil
+}
+
+// apply sets the fields of an IssueCreateInput or IssueUpdateInput
+func (f *fakeLinear) apply(issue *Issue, input map[string]json.RawMessage) {
+	for name, raw := range input {
+		switch name {
+		case "title":
+			json.Unmarshal(raw, &issue.Title)
+		case "description":
+			json.Unmarshal(raw, &issue.Description)
+		case "priority":
+			json.Unmarshal(raw, &issue.Priority)
+		case "dueDate":
+			issue.DueDate = nil
+			json.Unmarshal(raw, &issue.DueDate)
+		case "stateId":
+			var id string
+			json.Unmarshal(raw, &id)
+			for _, s := range states {
+				if s.ID == id {
+					issue.State = State{ID: s.ID, Name: s.Name, Type: s.Type}
+				}
+			}
+		case "assigneeId":
+			var id *string
+			json.Unmarshal(raw, &id)
+			issue.Assignee = nil
+			if id != nil {
+				user := f.users[*id]
+				issue.Assignee = &user
+			}
+		case "labelIds":
+			var ids []string
+			json.Unmarshal(raw, &ids)
+			issue.Labels.Nodes = nil
+			for _, id := range ids {
+				for _, label := range f.labels {
+					if label.ID == id {
+						issue.Labels.Nodes = append(issue.Labels.Nodes, label)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (f *fakeLinear) issue(id string) Issue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.issues[id]
+}
+
+func (f *fakeLinear) Body(key string) string { return f.issue(key).Description }
+
+func (f *fakeLinear) Delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.issues, key)
+}
+
+func (f *fakeLinear) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates + f.updates
+}
+
+func newTestSyncer(t *testing.T) (*integrations.Syncer, *fakeLinear, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P1", Category: "backend", Assignee: "alice", Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser", Status: "blocked", Priority: "P4", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	tracker, fake := newTestTracker(t)
+	return &integrations.Syncer{Tracker: tracker, DB: db}, fake, todos
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *fakeLinear) {
+	fake, client := newFakeLinear(t)
+	cfg := config.LinearConfig{
+		TeamID: "team-1",
+		States: map[string]string{"in_progress": "In Progress", "blocked": "In Review"},
+		Users:  map[string]string{"alice": "u-alice", "bob": "u-bob"},
+	}
+	return &Tracker{Client: client, Config: cfg}, fake
+}
+
+func runSync(t *testing.T, s *integrations.Syncer) *integrations.Result {
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		return newTestTracker(t)
+	})
+}
+
+func TestSyncIssueFormat(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	issue := fake.issue("ENG-1")
+	assert.Equal(t, 2, issue.Priority)
+	assert.Equal(t, "Todo", issue.State.Name, "the first unstarted state")
+	assert.Equal(t, "u-alice", issue.Assignee.ID)
+	require.Len(t, issue.Labels.Nodes, 1)
+	assert.Equal(t, "l-backend", issue.Labels.Nodes[0].ID, "labels match regardless of case")
+
+	issue = fake.issue("ENG-2")
+	assert.Equal(t, 4, issue.Priority)
+	assert.Equal(t, "In Review", issue.State.Name, "the configured state")
+
+	// P4 is written as low like P3, which still counts as unchanged
+	assert.Equal(t, 2, runSync(t, s).Unchanged)
+	assert.Equal(t, 0, fake.updates)
+
+	tag, err := s.DB.GetOrCreateTag("security")
+	require.NoError(t, err)
+	require.NoError(t, s.DB.AddTagToTODO(todos[0].ID, tag.ID))
+	runSync(t, s)
+	assert.Len(t, fake.issue("ENG-1").Labels.Nodes, 2, "missing labels are created")
+
+	// Deleted TODOs cancel their issue
+	require.NoError(t, s.DB.DeleteTODO(todos[1].ID))
+	assert.Equal(t, 1, runSync(t, s).Closed)
+	assert.Equal(t, "Canceled", fake.issue("ENG-2").State.Name)
+	canceled, err := s.Tracker.Fetch("ENG-2")
+	require.NoError(t, err)
+	assert.Equal(t, "wontfix", canceled.Status, "canceled issues are abandoned")
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newFakeLinear(t)
+
+	_, err := client.GetIssue("ENG-9")
+	assert.ErrorIs(t, err, integrations.ErrIssueGone)
+
+	client.APIKey = "wrong"
+	_, err = client.Team("team-1")
+	var apiErr *integrations.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+	assert.Contains(t, apiErr.Error(), "not authenticated")
+}
@@ -0,0 +1,162 @@
+// Package notion keeps TODOs and the pages of a Notion database in sync
+package notion
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// DefaultBaseURL is the Notion API
+const DefaultBaseURL = "https://api.notion.com/v1"
+
+// Version is the API version requests ask for
+const Version = "2022-06-28"
+
+// Client talks to the Notion API with an integration token
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client; an empty baseURL means DefaultBaseURL
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+		HTTP:    integrations.NewHTTPClient(),
+	}
+}
+
+// Database is a Notion database; only the types of its properties are
+// read
+type Database struct {
+	ID         string                    `json:"id"`
+	Properties map[string]PropertySchema `json:"properties"`
+}
+
+// PropertySchema describes a database property
+type PropertySchema struct {
+	ID   string `json:"id"`
+	Type string `json:"type"` // title, rich_text, number, select, status, multi_select, date, ...
+}
+
+// Page is a database page
+type Page struct {
+	ID         string              `json:"id"`
+	URL        string              `json:"url"`
+	Archived   bool                `json:"archived"`
+	Properties map[string]Property `json:"properties"`
+}
+
+// Property is the value of a page property
+type Property struct {
+	Type        string     `json:"type"`
+	Title       []RichText `json:"title,omitempty"`
+	RichText    []RichText `json:"rich_text,omitempty"`
+	Number      *float64   `json:"number,omitempty"`
+	Select      *Option    `json:"select,omitempty"`
+	Status      *Option    `json:"status,omitempty"`
+	MultiSelect []Option   `json:"multi_select,omitempty"`
+	Date        *Date      `json:"date,omitempty"`
+}
+
+// RichText is a run of text
+type RichText struct {
+	PlainText string `json:"plain_text"`
+}
+
+// Option is a choice of a select, status or multi-select property
+type Option struct {
+	Name string `json:"name"`
+}
+
+// Date is the value of a date property
+type Date struct {
+	Start string `json:"start"`
+}
+
+// GetDatabase returns a database and the types of its properties
+func (c *Client) GetDatabase(id string) (*Database, error) {
+	var db Database
+	if err := c.do(http.MethodGet, "/databases/"+id, nil, &db); err != nil {
+		return nil, fmt.Errorf("failed to get database %s: %w", id, err)
+	}
+	return &db, nil
+}
+
+// CreatePage adds a page to a database. Properties and children are given
+// as the API expects them.
+func (c *Client) CreatePage(databaseID string, properties map[string]interface{}, children []interface{}) (*Page, error) {
+	body := map[string]interface{}{
+		"parent":     map[string]string{"database_id": databaseID},
+		"properties": properties,
+	}
+	if len(children) > 0 {
+		body["children"] = children
+	}
+	var page Page
+	if err := c.do(http.MethodPost, "/pages", body, &page); err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	return &page, nil
+}
+
+// UpdatePage sets properties of a page
+func (c *Client) UpdatePage(id string, properties map[string]interface{}) (*Page, error) {
+	var page Page
+	if err := c.do(http.MethodPatch, "/pages/"+id, map[string]interface{}{"properties": properties}, &page); err != nil {
+		return nil, fmt.Errorf("failed to update page %s: %w", id, err)
+	}
+	return &page, nil
+}
+
+// ArchivePage moves a page to the trash
+func (c *Client) ArchivePage(id string) error {
+	var page Page
+	if err := c.do(http.MethodPatch, "/pages/"+id, map[string]interface{}{"archived": true}, &page); err != nil {
+		return fmt.Errorf("failed to archive page %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetPage returns a page, or integrations.ErrIssueGone when it was deleted
+// or archived
+func (c *Client) GetPage(id string) (*Page, error) {
+	var page Page
+	err := c.do(http.MethodGet, "/pages/"+id, nil, &page)
+	if integrations.HasStatus(err, http.StatusNotFound) || err == nil && page.Archived {
+		err = integrations.ErrIssueGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page %s: %w", id, err)
+	}
+	return &page, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *Client) do(method, path string, in, out interface{}) error {
+	req, err := http.NewRequest(method, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Notion-Version", Version)
+	return integrations.DoJSON(c.HTTP, req, in, out, decodeError)
+}
+
+func decodeError(status int, body io.Reader) error {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(body).Decode(&apiErr)
+	return &integrations.APIError{API: "Notion", Status: status, Message: apiErr.Message}
+}
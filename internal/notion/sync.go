@@ -0,0 +1,380 @@
+package notion
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+)
+
+// maxText is the longest text Notion accepts in one rich text object
+const maxText = 2000
+
+// statuses are the TODO statuses in the order they are matched against
+// status options
+var statuses = []string{"open", "in_progress", "blocked", "resolved", "closed", "wontfix"}
+
+var priorityOption = regexp.MustCompile(`^P[0-4]$`)
+
+// writable are the property types each field can be written as
+var writable = map[string][]string{
+	"status":   {"select", "status"},
+	"priority": {"select", "rich_text"},
+	"type":     {"select", "rich_text"},
+	"file":     {"rich_text"},
+	"line":     {"number", "rich_text"},
+	"assignee": {"rich_text", "select"},
+	"category": {"select", "rich_text"},
+	"tags":     {"multi_select"},
+	"due":      {"date"},
+}
+
+// Tracker adapts the pages of a Notion database for integrations.Syncer.
+// Each TODO is a page whose properties hold its fields; properties the
+// database lacks are skipped.
+type Tracker struct {
+	Client *Client
+	// Config holds the database and the property and status names
+	Config config.NotionConfig
+
+	title  string            // name of the title property
+	schema map[string]string // property name to type
+}
+
+func init() {
+	integrations.Register(integrations.Adapter{Name: "notion", Title: "Notion", New: newTracker})
+}
+
+func newTracker(cfg *config.Config) (integrations.Tracker, error) {
+	c := cfg.Notion
+	if c.Token == "" {
+		return nil, integrations.NotConfigured("Notion token", "integration.notion.token", "token")
+	}
+	if c.DatabaseID == "" {
+		return nil, integrations.NotConfigured("Notion database", "integration.notion.database-id", "database-id")
+	}
+	t, err := NewTracker(NewClient(c.APIURL, c.Token), c)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewTracker returns a tracker for the database in cfg, reading which
+// properties it has
+func NewTracker(client *Client, cfg config.NotionConfig) (*Tracker, error) {
+	db, err := client.GetDatabase(cfg.DatabaseID)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tracker{Client: client, Config: cfg, schema: make(map[string]string)}
+	for name, p := range db.Properties {
+		t.schema[name] = p.Type
+		if p.Type == "title" {
+			t.title = name
+		}
+	}
+	if t.title == "" {
+		return nil, fmt.Errorf("database %s has no title property", cfg.DatabaseID)
+	}
+	return t, nil
+}
+
+// Name is the name under which links to this database are stored
+func (t *Tracker) Name() string {
+	return "notion:" + t.Config.DatabaseID
+}
+
+// Map renders a TODO as the properties of its page
+func (t *Tracker) Map(todo *database.TODO, tags []string) integrations.Fields {
+	f := integrations.Fields{Title: integrations.Title(todo, maxText)}
+	if t.has("status") {
+		f.Status = todo.Status
+	}
+	if t.has("priority") {
+		f.Priority = todo.Priority
+	}
+	if t.has("assignee") {
+		f.Assignee = todo.Assignee
+	}
+	if t.has("category") {
+		f.Category = todo.Category
+	}
+	if t.has("tags") {
+		// Options may not contain commas
+		var labels []string
+		for _, tag := range tags {
+			labels = append(labels, strings.ReplaceAll(tag, ",", " "))
+		}
+		f.Labels = integrations.Normalize(labels)
+	}
+	if t.has("due") {
+		f.DueDate = integrations.DueDate(todo)
+	}
+	return f
+}
+
+// Fetch returns the page with the ID key
+func (t *Tracker) Fetch(key string) (*integrations.Issue, error) {
+	page, err := t.Client.GetPage(key)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(page), nil
+}
+
+// Create adds a page with the TODO's content as its body
+func (t *Tracker) Create(todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	properties := map[string]interface{}{t.title: richText("title", fields.Title)}
+	for field, value := range t.values(todo, fields) {
+		if name, typ := t.property(field); name != "" && value != "" {
+			properties[name] = propertyValue(typ, value)
+		}
+	}
+	if name, _ := t.property("tags"); name != "" {
+		properties[name] = multiSelect(fields.Labels)
+	}
+
+	var children []interface{}
+	for _, chunk := range chunks(todo.Content, maxText) {
+		children = append(children, map[string]interface{}{
+			"object":    "block",
+			"type":      "paragraph",
+			"paragraph": map[string]interface{}{"rich_text": textObjects(chunk)},
+		})
+	}
+	page, err := t.Client.CreatePage(t.Config.DatabaseID, properties, children)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(page), nil
+}
+
+// Update sets the properties that differ
+func (t *Tracker) Update(issue *integrations.Issue, todo *database.TODO, fields integrations.Fields) (*integrations.Issue, error) {
+	page := issue.Raw.(*Page)
+	properties := make(map[string]interface{})
+	if fields.Title != text(page.Properties[t.title]) {
+		properties[t.title] = richText("title", fields.Title)
+	}
+	for field, value := range t.values(todo, fields) {
+		name, typ := t.property(field)
+		if name == "" || value == text(page.Properties[name]) {
+			continue
+		}
+		if value == "" && typ == "status" {
+			// Status properties cannot be emptied
+			continue
+		}
+		properties[name] = propertyValue(typ, value)
+	}
+	if name, _ := t.property("tags"); name != "" && strings.Join(fields.Labels, ",") != strings.Join(issue.Labels, ",") {
+		properties[name] = multiSelect(fields.Labels)
+	}
+
+	if len(properties) == 0 {
+		return nil, nil
+	}
+	updated, err := t.Client.UpdatePage(page.ID, properties)
+	if err != nil {
+		return nil, err
+	}
+	return t.toIssue(updated), nil
+}
+
+// Close archives a page
+func (t *Tracker) Close(key string) error {
+	return t.Client.ArchivePage(key)
+}
+
+// values returns the text of each property written for a TODO
+func (t *Tracker) values(todo *database.TODO, f integrations.Fields) map[string]string {
+	values := map[string]string{
+		"priority": f.Priority,
+		"type":     todo.Type,
+		"file":     todo.FilePath,
+		"line":     strconv.Itoa(todo.LineNumber),
+		"assignee": f.Assignee,
+		"category": f.Category,
+		"due":      f.DueDate,
+	}
+	if f.Status != "" {
+		values["status"] = t.Config.Status(f.Status)
+	}
+	return values
+}
+
+// toIssue reads a page in TODO terms
+func (t *Tracker) toIssue(page *Page) *integrations.Issue {
+	read := integrations.Fields{Title: text(page.Properties[t.title])}
+	option := ""
+	if name, _ := t.property("status"); name != "" {
+		option = text(page.Properties[name])
+	}
+	var matched []string
+	for _, status := range statuses {
+		if option != "" && strings.EqualFold(t.Config.Status(status), option) {
+			matched = append(matched, status)
+		}
+	}
+	if len(matched) > 0 {
+		read.Status = matched[0]
+	}
+	if name, _ := t.property("priority"); name != "" {
+		if p := text(page.Properties[name]); priorityOption.MatchString(p) {
+			read.Priority = p
+		}
+	}
+	if name, _ := t.property("assignee"); name != "" {
+		read.Assignee = text(page.Properties[name])
+	}
+	if name, _ := t.property("category"); name != "" {
+		read.Category = text(page.Properties[name])
+	}
+	if name, _ := t.property("tags"); name != "" {
+		var labels []string
+		for _, option := range page.Properties[name].MultiSelect {
+			labels = append(labels, option.Name)
+		}
+		read.Labels = integrations.Normalize(labels)
+	}
+	if name, _ := t.property("due"); name != "" {
+		read.DueDate = text(page.Properties[name])
+	}
+
+	return &integrations.Issue{
+		Key:      page.ID,
+		URL:      page.URL,
+		Fields:   read,
+		Statuses: matched,
+		Revision: integrations.Fingerprint(struct {
+			integrations.Fields
+			Option string
+		}{read, option}),
+		Raw: page,
+	}
+}
+
+// has reports whether the database has a property a field can be written
+// to
+func (t *Tracker) has(field string) bool {
+	name, _ := t.property(field)
+	return name != ""
+}
+
+// property returns the name and type of the property a field is written
+// to, or "" when the database lacks it
+func (t *Tracker) property(field string) (string, string) {
+	name := t.Config.Property(field)
+	typ, ok := t.schema[name]
+	if !ok {
+		return "", ""
+	}
+	for _, w := range writable[field] {
+		if w == typ {
+			return name, typ
+		}
+	}
+	return "", ""
+}
+
+// text returns the value of a property as text
+func text(p Property) string {
+	var parts []RichText
+	switch p.Type {
+	case "title":
+		parts = p.Title
+	case "rich_text":
+		parts = p.RichText
+	case "select":
+		if p.Select != nil {
+			return p.Select.Name
+		}
+	case "status":
+		if p.Status != nil {
+			return p.Status.Name
+		}
+	case "number":
+		if p.Number != nil {
+			return strconv.FormatFloat(*p.Number, 'f', -1, 64)
+		}
+	case "date":
+		if p.Date != nil && len(p.Date.Start) >= 10 {
+			return p.Date.Start[:10]
+		}
+	}
+	var s strings.Builder
+	for _, part := range parts {
+		s.WriteString(part.PlainText)
+	}
+	return s.String()
+}
+
+// propertyValue returns a property value of type typ holding s
+func propertyValue(typ, s string) interface{} {
+	switch typ {
+	case "select", "status":
+		if s == "" {
+			return map[string]interface{}{typ: nil}
+		}
+		return map[string]interface{}{typ: Option{Name: s}}
+	case "number":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return map[string]interface{}{"number": n}
+		}
+		return map[string]interface{}{"number": nil}
+	case "date":
+		if s == "" {
+			return map[string]interface{}{"date": nil}
+		}
+		return map[string]interface{}{"date": Date{Start: s}}
+	}
+	return richText(typ, s)
+}
+
+func richText(typ, s string) map[string]interface{} {
+	return map[string]interface{}{typ: textObjects(s)}
+}
+
+// textObjects returns the rich text objects that spell s
+func textObjects(s string) []interface{} {
+	objects := []interface{}{}
+	for _, chunk := range chunks(s, maxText) {
+		objects = append(objects, map[string]interface{}{
+			"type": "text",
+			"text": map[string]string{"content": chunk},
+		})
+	}
+	return objects
+}
+
+func multiSelect(names []string) map[string]interface{} {
+	options := []Option{}
+	for _, name := range names {
+		options = append(options, Option{Name: name})
+	}
+	return map[string]interface{}{"multi_select": options}
+}
+
+// chunks splits s into pieces of at most max bytes without splitting
+// characters
+func chunks(s string, max int) []string {
+	var out []string
+	for len(s) > max {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		out = append(out, s[:cut])
+		s = s[cut:]
+	}
+	if s != "" {
+		out = append(out, s)
+	}
+	return out
+}
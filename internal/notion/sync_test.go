@@ -0,0 +1,290 @@
+package notion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations"
+	"github.com/duncan-2126/ProjectManagement/internal/integrations/integrationstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schema is the test database: Name, Status, Priority, File, Line, Tags
+// and Due, a people property the sync cannot write, and no Category
+var schema = map[string]string{
+	"Name":     "title",
+	"Status":   "status",
+	"Priority": "select",
+	"File":     "rich_text",
+	"Line":     "number",
+	"Tags":     "multi_select",
+	"Due":      "date",
+	"Assignee": "people",
+}
+
+// fakeNotion is a stand-in for the API of a workspace with one database,
+// db1
+type fakeNotion struct {
+	mu       sync.Mutex
+	pages    map[string]*Page
+	children map[string][]json.RawMessage
+	next     int
+	creates  int
+	updates  int
+}
+
+func newFakeNotion(t *testing.T) (*fakeNotion, *Client) {
+	f := &fakeNotion{pages: make(map[string]*Page), children: make(map[string][]json.RawMessage), next: 1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL, "secret_token")
+}
+
+func (f *fakeNotion) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret_token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"object":"error","code":"unauthorized","message":"API token is invalid."}`)
+		return
+	}
+	if r.Header.Get("Notion-Version") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Children   []json.RawMessage          `json:"children"`
+		Archived   bool                       `json:"archived"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/databases/db1":
+		properties := make(map[string]PropertySchema)
+		for name, typ := range schema {
+			properties[name] = PropertySchema{ID: strings.ToLower(name), Type: typ}
+		}
+		json.NewEncoder(w).Encode(Database{ID: "db1", Properties: properties})
+	case r.Method == http.MethodPost && r.URL.Path == "/pages":
+		id := fmt.Sprintf("page-%d", f.next)
+		f.next++
+		page := &Page{ID: id, URL: "https://www.notion.so/" + id, Properties: make(map[string]Property)}
+		if err := f.apply(page, body.Properties); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"object":"error","code":"validation_error","message":%q}`, err.Error())
+			return
+		}
+		f.pages[id] = page
+		f.children[id] = body.Children
+		f.creates++
+		json.NewEncoder(w).Encode(page)
+	case strings.HasPrefix(r.URL.Path, "/pages/"):
+		page, ok := f.pages[strings.TrimPrefix(r.URL.Path, "/pages/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"object":"error","code":"object_not_found","message":"Could not find page."}`)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			if err := f.apply(page, body.Properties); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"object":"error","code":"validation_error","message":%q}`, err.Error())
+				return
+			}
+			page.Archived = page.Archived || body.Archived
+			f.updates++
+		}
+		json.NewEncoder(w).Encode(page)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// apply sets page properties, refusing those the database lacks or values
+// of the wrong type
+func (f *fakeNotion) apply(page *Page, properties map[string]json.RawMessage) error {
+	for name, raw := range properties {
+		typ, ok := schema[name]
+		if !ok {
+			return fmt.Errorf("%s is not a property that exists", name)
+		}
+		var value map[string]json.RawMessage
+		json.Unmarshal(raw, &value)
+		if _, ok := value[typ]; !ok || len(value) != 1 {
+			return fmt.Errorf("%s is expected to be %s", name, typ)
+		}
+		var p Property
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		p.Type = typ
+		// Text comes back as plain text
+		var texts []struct {
+			Text struct{ Content string }
+		}
+		json.Unmarshal(value[typ], &texts)
+		p.Title, p.RichText = nil, nil
+		for _, t := range texts {
+			switch typ {
+			case "title":
+				p.Title = append(p.Title, RichText{PlainText: t.Text.Content})
+			case "rich_text":
+				p.RichText = append(p.RichText, RichText{PlainText: t.Text.Content})
+			}
+		}
+		page.Properties[name] = p
+	}
+	return nil
+}
+
+// edit changes a page as a person in Notion would
+func (f *fakeNotion) edit(id string, change func(map[string]Property)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f.pages[id].Properties)
+}
+
+func (f *fakeNotion) page(id string) Page {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.pages[id]
+}
+
+func (f *fakeNotion) Body(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var body string
+	for _, block := range f.children[key] {
+		body += string(block)
+	}
+	return body
+}
+
+// Delete archives a page, which is how pages are deleted in Notion
+func (f *fakeNotion) Delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pages[key].Archived = true
+}
+
+func (f *fakeNotion) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates + f.updates
+}
+
+func newTestSyncer(t *testing.T) (*integrations.Syncer, *fakeNotion, []database.TODO) {
+	db, err := database.New(t.TempDir())
+	require.NoError(t, err)
+	todos := []database.TODO{
+		{FilePath: "/p/a.go", LineNumber: 3, Type: "TODO", Content: "add retries", Status: "open", Priority: "P1", Category: "backend", Assignee: "alice", Hash: "a"},
+		{FilePath: "/p/b.go", LineNumber: 8, Type: "FIXME", Content: "fix parser", Status: "in_progress", Priority: "P0", Hash: "b"},
+		{FilePath: "/p/c.go", LineNumber: 1, Type: "TODO", Content: "already done", Status: "resolved", Hash: "c"},
+	}
+	for i := range todos {
+		require.NoError(t, db.CreateTODO(&todos[i]))
+	}
+	tracker, fake := newTestTracker(t)
+	return &integrations.Syncer{Tracker: tracker, DB: db}, fake, todos
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *fakeNotion) {
+	fake, client := newFakeNotion(t)
+	cfg := config.NotionConfig{
+		DatabaseID: "db1",
+		Statuses:   map[string]string{"open": "Not started", "in_progress": "In progress", "resolved": "Done"},
+	}
+	tracker, err := NewTracker(client, cfg)
+	require.NoError(t, err)
+	return tracker, fake
+}
+
+func runSync(t *testing.T, s *integrations.Syncer) *integrations.Result {
+	result, err := s.Sync()
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestConformance(t *testing.T) {
+	integrationstest.Run(t, func(t *testing.T) (integrations.Tracker, integrationstest.Remote) {
+		return newTestTracker(t)
+	})
+}
+
+func TestSyncPageFormat(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	page := fake.page("page-1")
+	assert.Equal(t, "Not started", text(page.Properties["Status"]))
+	assert.Equal(t, "P1", text(page.Properties["Priority"]))
+	assert.Equal(t, "/p/a.go", text(page.Properties["File"]))
+	assert.Equal(t, "3", text(page.Properties["Line"]))
+	assert.NotContains(t, page.Properties, "Assignee", "people cannot be written")
+	assert.Len(t, fake.children["page-1"], 1, "the content is the page body")
+
+	todos[0].Status = "wontfix"
+	todos[0].LineNumber = 12
+	require.NoError(t, s.DB.UpdateTODO(&todos[0]))
+	tag, err := s.DB.GetOrCreateTag("security")
+	require.NoError(t, err)
+	require.NoError(t, s.DB.AddTagToTODO(todos[0].ID, tag.ID))
+	runSync(t, s)
+	page = fake.page("page-1")
+	assert.Equal(t, "Won't Fix", text(page.Properties["Status"]))
+	assert.Equal(t, "12", text(page.Properties["Line"]))
+	assert.Equal(t, []Option{{Name: "security"}}, page.Properties["Tags"].MultiSelect)
+
+	// Deleted TODOs archive their page
+	require.NoError(t, s.DB.DeleteTODO(todos[0].ID))
+	assert.Equal(t, 1, runSync(t, s).Closed)
+	assert.True(t, fake.page("page-1").Archived)
+}
+
+func TestSyncUnknownStatus(t *testing.T) {
+	s, fake, todos := newTestSyncer(t)
+	runSync(t, s)
+
+	fake.edit("page-2", func(p map[string]Property) {
+		p["Status"] = Property{Type: "status", Status: &Option{Name: "Triage"}}
+	})
+	result := runSync(t, s)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, fake.updates, "the unknown option is written back")
+
+	todo, err := s.DB.GetTODOByID(todos[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "in_progress", todo.Status, "unknown options keep the local status")
+	assert.Equal(t, "In progress", text(fake.page("page-2").Properties["Status"]))
+}
+
+func TestNewTrackerErrors(t *testing.T) {
+	_, client := newFakeNotion(t)
+
+	_, err := NewTracker(client, config.NotionConfig{DatabaseID: "missing"})
+	assert.Error(t, err)
+
+	client.Token = "wrong"
+	_, err = NewTracker(client, config.NotionConfig{DatabaseID: "db1"})
+	var apiErr *integrations.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+	assert.Contains(t, apiErr.Error(), "API token is invalid")
+}
+
+func TestChunks(t *testing.T) {
+	assert.Equal(t, []string{"ab", "cd", "e"}, chunks("abcde", 2))
+	assert.Equal(t, []string{"a", "é"}, chunks("aé", 2), "characters are not split")
+	assert.Empty(t, chunks("", 2))
+}
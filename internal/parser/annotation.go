@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Annotations are written between a marker and its description:
+//
+//	TODO(alice)[P1][due:2026-12-01] #auth #perf: text
+//	FIXME(JIRA-123): text
+//
+// The parenthesised owner is an assignee, or an issue key when it looks like
+// one (JIRA-123, #42, owner/repo#42); several may be separated by commas.
+// Bracketed attributes set the priority ([P0] to [P4] or [priority:P1]) and
+// due date ([due:YYYY-MM-DD]); unknown attributes are ignored. Hash words
+// become tags, except numbers such as #42, which are issue keys.
+
+var (
+	issueKeyPattern = regexp.MustCompile(`^(?:[A-Z][A-Z0-9]+-\d+|(?:[\w.-]+/[\w.-]+)?#\d+)$`)
+	priorityPattern = regexp.MustCompile(`(?i)^P[0-4]$`)
+	tagPattern      = regexp.MustCompile(`#([\w./-]+)`)
+)
+
+// dueDateFormat is the layout of [due:...] attributes
+const dueDateFormat = "2006-01-02"
+
+// annotation holds the structured fields written inline after a marker
+type annotation struct {
+	Assignee  string
+	Priority  string
+	DueDate   *time.Time
+	Tags      []string
+	IssueKeys []string
+}
+
+// parseAnnotation interprets the owner, attribute and tag groups matched by
+// the parser's pattern
+func parseAnnotation(owner, attrs, tags string) annotation {
+	var a annotation
+
+	for _, part := range strings.Split(owner, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case issueKeyPattern.MatchString(part):
+			a.IssueKeys = appendUnique(a.IssueKeys, part)
+		case a.Assignee == "":
+			a.Assignee = strings.TrimPrefix(part, "@")
+		}
+	}
+
+	for _, attr := range strings.Split(attrs, "[") {
+		attr = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(attr), "]"))
+		key, value, hasValue := strings.Cut(attr, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case !hasValue && priorityPattern.MatchString(attr):
+			a.Priority = strings.ToUpper(attr)
+		case key == "priority" && priorityPattern.MatchString(value):
+			a.Priority = strings.ToUpper(value)
+		case key == "due":
+			if due, err := time.ParseInLocation(dueDateFormat, value, time.Local); err == nil {
+				a.DueDate = &due
+			}
+		case key == "issue" && value != "":
+			a.IssueKeys = appendUnique(a.IssueKeys, value)
+		}
+	}
+
+	for _, m := range tagPattern.FindAllStringSubmatch(tags, -1) {
+		if issueKeyPattern.MatchString("#" + m[1]) {
+			a.IssueKeys = appendUnique(a.IssueKeys, "#"+m[1])
+		} else {
+			a.Tags = appendUnique(a.Tags, m[1])
+		}
+	}
+
+	return a
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheVersion is bumped whenever parsing output changes for the same input
+const cacheVersion = 4
+
+// Cache remembers parse results per file so that re-scans only read and
+// parse files that changed. Entries are keyed by path and validated by
+// modification time and size; once an entry is older than the TTL the file
+// content is hashed again to catch edits that preserved the mtime.
+type Cache struct {
+	path string
+	ttl  time.Duration
+
+	mu          sync.Mutex
+	fingerprint string
+	files       map[string]*cacheEntry
+	dirty       bool
+}
+
+type cacheEntry struct {
+	ModTime     time.Time
+	Size        int64
+	ContentHash string
+	CheckedAt   time.Time
+	TODOs       []ParsedTODO
+}
+
+// cacheFile is the on-disk representation of a Cache
+type cacheFile struct {
+	Fingerprint string
+	Files       map[string]*cacheEntry
+}
+
+// LoadCache reads the cache stored at path. A missing or unreadable cache
+// file yields an empty cache rather than an error.
+func LoadCache(path string, ttl time.Duration) *Cache {
+	c := &Cache{
+		path:  path,
+		ttl:   ttl,
+		files: make(map[string]*cacheEntry),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+
+	var data cacheFile
+	if err := gob.NewDecoder(f).Decode(&data); err == nil && data.Files != nil {
+		c.fingerprint = data.Fingerprint
+		c.files = data.Files
+	}
+	return c
+}
+
+// Save writes the cache back to disk if anything changed
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(cacheFile{Fingerprint: c.fingerprint, Files: c.files}); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// Len returns the number of cached files
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.files)
+}
+
+// bind discards cached results produced by a differently configured parser
+func (c *Cache) bind(fingerprint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fingerprint != fingerprint {
+		c.fingerprint = fingerprint
+		c.files = make(map[string]*cacheEntry)
+		c.dirty = true
+	}
+}
+
+// lookup returns cached results when the file metadata is unchanged and the
+// entry is still within its TTL
+func (c *Cache) lookup(path string, info os.FileInfo) ([]ParsedTODO, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.files[path]
+	if !ok || !e.ModTime.Equal(info.ModTime()) || e.Size != info.Size() {
+		return nil, false
+	}
+	if time.Since(e.CheckedAt) > c.ttl {
+		return nil, false
+	}
+	return e.TODOs, true
+}
+
+// lookupContent returns cached results when the content is unchanged,
+// refreshing the entry's metadata
+func (c *Cache) lookupContent(path string, info os.FileInfo, sum string) ([]ParsedTODO, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.files[path]
+	if !ok || e.ContentHash != sum {
+		return nil, false
+	}
+	e.ModTime = info.ModTime()
+	e.Size = info.Size()
+	e.CheckedAt = time.Now()
+	c.dirty = true
+	return e.TODOs, true
+}
+
+// store records fresh parse results for a file
+func (c *Cache) store(path string, info os.FileInfo, sum string, todos []ParsedTODO) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.files[path] = &cacheEntry{
+		ModTime:     info.ModTime(),
+		Size:        info.Size(),
+		ContentHash: sum,
+		CheckedAt:   time.Now(),
+		TODOs:       todos,
+	}
+	c.dirty = true
+}
+
+// prune drops entries for files that were not part of the last walk
+func (c *Cache) prune(files []string) {
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.files {
+		if !seen[path] {
+			delete(c.files, path)
+			c.dirty = true
+		}
+	}
+}
+
+// contentHash returns the hex SHA-256 of content
+func contentHash(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// fingerprint identifies the parser settings that affect parse output
+func (p *Parser) fingerprint() string {
+	return fmt.Sprintf("v%d|%s|%t|%s|%s|%v", cacheVersion,
+		strings.Join(p.todoTypes, ","), p.ignoreCase,
+		strings.Join(p.includePatterns, ","),
+		strings.Join(p.excludePatterns, ","),
+		p.customLanguages)
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generateTree writes a tree of source files, a quarter of which contain TODOs
+func generateTree(tb testing.TB, dir string, files int) {
+	tb.Helper()
+	for i := 0; i < files; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("pkg%03d", i%100))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		content := fmt.Sprintf("package pkg\n\nfunc F%d() int {\n", i)
+		for j := 0; j < 200; j++ {
+			content += fmt.Sprintf("\tx := %d // compute value %d\n", j, j)
+		}
+		if i%4 == 0 {
+			content += fmt.Sprintf("\t// TODO: optimise F%d\n", i)
+		}
+		content += "\treturn 0\n}\n"
+		if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("file%d.go", i)), []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestCacheReusesUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	generateTree(t, dir, 20)
+	cachePath := filepath.Join(t.TempDir(), "cache.gob")
+
+	p := New(nil, nil, nil)
+	p.SetCache(LoadCache(cachePath, time.Hour))
+	first, err := p.ParseDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 5 {
+		t.Fatalf("expected 5 TODOs, got %d", len(first))
+	}
+	if err := p.cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit one file and check only its results change after reloading
+	edited := filepath.Join(dir, "pkg000", "file0.go")
+	if err := os.WriteFile(edited, []byte("package pkg\n\n// FIXME: rewritten\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p = New(nil, nil, nil)
+	cache := LoadCache(cachePath, time.Hour)
+	if cache.Len() != 20 {
+		t.Fatalf("expected 20 cached files, got %d", cache.Len())
+	}
+	p.SetCache(cache)
+	second, err := p.ParseDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 5 {
+		t.Fatalf("expected 5 TODOs, got %d", len(second))
+	}
+	found := false
+	for _, todo := range second {
+		if todo.Type == "FIXME" && todo.Content == "rewritten" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected edited file to be re-parsed")
+	}
+}
+
+func TestCacheInvalidatedByParserSettings(t *testing.T) {
+	dir := t.TempDir()
+	generateTree(t, dir, 4)
+	cachePath := filepath.Join(t.TempDir(), "cache.gob")
+
+	p := New(nil, nil, nil)
+	p.SetCache(LoadCache(cachePath, time.Hour))
+	if _, err := p.ParseDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	p.cache.Save()
+
+	p = New(nil, nil, []string{"FIXME"})
+	cache := LoadCache(cachePath, time.Hour)
+	p.SetCache(cache)
+	if cache.Len() != 0 {
+		t.Errorf("expected cache to be reset for different TODO types, got %d entries", cache.Len())
+	}
+}
+
+func benchmarkParseDir(b *testing.B, workers int, cached bool) {
+	dir := b.TempDir()
+	generateTree(b, dir, 2000)
+	cachePath := filepath.Join(b.TempDir(), "cache.gob")
+
+	if cached {
+		// Warm the cache once; every iteration then behaves like a re-scan
+		p := New(nil, nil, nil)
+		p.SetCache(LoadCache(cachePath, time.Hour))
+		if _, err := p.ParseDir(dir); err != nil {
+			b.Fatal(err)
+		}
+		if err := p.cache.Save(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := New(nil, nil, nil)
+		p.SetWorkers(workers)
+		if cached {
+			p.SetCache(LoadCache(cachePath, time.Hour))
+		}
+		if _, err := p.ParseDir(dir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseDirSerial(b *testing.B)       { benchmarkParseDir(b, 1, false) }
+func BenchmarkParseDirParallel(b *testing.B)     { benchmarkParseDir(b, 8, false) }
+func BenchmarkParseDirCachedRescan(b *testing.B) { benchmarkParseDir(b, 8, true) }
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFiles are read from every directory walked. Later files take
+// precedence, so a .todoignore can re-include what .gitignore excludes.
+var IgnoreFiles = []string{".gitignore", ".todoignore"}
+
+// Ignore decides which paths a scan skips using gitignore semantics. It
+// combines the configured exclude patterns, .git/info/exclude and every
+// .gitignore and .todoignore between the repository root and the file.
+type Ignore struct {
+	base   string // repository root, or the scan root outside a repository
+	global []gitignore.Pattern
+
+	mu   sync.Mutex
+	dirs map[string][]gitignore.Pattern
+}
+
+// NewIgnore builds the ignore rules for a scan rooted at root
+func NewIgnore(root string, exclude []string) *Ignore {
+	root, _ = filepath.Abs(root)
+	ig := &Ignore{
+		base: root,
+		dirs: make(map[string][]gitignore.Pattern),
+	}
+
+	// Configured excludes have the lowest precedence
+	for _, pattern := range exclude {
+		ig.global = append(ig.global, gitignore.ParsePattern(pattern, nil))
+	}
+
+	if repoRoot, gitDir := findGitDir(root); repoRoot != "" {
+		ig.base = repoRoot
+		ig.global = append(ig.global, readIgnoreFile(filepath.Join(gitDir, "info", "exclude"), nil)...)
+	}
+
+	return ig
+}
+
+// Match reports whether path is ignored. A path inside an ignored
+// directory is ignored too, as in git.
+func (ig *Ignore) Match(path string, isDir bool) bool {
+	parts, ok := ig.relParts(path)
+	if !ok {
+		return false
+	}
+	for i := 1; i <= len(parts); i++ {
+		if ig.matchParts(parts[:i], i < len(parts) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath checks a single path during a walk, where every parent
+// directory has already been checked
+func (ig *Ignore) matchPath(path string, isDir bool) bool {
+	parts, ok := ig.relParts(path)
+	if !ok {
+		return false
+	}
+	return ig.matchParts(parts, isDir)
+}
+
+// relParts splits path into components relative to the base
+func (ig *Ignore) relParts(path string) ([]string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+	rel, err := filepath.Rel(ig.base, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, false
+	}
+	return strings.Split(filepath.ToSlash(rel), "/"), true
+}
+
+// matchParts evaluates the rules visible to parts, which must already be
+// known not to sit inside an ignored directory
+func (ig *Ignore) matchParts(parts []string, isDir bool) bool {
+	patterns := append([]gitignore.Pattern(nil), ig.global...)
+	for i := 0; i < len(parts); i++ {
+		patterns = append(patterns, ig.dirPatterns(parts[:i])...)
+	}
+	return gitignore.NewMatcher(patterns).Match(parts, isDir)
+}
+
+// dirPatterns returns the patterns defined by ignore files in a directory,
+// given as path components relative to the base
+func (ig *Ignore) dirPatterns(domain []string) []gitignore.Pattern {
+	key := strings.Join(domain, "/")
+
+	ig.mu.Lock()
+	defer ig.mu.Unlock()
+
+	if ps, ok := ig.dirs[key]; ok {
+		return ps
+	}
+
+	dir := filepath.Join(append([]string{ig.base}, domain...)...)
+	var ps []gitignore.Pattern
+	for _, name := range IgnoreFiles {
+		ps = append(ps, readIgnoreFile(filepath.Join(dir, name), domain)...)
+	}
+	ig.dirs[key] = ps
+	return ps
+}
+
+// readIgnoreFile parses a gitignore-style file; a missing file has no patterns
+func readIgnoreFile(path string, domain []string) []gitignore.Pattern {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var ps []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		ps = append(ps, gitignore.ParsePattern(line, domain))
+	}
+	return ps
+}
+
+// findGitDir walks up from dir looking for a repository and returns its
+// worktree root and git directory
+func findGitDir(dir string) (string, string) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit
+			}
+			// Worktrees and submodules point at their git directory
+			if data, err := os.ReadFile(dotGit); err == nil {
+				gitDir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+				if !filepath.IsAbs(gitDir) {
+					gitDir = filepath.Join(dir, gitDir)
+				}
+				return dir, gitDir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// matchGlob reports whether a configured include pattern matches path,
+// using gitignore syntax so that ** spans directories
+func matchGlob(pattern, path string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	return gitignore.ParsePattern(pattern, nil).Match(parts, false) == gitignore.Exclude
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func scannedFiles(t *testing.T, p *Parser, dir string) []string {
+	t.Helper()
+	todos, err := p.ParseDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, todo := range todos {
+		rel, _ := filepath.Rel(dir, todo.FilePath)
+		files = append(files, filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestParseDirHonoursIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	todo := "// TODO: scan me\n"
+	writeFiles(t, dir, map[string]string{
+		".git/info/exclude":         "local/\n",
+		".gitignore":                "*.gen.go\nbuild/\n",
+		".todoignore":               "!keep.gen.go\nthird_party/**/*.go\n",
+		"main.go":                   todo,
+		"api.gen.go":                todo,
+		"keep.gen.go":               todo,
+		"build/out.go":              todo,
+		"local/scratch.go":          todo,
+		"third_party/a/b/lib.go":    todo,
+		"third_party/README.js":     todo,
+		"web/.gitignore":            "legacy.js\n# comment\n\n",
+		"web/app.js":                todo,
+		"web/legacy.js":             todo,
+		"web/nested/legacy.js":      todo,
+		"deep/vendor/pkg/helper.go": todo,
+	})
+
+	files := scannedFiles(t, New(nil, []string{"vendor"}, nil), dir)
+	expected := []string{"keep.gen.go", "main.go", "third_party/README.js", "web/app.js"}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, files)
+			break
+		}
+	}
+}
+
+func TestParseFileHonoursIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":       "generated/\n",
+		"generated/x.go":   "// TODO: ignored\n",
+		"src/handler.go":   "// TODO: found\n",
+		"src/sub/other.py": "# TODO: not included\n",
+	})
+
+	p := New([]string{"src/**/*.go"}, nil, nil)
+	p.SetRoot(dir)
+
+	todos, err := p.ParseFile(filepath.Join(dir, "generated", "x.go"))
+	if err != nil || len(todos) != 0 {
+		t.Errorf("expected ignored file to yield nothing, got %v %v", todos, err)
+	}
+	todos, err = p.ParseFile(filepath.Join(dir, "src", "handler.go"))
+	if err != nil || len(todos) != 1 {
+		t.Errorf("expected one TODO, got %v %v", todos, err)
+	}
+	todos, err = p.ParseFile(filepath.Join(dir, "src", "sub", "other.py"))
+	if err != nil || len(todos) != 0 {
+		t.Errorf("expected file outside include patterns to yield nothing, got %v %v", todos, err)
+	}
+}
@@ -0,0 +1,557 @@
+package parser
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Language represents a programming language with comment patterns
+type Language struct {
+	Name       string
+	Extensions []string
+	Filenames  []string // Exact file names or globs such as Dockerfile.*
+	SingleLine []string // Comment prefixes
+	MultiLine  []string // Multi-line comment start/end pairs
+	Strings    []string // String literal delimiters
+	RawStrings []string // Delimiters of literals without backslash escapes
+	Nested     bool     // Block comments may nest
+	Shebangs   []string // Interpreter names, e.g. python3 or bash
+}
+
+// Comment and string syntax shared by several languages
+var (
+	cStyleLine  = []string{"//"}
+	cStyleBlock = []string{"/*", "*/"}
+	htmlBlock   = []string{"<!--", "-->"}
+	quotes      = []string{`"`, "'"}
+)
+
+// Supported languages
+var Languages = []Language{
+	{
+		Name:       "Go",
+		Extensions: []string{".go"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"`, "'", "`"},
+		RawStrings: []string{"`"},
+	},
+	{
+		Name:       "JavaScript",
+		Extensions: []string{".js", ".jsx", ".mjs", ".cjs"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"`, "'", "`"},
+		Shebangs:   []string{"node"},
+	},
+	{
+		Name:       "TypeScript",
+		Extensions: []string{".ts", ".tsx", ".mts", ".cts"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"`, "'", "`"},
+		Shebangs:   []string{"deno", "ts-node", "tsx"},
+	},
+	{
+		Name:       "Python",
+		Extensions: []string{".py", ".pyw", ".pyi"},
+		SingleLine: []string{"#"},
+		MultiLine:  []string{`"""`, `"""`, "'''", "'''"},
+		Strings:    quotes,
+		Shebangs:   []string{"python", "python2", "python3"},
+	},
+	{
+		Name:       "Java",
+		Extensions: []string{".java"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "Kotlin",
+		Extensions: []string{".kt", ".kts"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"""`, `"`, "'"},
+		Nested:     true,
+	},
+	{
+		Name:       "Scala",
+		Extensions: []string{".scala", ".sc"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"""`, `"`},
+		Nested:     true,
+		Shebangs:   []string{"scala"},
+	},
+	{
+		Name:       "Groovy",
+		Extensions: []string{".groovy", ".gradle"},
+		Filenames:  []string{"Jenkinsfile"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"""`, "'''", `"`, "'"},
+		Shebangs:   []string{"groovy"},
+	},
+	{
+		Name:       "Swift",
+		Extensions: []string{".swift"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"""`, `"`},
+		Nested:     true,
+	},
+	{
+		Name:       "C",
+		Extensions: []string{".c", ".h"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "C++",
+		Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "C#",
+		Extensions: []string{".cs"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "Objective-C",
+		Extensions: []string{".m", ".mm"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "Dart",
+		Extensions: []string{".dart"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"""`, "'''", `"`, "'"},
+		Nested:     true,
+	},
+	{
+		Name:       "Rust",
+		Extensions: []string{".rs"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"`},
+		Nested:     true,
+	},
+	{
+		Name:       "Zig",
+		Extensions: []string{".zig"},
+		SingleLine: cStyleLine,
+		Strings:    []string{`"`},
+	},
+	{
+		Name:       "Solidity",
+		Extensions: []string{".sol"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "Protobuf",
+		Extensions: []string{".proto"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "Ruby",
+		Extensions: []string{".rb", ".rake", ".gemspec"},
+		Filenames:  []string{"Gemfile", "Rakefile", "Vagrantfile", "Podfile", "Guardfile"},
+		SingleLine: []string{"#"},
+		MultiLine:  []string{"=begin", "=end"},
+		Strings:    quotes,
+		Shebangs:   []string{"ruby"},
+	},
+	{
+		Name:       "Perl",
+		Extensions: []string{".pl", ".pm"},
+		SingleLine: []string{"#"},
+		Strings:    quotes,
+		Shebangs:   []string{"perl"},
+	},
+	{
+		Name:       "PHP",
+		Extensions: []string{".php"},
+		SingleLine: []string{"//", "#"},
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+		Shebangs:   []string{"php"},
+	},
+	{
+		Name:       "Shell",
+		Extensions: []string{".sh", ".bash", ".zsh", ".ksh", ".fish"},
+		Filenames:  []string{".bashrc", ".bash_profile", ".zshrc", ".profile"},
+		SingleLine: []string{"#"},
+		Strings:    quotes,
+		Shebangs:   []string{"sh", "bash", "zsh", "ksh", "dash", "fish"},
+	},
+	{
+		Name:       "PowerShell",
+		Extensions: []string{".ps1", ".psm1", ".psd1"},
+		SingleLine: []string{"#"},
+		MultiLine:  []string{"<#", "#>"},
+		Strings:    quotes,
+		Shebangs:   []string{"pwsh"},
+	},
+	{
+		Name:       "Batch",
+		Extensions: []string{".bat", ".cmd"},
+		SingleLine: []string{"::", "REM ", "rem "},
+	},
+	{
+		Name:       "Lua",
+		Extensions: []string{".lua"},
+		SingleLine: []string{"--"},
+		MultiLine:  []string{"--[[", "]]"},
+		Strings:    quotes,
+		Shebangs:   []string{"lua"},
+	},
+	{
+		Name:       "Haskell",
+		Extensions: []string{".hs", ".lhs"},
+		SingleLine: []string{"--"},
+		MultiLine:  []string{"{-", "-}"},
+		Strings:    []string{`"`},
+		Nested:     true,
+		Shebangs:   []string{"runhaskell", "stack"},
+	},
+	{
+		Name:       "Elm",
+		Extensions: []string{".elm"},
+		SingleLine: []string{"--"},
+		MultiLine:  []string{"{-", "-}"},
+		Strings:    []string{`"""`, `"`},
+		Nested:     true,
+	},
+	{
+		Name:       "OCaml",
+		Extensions: []string{".ml", ".mli"},
+		MultiLine:  []string{"(*", "*)"},
+		Strings:    []string{`"`},
+		Nested:     true,
+		Shebangs:   []string{"ocaml"},
+	},
+	{
+		Name:       "F#",
+		Extensions: []string{".fs", ".fsi", ".fsx"},
+		SingleLine: cStyleLine,
+		MultiLine:  []string{"(*", "*)"},
+		Strings:    []string{`"""`, `"`},
+		Nested:     true,
+	},
+	{
+		Name:       "Erlang",
+		Extensions: []string{".erl", ".hrl"},
+		SingleLine: []string{"%"},
+		Strings:    []string{`"`},
+		Shebangs:   []string{"escript"},
+	},
+	{
+		Name:       "Elixir",
+		Extensions: []string{".ex", ".exs"},
+		SingleLine: []string{"#"},
+		Strings:    []string{`"""`, "'''", `"`, "'"},
+		Shebangs:   []string{"elixir"},
+	},
+	{
+		Name:       "Clojure",
+		Extensions: []string{".clj", ".cljs", ".cljc", ".edn"},
+		SingleLine: []string{";"},
+		Strings:    []string{`"`},
+	},
+	{
+		Name:       "Lisp",
+		Extensions: []string{".lisp", ".lsp", ".el", ".scm", ".ss", ".rkt"},
+		SingleLine: []string{";"},
+		MultiLine:  []string{"#|", "|#"},
+		Strings:    []string{`"`},
+		Nested:     true,
+		Shebangs:   []string{"racket", "guile", "sbcl"},
+	},
+	{
+		Name:       "R",
+		Extensions: []string{".r"},
+		SingleLine: []string{"#"},
+		Strings:    quotes,
+		Shebangs:   []string{"Rscript"},
+	},
+	{
+		Name:       "Julia",
+		Extensions: []string{".jl"},
+		SingleLine: []string{"#"},
+		MultiLine:  []string{"#=", "=#"},
+		Strings:    []string{`"""`, `"`},
+		Nested:     true,
+		Shebangs:   []string{"julia"},
+	},
+	{
+		Name:       "TeX",
+		Extensions: []string{".tex", ".sty", ".cls"},
+		SingleLine: []string{"%"},
+	},
+	{
+		Name:       "SQL",
+		Extensions: []string{".sql"},
+		SingleLine: []string{"--"},
+		MultiLine:  cStyleBlock,
+		Strings:    []string{"'"},
+	},
+	{
+		Name:       "GraphQL",
+		Extensions: []string{".graphql", ".gql"},
+		SingleLine: []string{"#"},
+		Strings:    []string{`"""`, `"`},
+	},
+	{
+		Name:       "YAML",
+		Extensions: []string{".yaml", ".yml"},
+		SingleLine: []string{"#"},
+		Strings:    quotes,
+	},
+	{
+		Name:       "TOML",
+		Extensions: []string{".toml"},
+		SingleLine: []string{"#"},
+		Strings:    []string{`"""`, "'''", `"`, "'"},
+	},
+	{
+		Name:       "INI",
+		Extensions: []string{".ini", ".cfg", ".conf"},
+		SingleLine: []string{";", "#"},
+	},
+	{
+		Name:       "Terraform",
+		Extensions: []string{".tf", ".tfvars", ".hcl"},
+		SingleLine: []string{"#", "//"},
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"`},
+	},
+	{
+		Name:       "Nix",
+		Extensions: []string{".nix"},
+		SingleLine: []string{"#"},
+		MultiLine:  cStyleBlock,
+		Strings:    []string{`"`},
+	},
+	{
+		Name:       "Dockerfile",
+		Extensions: []string{".dockerfile"},
+		Filenames:  []string{"Dockerfile", "Containerfile", "Dockerfile.*"},
+		SingleLine: []string{"#"},
+	},
+	{
+		Name:       "Makefile",
+		Extensions: []string{".mk", ".mak"},
+		Filenames:  []string{"Makefile", "makefile", "GNUmakefile"},
+		SingleLine: []string{"#"},
+		Shebangs:   []string{"make"},
+	},
+	{
+		Name:       "CMake",
+		Extensions: []string{".cmake"},
+		Filenames:  []string{"CMakeLists.txt"},
+		SingleLine: []string{"#"},
+		Strings:    []string{`"`},
+	},
+	{
+		Name:       "CSS",
+		Extensions: []string{".css"},
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "SCSS",
+		Extensions: []string{".scss", ".sass", ".less"},
+		SingleLine: cStyleLine,
+		MultiLine:  cStyleBlock,
+		Strings:    quotes,
+	},
+	{
+		Name:       "HTML",
+		Extensions: []string{".html", ".htm", ".xhtml"},
+		MultiLine:  htmlBlock,
+	},
+	{
+		Name:       "XML",
+		Extensions: []string{".xml", ".xsd", ".xsl", ".svg", ".plist"},
+		MultiLine:  htmlBlock,
+	},
+	{
+		Name:       "Markdown",
+		Extensions: []string{".md", ".markdown", ".mdx"},
+		MultiLine:  htmlBlock,
+	},
+	{
+		// Single-file components mix markup, script and style comments
+		Name:       "Vue",
+		Extensions: []string{".vue"},
+		SingleLine: cStyleLine,
+		MultiLine:  []string{"/*", "*/", "<!--", "-->"},
+		Strings:    quotes,
+	},
+	{
+		Name:       "Svelte",
+		Extensions: []string{".svelte"},
+		SingleLine: cStyleLine,
+		MultiLine:  []string{"/*", "*/", "<!--", "-->"},
+		Strings:    quotes,
+	},
+}
+
+// DefaultRegistry resolves files against the built-in languages
+var DefaultRegistry = NewRegistry(Languages)
+
+// Registry resolves files to languages by exact file name, extension and
+// shebang line
+type Registry struct {
+	languages  []Language
+	byName     map[string]*Language
+	byExt      map[string]*Language
+	byShebang  map[string]*Language
+	byFilename map[string]*Language
+	globs      []filenameGlob
+}
+
+type filenameGlob struct {
+	pattern string
+	lang    *Language
+}
+
+// NewRegistry indexes languages for detection. When two languages claim
+// the same extension, file name or interpreter, the later one wins.
+func NewRegistry(languages []Language) *Registry {
+	r := &Registry{
+		languages:  append([]Language(nil), languages...),
+		byName:     make(map[string]*Language),
+		byExt:      make(map[string]*Language),
+		byShebang:  make(map[string]*Language),
+		byFilename: make(map[string]*Language),
+	}
+	for i := range r.languages {
+		lang := &r.languages[i]
+		r.byName[strings.ToLower(lang.Name)] = lang
+		for _, ext := range lang.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			r.byExt[strings.ToLower(ext)] = lang
+		}
+		for _, name := range lang.Filenames {
+			if strings.ContainsAny(name, "*?[") {
+				r.globs = append(r.globs, filenameGlob{pattern: name, lang: lang})
+			} else {
+				r.byFilename[name] = lang
+			}
+		}
+		for _, interp := range lang.Shebangs {
+			r.byShebang[interp] = lang
+		}
+	}
+	return r
+}
+
+// Extend returns a registry with languages added. A language named like an
+// existing one (ignoring case) replaces it.
+func (r *Registry) Extend(languages []Language) *Registry {
+	if len(languages) == 0 {
+		return r
+	}
+	replaced := make(map[string]bool)
+	for _, lang := range languages {
+		replaced[strings.ToLower(lang.Name)] = true
+	}
+	var merged []Language
+	for _, lang := range r.languages {
+		if !replaced[strings.ToLower(lang.Name)] {
+			merged = append(merged, lang)
+		}
+	}
+	return NewRegistry(append(merged, languages...))
+}
+
+// Languages returns the languages in the registry
+func (r *Registry) Languages() []Language {
+	return append([]Language(nil), r.languages...)
+}
+
+// Lookup returns the language with the given name, ignoring case
+func (r *Registry) Lookup(name string) *Language {
+	return r.byName[strings.ToLower(name)]
+}
+
+// ByExtension returns the language for a file extension
+func (r *Registry) ByExtension(ext string) *Language {
+	return r.byExt[strings.ToLower(ext)]
+}
+
+// Detect returns the language of a file from its name, falling back to the
+// interpreter named on a shebang line, or nil if it is not recognised
+func (r *Registry) Detect(path string, content []byte) *Language {
+	name := filepath.Base(path)
+	if lang := r.byFilename[name]; lang != nil {
+		return lang
+	}
+	for _, g := range r.globs {
+		if ok, _ := filepath.Match(g.pattern, name); ok {
+			return g.lang
+		}
+	}
+	if lang := r.ByExtension(filepath.Ext(name)); lang != nil {
+		return lang
+	}
+	if interp := shebangInterpreter(content); interp != "" {
+		if lang := r.byShebang[interp]; lang != nil {
+			return lang
+		}
+		// python3.12 and similar versioned names
+		if lang := r.byShebang[strings.TrimRight(interp, "0123456789.")]; lang != nil {
+			return lang
+		}
+	}
+	return nil
+}
+
+// GetLanguageByExtension returns the built-in language for a file extension
+func GetLanguageByExtension(ext string) *Language {
+	return DefaultRegistry.ByExtension(ext)
+}
+
+// shebangInterpreter returns the interpreter named by a #! line, looking
+// through /usr/bin/env and its options
+func shebangInterpreter(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+	line := content[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp != "env" {
+		return interp
+	}
+	for _, f := range fields[1:] {
+		if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+			continue
+		}
+		return filepath.Base(f)
+	}
+	return ""
+}
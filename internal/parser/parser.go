@@ -3,116 +3,15 @@ package parser
 import (
 	"crypto/sha256"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Language represents a programming language with comment patterns
-type Language struct {
-	Name       string
-	Extensions []string
-	SingleLine []string // Comment prefixes
-	MultiLine  []string // Multi-line comment start/end pairs
-	Shebangs   []string
-}
-
-// Supported languages
-var Languages = []Language{
-	{
-		Name:       "Go",
-		Extensions: []string{".go"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "JavaScript",
-		Extensions: []string{".js", ".jsx", ".mjs"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "TypeScript",
-		Extensions: []string{".ts", ".tsx"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "Python",
-		Extensions: []string{".py"},
-		SingleLine: []string{"#"},
-		MultiLine:  []string{`"""`, `"""`},
-	},
-	{
-		Name:       "Java",
-		Extensions: []string{".java"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "C",
-		Extensions: []string{".c", ".h"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "C++",
-		Extensions: []string{".cpp", ".cc", ".cxx", ".hpp"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "Rust",
-		Extensions: []string{".rs"},
-		SingleLine: []string{"//"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "Ruby",
-		Extensions: []string{".rb"},
-		SingleLine: []string{"#"},
-		MultiLine:  []string{"=begin", "=end"},
-	},
-	{
-		Name:       "Shell",
-		Extensions: []string{".sh", ".bash", ".zsh"},
-		SingleLine: []string{"#"},
-		Shebangs:   []string{"#!/bin/sh", "#!/bin/bash", "#!/usr/bin/env"},
-	},
-	{
-		Name:       "SQL",
-		Extensions: []string{".sql"},
-		SingleLine: []string{"--"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "YAML",
-		Extensions: []string{".yaml", ".yml"},
-		SingleLine: []string{"#"},
-	},
-	{
-		Name:       "PHP",
-		Extensions: []string{".php"},
-		SingleLine: []string{"//", "#"},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "CSS",
-		Extensions: []string{".css", ".scss", ".sass", ".less"},
-		SingleLine: []string{},
-		MultiLine:  []string{"/*", "*/"},
-	},
-	{
-		Name:       "HTML",
-		Extensions: []string{".html", ".htm"},
-		SingleLine: []string{},
-		MultiLine:  []string{"<!--", "-->"},
-	},
-}
-
 // TODO types to look for
 var TODOTypes = []string{
 	"TODO",
@@ -123,8 +22,35 @@ var TODOTypes = []string{
 	"XXX",
 }
 
-// Pattern to match TODO comments
-var todoPattern = regexp.MustCompile(`(?i)^\s*(?://+|#+|\*+|--+)?\s*(TODO|FIXME|HACK|BUG|NOTE|XXX)\s*(\([^)]+\))?\s*[:\-]?\s*(.*)$`)
+// Pattern to match TODO comments with the default markers
+var todoPattern = compilePattern(TODOTypes, true)
+
+// markerName is the form a custom marker must take
+var markerName = regexp.MustCompile(`^\w+$`)
+
+// compilePattern builds the matcher for comments starting with one of the
+// markers. Its groups are the marker, owner, attributes, tags and
+// description (see annotation.go for the annotation syntax).
+func compilePattern(markers []string, ignoreCase bool) *regexp.Regexp {
+	var alts []string
+	for _, m := range markers {
+		if markerName.MatchString(m) {
+			alts = append(alts, regexp.QuoteMeta(m))
+		}
+	}
+	if len(alts) == 0 {
+		return compilePattern(TODOTypes, ignoreCase)
+	}
+	// Prefer the longest marker when one is a prefix of another
+	sort.Slice(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+
+	flags := ""
+	if ignoreCase {
+		flags = "(?i)"
+	}
+	return regexp.MustCompile(flags + `^\s*(?://+|#+|\*+|--+)?\s*(` + strings.Join(alts, "|") +
+		`)\b(?:\s*\(([^)]*)\))?((?:\s*\[[^\]]*\])*)((?:\s+#[\w./-]+)*)\s*[:\-]?\s*(.*)$`)
+}
 
 // ParsedTODO represents a parsed TODO comment
 type ParsedTODO struct {
@@ -137,16 +63,39 @@ type ParsedTODO struct {
 	Email      string
 	CreatedAt  time.Time
 	Hash       string
+	// ContextHash fingerprints the surrounding code so a TODO can be
+	// recognised after it moves or its text is edited
+	ContextHash string
+
+	// Fields set by inline annotations
+	Assignee  string
+	Priority  string
+	DueDate   *time.Time
+	Tags      []string
+	IssueKeys []string
 }
 
+// maxFileSize is the largest file the parser will read
+const maxFileSize = 10 * 1024 * 1024
+
 // Parser handles parsing TODO comments from files
 type Parser struct {
 	includePatterns []string
 	excludePatterns []string
 	todoTypes       []string
+	ignoreCase      bool
+	pattern         *regexp.Regexp
+	workers         int
+	languages       *Registry
+	customLanguages []Language
+	cache           *Cache
+	root            string
+
+	mu      sync.Mutex
+	ignores map[string]*Ignore
 }
 
-// New creates a new parser
+// New creates a new parser. Markers are matched ignoring case.
 func New(include, exclude []string, todoTypes []string) *Parser {
 	if len(todoTypes) == 0 {
 		todoTypes = TODOTypes
@@ -155,208 +104,407 @@ func New(include, exclude []string, todoTypes []string) *Parser {
 		includePatterns: include,
 		excludePatterns: exclude,
 		todoTypes:       todoTypes,
+		ignoreCase:      true,
+		pattern:         compilePattern(todoTypes, true),
+		workers:         1,
+		languages:       DefaultRegistry,
 	}
 }
 
-// GetLanguageByExtension returns the language for a file extension
-func GetLanguageByExtension(ext string) *Language {
-	ext = strings.ToLower(ext)
-	for i := range Languages {
-		for _, e := range Languages[i].Extensions {
-			if strings.ToLower(e) == ext {
-				return &Languages[i]
-			}
-		}
+// SetWorkers sets how many files ParseDir parses concurrently
+func (p *Parser) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
 	}
-	return nil
+	p.workers = n
 }
 
-// ParseFile parses a single file for TODO comments
+// SetRoot sets the directory ParseFile resolves include patterns and
+// ignore files against; it defaults to the working directory
+func (p *Parser) SetRoot(root string) {
+	p.root = root
+}
+
+// SetIgnoreCase sets whether markers match regardless of case. Call it
+// before SetCache.
+func (p *Parser) SetIgnoreCase(ignore bool) {
+	p.ignoreCase = ignore
+	p.pattern = compilePattern(p.todoTypes, ignore)
+}
+
+// SetLanguages adds language definitions on top of the built-in registry.
+// A definition named like a built-in language replaces it. Call it before
+// SetCache so cached results are keyed on the definitions.
+func (p *Parser) SetLanguages(languages []Language) {
+	p.customLanguages = languages
+	p.languages = DefaultRegistry.Extend(languages)
+}
+
+// SetCache enables incremental parsing backed by c
+func (p *Parser) SetCache(c *Cache) {
+	if c != nil {
+		c.bind(p.fingerprint())
+	}
+	p.cache = c
+}
+
+// ParseFile parses a single file for TODO comments. Files excluded by
+// configuration or ignore files, or not matching the include patterns,
+// yield no TODOs.
 func (p *Parser) ParseFile(filePath string) ([]ParsedTODO, error) {
-	// Check if file should be excluded
-	relPath, err := filepath.Rel(".", filePath)
-	if err != nil {
-		relPath = filePath
+	if p.Ignored(filePath) {
+		return nil, nil
 	}
 
-	for _, pattern := range p.excludePatterns {
-		matched, err := filepath.Match(pattern, relPath)
-		if err == nil && matched {
-			return nil, nil
-		}
+	return p.parseFile(filePath)
+}
+
+// Ignored reports whether a file is excluded by configuration or ignore
+// files, or does not match the include patterns
+func (p *Parser) Ignored(filePath string) bool {
+	root := p.root
+	if root == "" {
+		root, _ = os.Getwd()
 	}
+	return p.ignoreFor(root).Match(filePath, false) || !p.included(root, filePath)
+}
 
-	// Check if file matches include patterns
-	if len(p.includePatterns) > 0 {
-		matched := false
-		for _, pattern := range p.includePatterns {
-			m, err := filepath.Match(pattern, relPath)
-			if err == nil && m {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			return nil, nil
-		}
+// IgnoredDir reports whether a directory is skipped when scanning: hidden
+// directories and those excluded by configuration or ignore files
+func (p *Parser) IgnoredDir(dirPath string) bool {
+	if strings.HasPrefix(filepath.Base(dirPath), ".") {
+		return true
 	}
+	root := p.root
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	return p.ignoreFor(root).Match(dirPath, true)
+}
+
+// ParseContent extracts TODO comments from content that is not read from
+// disk, such as a file at an older commit. path selects the language and
+// is reported as the FilePath of each TODO.
+func (p *Parser) ParseContent(path string, content []byte) []ParsedTODO {
+	return p.parseContent(path, path, content)
+}
 
-	// Open file
-	file, err := os.Open(filePath)
+// parseFile parses a file that has already passed the ignore checks
+func (p *Parser) parseFile(filePath string) ([]ParsedTODO, error) {
+	relPath, err := filepath.Rel(".", filePath)
 	if err != nil {
-		return nil, err
+		relPath = filePath
 	}
-	defer file.Close()
 
 	// Check file size
-	info, err := file.Stat()
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return nil, err
 	}
-	if info.Size() > 10*1024*1024 { // Skip files > 10MB
+	if info.Size() > maxFileSize {
 		return nil, nil
 	}
 
-	// Get language
-	ext := filepath.Ext(filePath)
-	lang := GetLanguageByExtension(ext)
+	// Unchanged files are served from the cache without being read
+	if p.cache != nil {
+		if todos, ok := p.cache.lookup(filePath, info); ok {
+			return todos, nil
+		}
+	}
 
 	// Read file content
-	content, err := io.ReadAll(file)
+	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
+	if p.cache != nil {
+		sum := contentHash(content)
+		if todos, ok := p.cache.lookupContent(filePath, info, sum); ok {
+			return todos, nil
+		}
+		todos := p.parseContent(filePath, relPath, content)
+		p.cache.store(filePath, info, sum, todos)
+		return todos, nil
+	}
+
+	return p.parseContent(filePath, relPath, content), nil
+}
+
+// parseContent extracts TODO comments from the content of a file. A TODO's
+// description continues over the following lines of the same comment until
+// a blank line, another marker or the end of the comment.
+func (p *Parser) parseContent(filePath, relPath string, content []byte) []ParsedTODO {
 	lines := strings.Split(string(content), "\n")
-	var todos []ParsedTODO
 
-	// Track multi-line comments
-	inMLComment := false
+	// Skip binary files
+	if strings.Contains(lines[0], "\x00") {
+		return nil
+	}
 
-	for lineNum, line := range lines {
-		// Skip binary files
-		if lineNum == 0 && strings.Contains(line, "\x00") {
-			return nil, nil
-		}
+	var comments []comment
+	if lang := p.languages.Detect(filePath, content); lang != nil {
+		comments = tokenize(lines, lang)
+	} else {
+		comments = hashComments(lines)
+	}
 
-		trimmed := strings.TrimSpace(line)
-
-		// Handle multi-line comments
-		if lang != nil && len(lang.MultiLine) >= 2 {
-			for i := 0; i < len(lang.MultiLine); i += 2 {
-				startToken := lang.MultiLine[i]
-				endToken := lang.MultiLine[i+1]
-
-					if !inMLComment && strings.Contains(trimmed, startToken) {
-						inMLComment = true
-						// Check if comment ends on same line
-						if strings.Contains(trimmed, endToken) {
-							inMLComment = false
-						}
-					trimmed = extractMLComment(trimmed, startToken, endToken)
-				} else if inMLComment && strings.Contains(trimmed, endToken) {
-					inMLComment = false
-					trimmed = ""
-					break
-				}
+	var todos []ParsedTODO
+	for _, c := range comments {
+		for i := 0; i < len(c.lines); i++ {
+			cl := c.lines[i]
+			loc := p.pattern.FindStringSubmatchIndex(cl.text)
+			if loc == nil {
+				continue
 			}
-		}
 
-		if inMLComment {
-			continue
-		}
+			todoType := strings.ToUpper(cl.text[loc[2]:loc[3]])
+			a := parseAnnotation(submatch(cl.text, loc, 2), submatch(cl.text, loc, 3), submatch(cl.text, loc, 4))
+			var parts []string
+			if text := strings.TrimSpace(submatch(cl.text, loc, 5)); text != "" {
+				parts = append(parts, text)
+			}
 
-		// Check for single-line comments
-		isComment := false
-		if lang != nil {
-			for _, comment := range lang.SingleLine {
-				if strings.HasPrefix(trimmed, comment) {
-					isComment = true
-					trimmed = strings.TrimPrefix(trimmed, comment)
-					trimmed = strings.TrimSpace(trimmed)
+			// Gather continuation lines
+			last := cl.line
+			for i+1 < len(c.lines) {
+				next := c.lines[i+1]
+				text := strings.TrimSpace(decorationPattern.ReplaceAllString(next.text, ""))
+				if text == "" || p.pattern.MatchString(next.text) {
 					break
 				}
+				parts = append(parts, text)
+				last = next.line
+				i++
 			}
+			content := strings.Join(parts, " ")
+
+			// Generate hash for deduplication
+			hash := fmt.Sprintf("%x", sha256.Sum256([]byte(filePath+fmt.Sprint(cl.line+1)+todoType+content)))
+
+			todos = append(todos, ParsedTODO{
+				FilePath:    relPath,
+				LineNumber:  cl.line + 1,
+				Column:      cl.col + loc[2] + 1,
+				Type:        todoType,
+				Content:     content,
+				CreatedAt:   time.Now(),
+				Hash:        hash,
+				ContextHash: contextHash(lines, cl.line, last),
+				Assignee:    a.Assignee,
+				Priority:    a.Priority,
+				DueDate:     a.DueDate,
+				Tags:        a.Tags,
+				IssueKeys:   a.IssueKeys,
+			})
 		}
+	}
 
-		// Check for TODO pattern
-		if isComment || (lang == nil && strings.HasPrefix(trimmed, "#")) {
-			matches := todoPattern.FindStringSubmatch(trimmed)
-			if len(matches) >= 2 {
-				todoType := strings.ToUpper(matches[1])
-				var content string
-				if len(matches) >= 4 {
-					content = matches[3]
-				}
+	sort.SliceStable(todos, func(i, j int) bool {
+		if todos[i].LineNumber != todos[j].LineNumber {
+			return todos[i].LineNumber < todos[j].LineNumber
+		}
+		return todos[i].Column < todos[j].Column
+	})
 
-				// Generate hash for deduplication
-				hash := fmt.Sprintf("%x", sha256.Sum256([]byte(filePath+fmt.Sprint(lineNum+1)+todoType+content)))
-
-				todo := ParsedTODO{
-					FilePath:   relPath,
-					LineNumber: lineNum + 1,
-					Column:     strings.Index(line, matches[0]) + 1,
-					Type:       todoType,
-					Content:    content,
-					CreatedAt:  time.Now(),
-					Hash:       hash,
-				}
-				todos = append(todos, todo)
-			}
+	return todos
+}
+
+// submatch returns the text of group n from a FindStringSubmatchIndex result
+func submatch(s string, loc []int, n int) string {
+	if loc[2*n] < 0 {
+		return ""
+	}
+	return s[loc[2*n]:loc[2*n+1]]
+}
+
+// contextHash fingerprints the nearest non-blank lines above and below the
+// TODO spanning lines first to last, ignoring indentation
+func contextHash(lines []string, first, last int) string {
+	var before, after string
+	for i := first - 1; i >= 0; i-- {
+		if t := strings.TrimSpace(lines[i]); t != "" {
+			before = t
+			break
+		}
+	}
+	for i := last + 1; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); t != "" {
+			after = t
+			break
 		}
 	}
+	if before == "" && after == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(before+"\n"+after)))[:16]
+}
+
+// FileErrors holds the files, or directories, that could not be read, by
+// path
+type FileErrors map[string]error
 
-	return todos, nil
+// Paths returns the files that failed, sorted
+func (e FileErrors) Paths() []string {
+	paths := make([]string, 0, len(e))
+	for path := range e {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
 }
 
-// extractMLComment extracts content from multi-line comment
-func extractMLComment(line, start, end string) string {
-	result := strings.TrimSpace(line)
-	result = strings.TrimPrefix(result, start)
-	result = strings.TrimSuffix(result, end)
-	return strings.TrimSpace(result)
+func (e FileErrors) Error() string {
+	paths := e.Paths()
+	if len(paths) == 1 {
+		return fmt.Sprintf("failed to parse %s: %v", paths[0], e[paths[0]])
+	}
+	return fmt.Sprintf("failed to parse %d files, first %s: %v", len(paths), paths[0], e[paths[0]])
 }
 
-// ParseDir recursively parses a directory for TODO comments
+// ParseDir recursively parses a directory for TODO comments. Files are
+// parsed concurrently by the configured number of workers. Files that
+// cannot be read are left out and reported together as FileErrors, along
+// with the TODOs of every other file.
 func (p *Parser) ParseDir(dirPath string) ([]ParsedTODO, error) {
+	root, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	failed := make(FileErrors)
+	files, err := p.collectFiles(dirPath, dirPath, p.ignoreFor(root), failed)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := p.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	type result struct {
+		path  string
+		todos []ParsedTODO
+		err   error
+	}
+	paths := make(chan string)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				todos, err := p.parseFile(path)
+				if err == nil && len(todos) == 0 {
+					continue
+				}
+				results <- result{path, todos, err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, path := range files {
+			paths <- path
+		}
+		close(paths)
+		wg.Wait()
+		close(results)
+	}()
+
 	var allTodos []ParsedTODO
+	for r := range results {
+		if r.err != nil {
+			failed[r.path] = r.err
+			continue
+		}
+		allTodos = append(allTodos, r.todos...)
+	}
+
+	// Keep output deterministic regardless of worker scheduling
+	sort.Slice(allTodos, func(i, j int) bool {
+		if allTodos[i].FilePath != allTodos[j].FilePath {
+			return allTodos[i].FilePath < allTodos[j].FilePath
+		}
+		return allTodos[i].LineNumber < allTodos[j].LineNumber
+	})
+
+	if p.cache != nil {
+		p.cache.prune(files)
+	}
 
+	if len(failed) > 0 {
+		return allTodos, failed
+	}
+	return allTodos, nil
+}
+
+// collectFiles walks a directory and returns the files to parse, pruning
+// hidden and ignored directories. Subdirectories that cannot be read are
+// added to failed.
+func (p *Parser) collectFiles(root, dirPath string, ig *Ignore, failed FileErrors) ([]string, error) {
 	entries, err := os.ReadDir(dirPath)
 	if err != nil {
 		return nil, err
 	}
 
+	var files []string
 	for _, entry := range entries {
 		path := filepath.Join(dirPath, entry.Name())
 
 		if entry.IsDir() {
-			// Skip excluded directories
-			skip := false
-			for _, pattern := range p.excludePatterns {
-				if entry.Name() == pattern || strings.HasPrefix(entry.Name(), ".") {
-					skip = true
-					break
-				}
-			}
-			if skip {
+			// Skip hidden and ignored directories
+			if strings.HasPrefix(entry.Name(), ".") || ig.matchPath(path, true) {
 				continue
 			}
 
-			subTodos, err := p.ParseDir(path)
+			subFiles, err := p.collectFiles(root, path, ig, failed)
 			if err != nil {
+				failed[path] = err
 				continue
 			}
-			allTodos = append(allTodos, subTodos...)
-		} else {
-			todos, err := p.ParseFile(path)
-			if err != nil {
+			files = append(files, subFiles...)
+		} else if entry.Type().IsRegular() {
+			if ig.matchPath(path, false) || !p.included(root, path) {
 				continue
 			}
-			if todos != nil {
-				allTodos = append(allTodos, todos...)
-			}
+			files = append(files, path)
 		}
 	}
 
-	return allTodos, nil
+	return files, nil
+}
+
+// included reports whether path matches the include patterns, if any
+func (p *Parser) included(root, path string) bool {
+	if len(p.includePatterns) == 0 {
+		return true
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	for _, pattern := range p.includePatterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreFor returns the ignore rules for scans rooted at root
+func (p *Parser) ignoreFor(root string) *Ignore {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ignores == nil {
+		p.ignores = make(map[string]*Ignore)
+	}
+	ig, ok := p.ignores[root]
+	if !ok {
+		ig = NewIgnore(root, p.excludePatterns)
+		p.ignores[root] = ig
+	}
+	return ig
 }
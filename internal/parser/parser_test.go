@@ -95,3 +95,210 @@ func TestParserExcludePatterns(t *testing.T) {
 		t.Errorf("expected 3 exclude patterns, got %d", len(parser.excludePatterns))
 	}
 }
+
+func TestParseContentComments(t *testing.T) {
+	type todo struct {
+		line    int
+		typ     string
+		content string
+	}
+	tests := []struct {
+		name     string
+		file     string
+		src      string
+		expected []todo
+	}{
+		{
+			name:     "block comment body",
+			file:     "main.go",
+			src:      "package main\n\n/*\n * Helpers.\n *\n * TODO: handle errors\n */\nfunc f() {}\n",
+			expected: []todo{{6, "TODO", "handle errors"}},
+		},
+		{
+			name:     "continuation lines",
+			file:     "main.go",
+			src:      "// TODO: split this function\n// into smaller pieces\n//\n// unrelated\nfunc f() {}\n",
+			expected: []todo{{1, "TODO", "split this function into smaller pieces"}},
+		},
+		{
+			name:     "continuation stops at next marker",
+			file:     "main.go",
+			src:      "// TODO: first\n// FIXME: second\n// still second\n",
+			expected: []todo{{1, "TODO", "first"}, {2, "FIXME", "second still second"}},
+		},
+		{
+			name:     "trailing comment does not absorb the next one",
+			file:     "main.go",
+			src:      "x := 1 // TODO: trailing\n// unrelated\n",
+			expected: []todo{{1, "TODO", "trailing"}},
+		},
+		{
+			name:     "marker inside string",
+			file:     "main.go",
+			src:      "s := \"// TODO: not a comment\"\nr := `/* FIXME: raw */`\nc := '\"' // BUG: real\n",
+			expected: []todo{{3, "BUG", "real"}},
+		},
+		{
+			name:     "raw string ending in a backslash",
+			file:     "main.go",
+			src:      "var p = `C:\\`\n// TODO: after the path\nvar q = `a\\\nb` // FIXME: after multi-line\n",
+			expected: []todo{{2, "TODO", "after the path"}, {4, "FIXME", "after multi-line"}},
+		},
+		{
+			name:     "same-line block comments",
+			file:     "main.c",
+			src:      "int x; /* TODO: a */ int y; /* FIXME: b */\n",
+			expected: []todo{{1, "TODO", "a"}, {1, "FIXME", "b"}},
+		},
+		{
+			name:     "nested block comments",
+			file:     "lib.rs",
+			src:      "/* outer /* inner */\n   TODO: still in comment */\nfn f() {} // HACK: after\n",
+			expected: []todo{{2, "TODO", "still in comment"}, {3, "HACK", "after"}},
+		},
+		{
+			name:     "python docstring",
+			file:     "app.py",
+			src:      "def f():\n    \"\"\"Do things.\n\n    TODO: cache the result\n    per user\n    \"\"\"\n    s = \"# TODO: no\"\n",
+			expected: []todo{{4, "TODO", "cache the result per user"}},
+		},
+		{
+			name:     "html comment",
+			file:     "index.html",
+			src:      "<div>\n<!--\n  FIXME: broken layout\n-->\n</div>\n",
+			expected: []todo{{3, "FIXME", "broken layout"}},
+		},
+		{
+			name:     "word boundary",
+			file:     "main.go",
+			src:      "// Notes about todos\n",
+			expected: nil,
+		},
+	}
+
+	p := New(nil, nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.parseContent(tt.file, tt.file, []byte(tt.src))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %d TODOs, got %d: %+v", len(tt.expected), len(got), got)
+			}
+			for i, want := range tt.expected {
+				if got[i].LineNumber != want.line || got[i].Type != want.typ || got[i].Content != want.content {
+					t.Errorf("TODO %d: expected %d %s %q, got %d %s %q",
+						i, want.line, want.typ, want.content, got[i].LineNumber, got[i].Type, got[i].Content)
+				}
+			}
+		})
+	}
+}
+
+func TestParseContentColumn(t *testing.T) {
+	p := New(nil, nil, nil)
+	got := p.parseContent("main.go", "main.go", []byte("x := 1 // TODO: here\n"))
+	if len(got) != 1 || got[0].Column != 11 {
+		t.Fatalf("expected column 11, got %+v", got)
+	}
+}
+
+func TestParseContentAnnotations(t *testing.T) {
+	p := New(nil, nil, nil)
+	src := "// TODO(alice)[P1][due:2026-12-01] #auth #perf: tighten checks\n" +
+		"// FIXME(JIRA-123): flaky\n" +
+		"// HACK(@bob, org/repo#7) [priority:p0] [wip]: temporary\n"
+	got := p.parseContent("main.go", "main.go", []byte(src))
+	if len(got) != 3 {
+		t.Fatalf("expected 3 TODOs, got %d: %+v", len(got), got)
+	}
+
+	first := got[0]
+	if first.Assignee != "alice" || first.Priority != "P1" || first.Content != "tighten checks" {
+		t.Errorf("unexpected annotation: %+v", first)
+	}
+	if first.DueDate == nil || first.DueDate.Format("2006-01-02") != "2026-12-01" {
+		t.Errorf("expected due date 2026-12-01, got %v", first.DueDate)
+	}
+	if len(first.Tags) != 2 || first.Tags[0] != "auth" || first.Tags[1] != "perf" {
+		t.Errorf("expected tags [auth perf], got %v", first.Tags)
+	}
+
+	if got[1].Assignee != "" || len(got[1].IssueKeys) != 1 || got[1].IssueKeys[0] != "JIRA-123" {
+		t.Errorf("expected issue key JIRA-123, got %+v", got[1])
+	}
+
+	third := got[2]
+	if third.Assignee != "bob" || third.Priority != "P0" || third.Content != "temporary" {
+		t.Errorf("unexpected annotation: %+v", third)
+	}
+	if len(third.IssueKeys) != 1 || third.IssueKeys[0] != "org/repo#7" {
+		t.Errorf("expected issue key org/repo#7, got %v", third.IssueKeys)
+	}
+}
+
+func TestRegistryDetect(t *testing.T) {
+	tests := []struct {
+		path     string
+		content  string
+		expected string
+	}{
+		{"src/App.kt", "", "Kotlin"},
+		{"Dockerfile", "", "Dockerfile"},
+		{"deploy/Dockerfile.prod", "", "Dockerfile"},
+		{"Makefile", "", "Makefile"},
+		{"main.tf", "", "Terraform"},
+		{"README.md", "", "Markdown"},
+		{"bin/deploy", "#!/bin/bash\necho hi\n", "Shell"},
+		{"bin/tool", "#!/usr/bin/env -S python3.12 -u\n", "Python"},
+		{"bin/run", "#!/usr/bin/env node\n", "JavaScript"},
+		{"notes", "just text\n", ""},
+	}
+
+	for _, tt := range tests {
+		lang := DefaultRegistry.Detect(tt.path, []byte(tt.content))
+		name := ""
+		if lang != nil {
+			name = lang.Name
+		}
+		if name != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.expected, name)
+		}
+	}
+}
+
+func TestSetLanguages(t *testing.T) {
+	p := New(nil, nil, nil)
+	p.SetLanguages([]Language{
+		{Name: "Jsonnet", Extensions: []string{".jsonnet"}, SingleLine: []string{"//", "#"}, Strings: []string{`"`}},
+		// Replaces the built-in YAML definition
+		{Name: "yaml", Extensions: []string{".yml"}, SingleLine: []string{";"}},
+	})
+
+	got := p.parseContent("lib.jsonnet", "lib.jsonnet", []byte("local x = \"# TODO: no\";\n# TODO: yes\n"))
+	if len(got) != 1 || got[0].Content != "yes" {
+		t.Errorf("expected one TODO from custom language, got %+v", got)
+	}
+
+	got = p.parseContent("a.yml", "a.yml", []byte("; FIXME: custom comment\n"))
+	if len(got) != 1 || got[0].Type != "FIXME" {
+		t.Errorf("expected override to use ; comments, got %+v", got)
+	}
+	if p.languages.ByExtension(".yaml") != nil {
+		t.Errorf("expected overridden YAML to drop .yaml")
+	}
+}
+
+func TestCustomMarkers(t *testing.T) {
+	src := "// SECURITY: validate input\n// optimize: cache this\n// TODO: not configured\n// TODOS are plural\n"
+
+	p := New(nil, nil, []string{"SECURITY", "OPTIMIZE"})
+	got := p.parseContent("main.go", "main.go", []byte(src))
+	if len(got) != 2 || got[0].Type != "SECURITY" || got[1].Type != "OPTIMIZE" {
+		t.Fatalf("expected SECURITY and OPTIMIZE, got %+v", got)
+	}
+
+	p.SetIgnoreCase(false)
+	got = p.parseContent("main.go", "main.go", []byte(src))
+	if len(got) != 1 || got[0].Type != "SECURITY" {
+		t.Fatalf("expected only SECURITY when case-sensitive, got %+v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commentLine is one line of a comment with its delimiters removed
+type commentLine struct {
+	line int    // 0-based line index
+	col  int    // 0-based byte offset of text within the line
+	text string // comment text on this line
+}
+
+// comment is a block comment or a run of line comments on consecutive lines
+type comment struct {
+	token string // opening delimiter
+	block bool
+	own   bool // no code precedes the comment on its first line
+	lines []commentLine
+}
+
+// decorationPattern matches the leading markers of a continuation line,
+// such as the asterisks of a Javadoc block or repeated line delimiters
+var decorationPattern = regexp.MustCompile(`^\s*(?://+|#+|\*+|--+)?\s*`)
+
+// tokenize splits source lines into comments using the language's comment
+// and string literal syntax. Delimiters inside string literals are ignored,
+// block comments may span lines or share a line with code, and languages
+// with nested block comments track their depth.
+func tokenize(lines []string, lang *Language) []comment {
+	var comments []comment
+
+	var (
+		current  *comment // open block comment
+		endToken string
+		startTok string
+		depth    int
+		quote    string // open string literal
+	)
+
+	for n, line := range lines {
+		codeSeen := false
+		seg := 0
+		i := 0
+
+		for i < len(line) {
+			if current != nil {
+				end := strings.Index(line[i:], endToken)
+				if lang.Nested {
+					if start := strings.Index(line[i:], startTok); start >= 0 && (end < 0 || start < end) {
+						depth++
+						i += start + len(startTok)
+						continue
+					}
+				}
+				if end < 0 {
+					break
+				}
+				i += end + len(endToken)
+				if depth--; depth > 0 {
+					continue
+				}
+				current.lines = append(current.lines, commentLine{line: n, col: seg, text: line[seg : i-len(endToken)]})
+				comments = append(comments, *current)
+				current = nil
+				codeSeen = true
+				continue
+			}
+
+			if quote != "" {
+				i = skipString(line, i, quote, !isRaw(lang, quote))
+				if i < 0 {
+					// Only multi-line literals stay open past the end of the line
+					if !spansLines(quote) {
+						quote = ""
+					}
+					i = len(line)
+					break
+				}
+				quote = ""
+				continue
+			}
+
+			if c := line[i]; c == ' ' || c == '\t' || c == '\r' {
+				i++
+				continue
+			}
+
+			if start, end, ok := blockStart(lang, line[i:]); ok {
+				current = &comment{token: start, block: true, own: !codeSeen}
+				startTok, endToken, depth = start, end, 1
+				i += len(start)
+				seg = i
+				continue
+			}
+
+			if tok, ok := lineStart(lang, line[i:]); ok {
+				comments = appendLineComment(comments, comment{
+					token: tok,
+					own:   !codeSeen,
+					lines: []commentLine{{line: n, col: i + len(tok), text: line[i+len(tok):]}},
+				})
+				i = len(line)
+				break
+			}
+
+			if q, ok := stringStart(lang, line[i:]); ok {
+				quote = q
+				i += len(q)
+				codeSeen = true
+				continue
+			}
+
+			codeSeen = true
+			i++
+		}
+
+		if current != nil {
+			current.lines = append(current.lines, commentLine{line: n, col: seg, text: line[seg:]})
+			seg = 0
+		}
+	}
+
+	// An unterminated block comment runs to the end of the file
+	if current != nil {
+		comments = append(comments, *current)
+	}
+
+	return comments
+}
+
+// appendLineComment adds a line comment, merging it into the previous one
+// when both stand on their own consecutive lines with the same delimiter,
+// so that a description wrapped over several lines reads as one comment
+func appendLineComment(comments []comment, c comment) []comment {
+	if n := len(comments); n > 0 && c.own && comments[n-1].own {
+		prev := &comments[n-1]
+		last := prev.lines[len(prev.lines)-1]
+		if !prev.block && prev.token == c.token && last.line == c.lines[0].line-1 {
+			prev.lines = append(prev.lines, c.lines...)
+			return comments
+		}
+	}
+	return append(comments, c)
+}
+
+// hashComments treats every line starting with # as a comment; it is used
+// for files in languages the parser does not know
+func hashComments(lines []string) []comment {
+	var comments []comment
+	for n, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		col := len(line) - len(trimmed) + 1
+		comments = appendLineComment(comments, comment{
+			token: "#",
+			own:   true,
+			lines: []commentLine{{line: n, col: col, text: line[col:]}},
+		})
+	}
+	return comments
+}
+
+// blockStart reports whether s begins with one of the language's block
+// comment openers, preferring the longest
+func blockStart(lang *Language, s string) (string, string, bool) {
+	var start, end string
+	for i := 0; i+1 < len(lang.MultiLine); i += 2 {
+		if strings.HasPrefix(s, lang.MultiLine[i]) && len(lang.MultiLine[i]) > len(start) {
+			start, end = lang.MultiLine[i], lang.MultiLine[i+1]
+		}
+	}
+	return start, end, start != ""
+}
+
+// lineStart reports whether s begins with a line comment delimiter
+func lineStart(lang *Language, s string) (string, bool) {
+	for _, tok := range lang.SingleLine {
+		if tok != "" && strings.HasPrefix(s, tok) {
+			return tok, true
+		}
+	}
+	return "", false
+}
+
+// stringStart reports whether s begins with a string literal delimiter,
+// preferring the longest
+func stringStart(lang *Language, s string) (string, bool) {
+	var quote string
+	for _, q := range lang.Strings {
+		if strings.HasPrefix(s, q) && len(q) > len(quote) {
+			quote = q
+		}
+	}
+	return quote, quote != ""
+}
+
+// skipString returns the offset just past the closing quote, or -1 if the
+// literal does not end on this line. With escapes, backslash escapes the
+// next character.
+func skipString(line string, i int, quote string, escapes bool) int {
+	for i < len(line) {
+		if escapes && line[i] == '\\' {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(line[i:], quote) {
+			return i + len(quote)
+		}
+		i++
+	}
+	return -1
+}
+
+// isRaw reports whether a literal opened by quote has no escapes
+func isRaw(lang *Language, quote string) bool {
+	for _, q := range lang.RawStrings {
+		if q == quote {
+			return true
+		}
+	}
+	return false
+}
+
+// spansLines reports whether a string literal may continue past a newline
+func spansLines(quote string) bool {
+	return quote == "`" || len(quote) == 3
+}
@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+)
+
+// Rule IDs reported with each violation
+const (
+	RuleAssignee = "require-assignee"
+	RuleIssue    = "require-issue"
+	RulePriority = "max-priority"
+	RuleAge      = "max-age"
+	RuleIncrease = "no-increase"
+	RuleOverdue  = "no-overdue"
+	RuleStale    = "no-stale"
+)
+
+// descriptions explain each rule in reports
+var descriptions = map[string]string{
+	RuleAssignee: "New TODOs with these markers must name an assignee",
+	RuleIssue:    "New TODOs with these markers must reference an issue",
+	RulePriority: "The number of open TODOs per priority is capped",
+	RuleAge:      "TODOs with these markers may only stay open for a limited time",
+	RuleIncrease: "The number of TODOs must not grow relative to the base",
+	RuleOverdue:  "Open TODOs must not be past their due date",
+	RuleStale:    "Open TODOs must not go without updates for too long",
+}
+
+// Describe returns a one-line description of a rule
+func Describe(rule string) string {
+	return descriptions[rule]
+}
+
+// Rules configures which checks run. Marker lists may contain "*" to
+// match every marker; marker and priority keys are matched ignoring case.
+type Rules struct {
+	RequireAssignee []string
+	RequireIssue    []string
+	MaxPriority     map[string]int
+	MaxAgeDays      map[string]int
+	NoIncrease      bool
+	NoOverdue       bool
+	NoStale         bool
+}
+
+// Enabled returns the IDs of the rules that are switched on, in report order
+func (r Rules) Enabled() []string {
+	var rules []string
+	if len(r.RequireAssignee) > 0 {
+		rules = append(rules, RuleAssignee)
+	}
+	if len(r.RequireIssue) > 0 {
+		rules = append(rules, RuleIssue)
+	}
+	if len(r.MaxPriority) > 0 {
+		rules = append(rules, RulePriority)
+	}
+	if len(r.MaxAgeDays) > 0 {
+		rules = append(rules, RuleAge)
+	}
+	if r.NoIncrease {
+		rules = append(rules, RuleIncrease)
+	}
+	if r.NoOverdue {
+		rules = append(rules, RuleOverdue)
+	}
+	if r.NoStale {
+		rules = append(rules, RuleStale)
+	}
+	return rules
+}
+
+// Input is what the rules are checked against
+type Input struct {
+	// TODOs are all TODOs currently in the code
+	TODOs []parser.ParsedTODO
+	// New are the TODOs added or changed relative to the base; they are
+	// checked by the per-TODO rules. Without a base, pass TODOs.
+	New []parser.ParsedTODO
+	// Delta is the change in the TODO count relative to the base; it is
+	// only checked when HasBase is set
+	Delta   int
+	HasBase bool
+	// Tracked are the open TODOs in the database, nil if there is none.
+	// Priorities are counted from them when available, since they may have
+	// been edited since the comment was written.
+	Tracked []database.TODO
+	// Overdue and Stale are the open TODOs the database reports as such
+	Overdue []database.TODO
+	Stale   []database.TODO
+	Now     time.Time
+}
+
+// Violation is a broken rule. Violations that are not about a single TODO
+// have no file.
+type Violation struct {
+	Rule     string
+	Message  string
+	FilePath string
+	Line     int
+	Column   int
+}
+
+// Check runs the enabled rules and returns the violations, grouped by rule
+func Check(r Rules, in Input) []Violation {
+	var violations []Violation
+	add := func(rule, msg, path string, line, column int) {
+		violations = append(violations, Violation{Rule: rule, Message: msg, FilePath: path, Line: line, Column: column})
+	}
+
+	for _, t := range in.New {
+		if t.Assignee == "" && matchMarker(r.RequireAssignee, t.Type) {
+			add(RuleAssignee, fmt.Sprintf("%s has no assignee: %s", t.Type, t.Content), t.FilePath, t.LineNumber, t.Column)
+		}
+	}
+	for _, t := range in.New {
+		if len(t.IssueKeys) == 0 && matchMarker(r.RequireIssue, t.Type) {
+			add(RuleIssue, fmt.Sprintf("%s has no issue reference: %s", t.Type, t.Content), t.FilePath, t.LineNumber, t.Column)
+		}
+	}
+
+	if len(r.MaxPriority) > 0 {
+		counts := make(map[string]int)
+		if in.Tracked != nil {
+			for _, t := range in.Tracked {
+				counts[strings.ToUpper(t.Priority)]++
+			}
+		} else {
+			for _, t := range in.TODOs {
+				counts[strings.ToUpper(t.Priority)]++
+			}
+		}
+		for _, priority := range sortedKeys(r.MaxPriority) {
+			max := r.MaxPriority[priority]
+			p := strings.ToUpper(priority)
+			if counts[p] > max {
+				add(RulePriority, fmt.Sprintf("%d open %s TODOs, at most %d allowed", counts[p], p, max), "", 0, 0)
+			}
+		}
+	}
+
+	if len(r.MaxAgeDays) > 0 {
+		for _, t := range in.Tracked {
+			days, ok := lookup(r.MaxAgeDays, t.Type)
+			if !ok {
+				continue
+			}
+			age := int(in.Now.Sub(t.CreatedAt).Hours() / 24)
+			if age > days {
+				add(RuleAge, fmt.Sprintf("%s has been open for %d days, at most %d allowed: %s", t.Type, age, days, t.Content), t.FilePath, t.LineNumber, t.Column)
+			}
+		}
+	}
+
+	if r.NoIncrease && in.HasBase && in.Delta > 0 {
+		add(RuleIncrease, fmt.Sprintf("TODO count increased by %d", in.Delta), "", 0, 0)
+	}
+
+	if r.NoOverdue {
+		for _, t := range in.Overdue {
+			add(RuleOverdue, fmt.Sprintf("%s was due %s: %s", t.Type, t.DueDate.Format("2006-01-02"), t.Content), t.FilePath, t.LineNumber, t.Column)
+		}
+	}
+	if r.NoStale {
+		for _, t := range in.Stale {
+			add(RuleStale, fmt.Sprintf("%s not updated since %s: %s", t.Type, t.UpdatedAt.Format("2006-01-02"), t.Content), t.FilePath, t.LineNumber, t.Column)
+		}
+	}
+
+	return violations
+}
+
+// matchMarker reports whether marker is in list, which may contain "*"
+func matchMarker(list []string, marker string) bool {
+	for _, m := range list {
+		if m == "*" || strings.EqualFold(m, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds a marker's limit, ignoring case
+func lookup(limits map[string]int, marker string) (int, bool) {
+	for key, v := range limits {
+		if strings.EqualFold(key, marker) {
+			return v, true
+		}
+	}
+	if v, ok := limits["*"]; ok {
+		return v, true
+	}
+	return 0, false
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rulesOf(violations []Violation) []string {
+	var rules []string
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestCheckNewTODOs(t *testing.T) {
+	todos := []parser.ParsedTODO{
+		{FilePath: "a.go", LineNumber: 1, Type: "FIXME", Content: "leak", IssueKeys: []string{"#1"}},
+		{FilePath: "a.go", LineNumber: 2, Type: "FIXME", Content: "race", Assignee: "alice"},
+		{FilePath: "a.go", LineNumber: 3, Type: "NOTE", Content: "context"},
+	}
+	rules := Rules{RequireAssignee: []string{"fixme"}, RequireIssue: []string{"FIXME"}}
+
+	violations := Check(rules, Input{TODOs: todos, New: todos})
+	assert.Equal(t, []string{RuleAssignee, RuleIssue}, rulesOf(violations))
+	assert.Equal(t, 1, violations[0].Line)
+	assert.Equal(t, 2, violations[1].Line)
+
+	// Only new TODOs are held to the rules
+	violations = Check(rules, Input{TODOs: todos, New: todos[2:]})
+	assert.Empty(t, violations)
+
+	// "*" applies to every marker
+	violations = Check(Rules{RequireIssue: []string{"*"}}, Input{TODOs: todos, New: todos})
+	assert.Len(t, violations, 2)
+}
+
+func TestCheckCounts(t *testing.T) {
+	now := time.Now()
+	todos := []parser.ParsedTODO{{Priority: "P0"}, {Priority: "P1"}}
+	tracked := []database.TODO{
+		{Type: "HACK", Priority: "P0", CreatedAt: now.AddDate(0, 0, -100), FilePath: "a.go", LineNumber: 4},
+		{Type: "HACK", Priority: "P0", CreatedAt: now.AddDate(0, 0, -10)},
+		{Type: "TODO", Priority: "P2", CreatedAt: now.AddDate(0, 0, -365)},
+	}
+	rules := Rules{
+		MaxPriority: map[string]int{"p0": 1},
+		MaxAgeDays:  map[string]int{"hack": 90},
+		NoIncrease:  true,
+	}
+
+	// Priorities come from the database when there is one
+	violations := Check(rules, Input{TODOs: todos, Tracked: tracked, Delta: 1, HasBase: true, Now: now})
+	assert.Equal(t, []string{RulePriority, RuleAge, RuleIncrease}, rulesOf(violations))
+	assert.Equal(t, "2 open P0 TODOs, at most 1 allowed", violations[0].Message)
+	assert.Equal(t, "a.go", violations[1].FilePath)
+
+	violations = Check(rules, Input{TODOs: todos, Delta: 1, Now: now})
+	assert.Empty(t, violations)
+}
+
+func TestReports(t *testing.T) {
+	rules := []string{RuleAssignee, RuleIncrease}
+	violations := []Violation{
+		{Rule: RuleAssignee, Message: "FIXME has no assignee: leak", FilePath: "src/a.go", Line: 3, Column: 4},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSARIF(&buf, rules, violations, "1.0.0"))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, 2)
+	require.Len(t, log.Runs[0].Results, 1)
+	loc := log.Runs[0].Results[0].Locations[0].PhysicalLocation
+	assert.Equal(t, "src/a.go", loc.ArtifactLocation.URI)
+	assert.Equal(t, 3, loc.Region.StartLine)
+
+	buf.Reset()
+	require.NoError(t, WriteJUnit(&buf, rules, violations))
+	out := buf.String()
+	assert.Contains(t, out, `<testsuites name="todo check" tests="2" failures="1">`)
+	assert.Contains(t, out, `<testcase name="no-increase" classname="todo.policy"></testcase>`)
+	assert.Equal(t, 1, strings.Count(out, "<failure"))
+}
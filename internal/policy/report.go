@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/duncan-2126/ProjectManagement/internal/sarif"
+)
+
+// toolName identifies this tool in CI reports
+const toolName = "todo check"
+
+// WriteSARIF writes violations as a SARIF 2.1.0 log. rules lists the rules
+// that ran; file paths must be relative to the source root.
+func WriteSARIF(w io.Writer, rules []string, violations []Violation, version string) error {
+	driver := sarif.Driver{
+		Name:           toolName,
+		Version:        version,
+		InformationURI: "https://github.com/duncan-2126/ProjectManagement",
+	}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarif.Rule{
+			ID:                   rule,
+			ShortDescription:     &sarif.Message{Text: Describe(rule)},
+			DefaultConfiguration: &sarif.Configuration{Level: "error"},
+		})
+	}
+
+	var results []sarif.Result
+	for _, v := range violations {
+		r := sarif.Result{
+			RuleID:  v.Rule,
+			Level:   "error",
+			Message: sarif.Message{Text: v.Message},
+		}
+		if v.FilePath != "" {
+			r.Locations = []sarif.Location{sarif.FileLocation(v.FilePath, v.Line, v.Column)}
+		}
+		results = append(results, r)
+	}
+
+	return sarif.NewLog(driver, results).Write(w)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes one test case per rule that ran, failing those with
+// violations, in the JUnit XML format understood by most CI systems
+func WriteJUnit(w io.Writer, rules []string, violations []Violation) error {
+	byRule := make(map[string][]Violation)
+	for _, v := range violations {
+		byRule[v.Rule] = append(byRule[v.Rule], v)
+	}
+
+	suite := junitSuite{Name: toolName}
+	for _, rule := range rules {
+		c := junitCase{Name: rule, ClassName: "todo.policy"}
+		if vs := byRule[rule]; len(vs) > 0 {
+			var text strings.Builder
+			for _, v := range vs {
+				text.WriteString(v.String())
+				text.WriteString("\n")
+			}
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d violation(s): %s", len(vs), Describe(rule)),
+				Type:    rule,
+				Text:    text.String(),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+	}
+
+	doc := junitSuites{Name: toolName, Tests: suite.Tests, Failures: suite.Failures, Suites: []junitSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// String formats a violation as file:line: message
+func (v Violation) String() string {
+	if v.FilePath == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", v.FilePath, v.Line, v.Message)
+}
@@ -0,0 +1,162 @@
+// Package projects keeps the registry of projects known to this user, so
+// the server and CLI can work with several projects at once
+package projects
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/config"
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+)
+
+// FileName is the registry file inside the user config directory
+const FileName = "projects.json"
+
+// validName keeps project names usable in URLs; "*" in particular is
+// reserved for queries across all projects
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ErrNotFound is returned for projects that are not registered
+var ErrNotFound = errors.New("project not registered")
+
+// Project is a registered project
+type Project struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Registry is the list of registered projects stored in a file
+type Registry struct {
+	path     string
+	Projects []Project `json:"projects"`
+}
+
+// DefaultPath returns where the registry of the current user is kept
+func DefaultPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the registry at path; a missing file is an empty registry
+func Load(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project registry: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse project registry %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// LoadDefault reads the registry of the current user
+func LoadDefault() (*Registry, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// Save writes the registry back to its file
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode project registry: %w", err)
+	}
+	// Write then rename so a crash never leaves a truncated registry
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write project registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to write project registry: %w", err)
+	}
+	return nil
+}
+
+// Add registers the project at path under name
+func (r *Registry) Add(name, path string) (*Project, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid project name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	for _, p := range r.Projects {
+		if p.Name == name {
+			return nil, fmt.Errorf("a project named %s is already registered", name)
+		}
+		if p.Path == abs {
+			return nil, fmt.Errorf("%s is already registered as %s", abs, p.Name)
+		}
+	}
+
+	r.Projects = append(r.Projects, Project{Name: name, Path: abs, AddedAt: time.Now()})
+	sort.Slice(r.Projects, func(i, j int) bool { return r.Projects[i].Name < r.Projects[j].Name })
+	return r.Get(name)
+}
+
+// Remove unregisters a project; its database is left alone
+func (r *Registry) Remove(name string) error {
+	for i, p := range r.Projects {
+		if p.Name == name {
+			r.Projects = append(r.Projects[:i], r.Projects[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Get returns the project registered under name
+func (r *Registry) Get(name string) (*Project, error) {
+	for i := range r.Projects {
+		if r.Projects[i].Name == name {
+			return &r.Projects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Find returns the project registered under a name or at a path
+func (r *Registry) Find(nameOrPath string) (*Project, error) {
+	if p, err := r.Get(nameOrPath); err == nil {
+		return p, nil
+	}
+	if abs, err := filepath.Abs(nameOrPath); err == nil {
+		for i := range r.Projects {
+			if r.Projects[i].Path == abs {
+				return &r.Projects[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, nameOrPath)
+}
+
+// Open opens the database of a project. Unlike database.New it refuses to
+// create one where none exists, such as for a project that was moved.
+func (p *Project) Open() (*database.DB, error) {
+	if _, err := os.Stat(filepath.Join(p.Path, ".todo", "todos.db")); err != nil {
+		return nil, fmt.Errorf("project %s has no database at %s: %w", p.Name, p.Path, err)
+	}
+	return database.New(p.Path)
+}
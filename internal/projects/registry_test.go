@@ -0,0 +1,78 @@
+package projects
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config", FileName)
+
+	registry, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, registry.Projects)
+
+	_, err = registry.Add("web", "/src/web")
+	require.NoError(t, err)
+	_, err = registry.Add("api", "/src/api")
+	require.NoError(t, err)
+
+	_, err = registry.Add("api", "/src/other")
+	assert.ErrorContains(t, err, "already registered")
+	_, err = registry.Add("other", "/src/api")
+	assert.ErrorContains(t, err, "already registered as api")
+	for _, name := range []string{"*", "", "a/b", ".hidden"} {
+		_, err = registry.Add(name, "/src/"+name+"x")
+		assert.ErrorContains(t, err, "invalid project name", name)
+	}
+	require.NoError(t, registry.Save())
+
+	registry, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, registry.Projects, 2)
+	assert.Equal(t, "api", registry.Projects[0].Name)
+	assert.Equal(t, "web", registry.Projects[1].Name)
+
+	p, err := registry.Find("/src/web")
+	require.NoError(t, err)
+	assert.Equal(t, "web", p.Name)
+	p, err = registry.Find("api")
+	require.NoError(t, err)
+	assert.Equal(t, "/src/api", p.Path)
+
+	require.NoError(t, registry.Remove("api"))
+	assert.True(t, errors.Is(registry.Remove("api"), ErrNotFound))
+	_, err = registry.Find("api")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestLoadInvalidRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "failed to parse project registry")
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	p := Project{Name: "app", Path: dir}
+
+	_, err := p.Open()
+	assert.ErrorContains(t, err, "has no database")
+	_, err = os.Stat(filepath.Join(dir, ".todo"))
+	assert.True(t, os.IsNotExist(err), "Open must not create a database")
+
+	_, err = database.New(dir)
+	require.NoError(t, err)
+	db, err := p.Open()
+	require.NoError(t, err)
+	assert.NotNil(t, db)
+}
@@ -0,0 +1,236 @@
+package reconcile
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultThreshold is the minimum content similarity for a fuzzy match
+const DefaultThreshold = 0.6
+
+// Item is a TODO reduced to the fields used for matching
+type Item struct {
+	FilePath    string
+	LineNumber  int
+	Type        string
+	Content     string
+	ContextHash string
+}
+
+// Match pairs an old item with a new one by index
+type Match struct {
+	Old int
+	New int
+}
+
+// Result holds the outcome of a reconciliation
+type Result struct {
+	Matches []Match
+	Added   []int // indices into the new items without a match
+	Missing []int // indices into the old items without a match
+}
+
+// Options controls reconciliation
+type Options struct {
+	// Renames maps old file paths to their new location
+	Renames map[string]string
+	// Threshold is the minimum similarity for fuzzy content matches
+	Threshold float64
+}
+
+// candidate is a possible pairing with a score used for greedy assignment
+type candidate struct {
+	old, new int
+	score    float64
+	distance int
+}
+
+// Reconcile matches previously known TODOs to freshly parsed ones.
+//
+// Matching runs in passes of decreasing confidence so that strong matches
+// claim their partners before weaker heuristics are tried:
+//  1. same file, type and content (the TODO moved within its file)
+//  2. same file, type and surrounding context (the TODO text was edited)
+//  3. same file and type with similar content
+//  4. same type, content and context in another file (the file was moved)
+func Reconcile(old, new []Item, opts Options) Result {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+
+	oldPaths := make([]string, len(old))
+	for i, o := range old {
+		oldPaths[i] = resolvePath(o.FilePath, opts.Renames)
+	}
+
+	usedOld := make([]bool, len(old))
+	usedNew := make([]bool, len(new))
+	var matches []Match
+
+	assign := func(cands []candidate) {
+		sort.SliceStable(cands, func(i, j int) bool {
+			if cands[i].score != cands[j].score {
+				return cands[i].score > cands[j].score
+			}
+			return cands[i].distance < cands[j].distance
+		})
+		for _, c := range cands {
+			if usedOld[c.old] || usedNew[c.new] {
+				continue
+			}
+			usedOld[c.old] = true
+			usedNew[c.new] = true
+			matches = append(matches, Match{Old: c.old, New: c.new})
+		}
+	}
+
+	// Index new items by file for the same-file passes
+	byFile := make(map[string][]int)
+	for j, n := range new {
+		byFile[n.FilePath] = append(byFile[n.FilePath], j)
+	}
+
+	sameFile := func(score func(o, n Item) (float64, bool)) {
+		var cands []candidate
+		for i, o := range old {
+			if usedOld[i] {
+				continue
+			}
+			for _, j := range byFile[oldPaths[i]] {
+				if usedNew[j] || !strings.EqualFold(o.Type, new[j].Type) {
+					continue
+				}
+				if s, ok := score(o, new[j]); ok {
+					cands = append(cands, candidate{old: i, new: j, score: s, distance: abs(o.LineNumber - new[j].LineNumber)})
+				}
+			}
+		}
+		assign(cands)
+	}
+
+	// Pass 1: identical content, prefer identical context
+	sameFile(func(o, n Item) (float64, bool) {
+		if normalize(o.Content) != normalize(n.Content) {
+			return 0, false
+		}
+		if o.ContextHash != "" && o.ContextHash == n.ContextHash {
+			return 2, true
+		}
+		return 1, true
+	})
+
+	// Pass 2: identical context, edited content
+	sameFile(func(o, n Item) (float64, bool) {
+		if o.ContextHash == "" || o.ContextHash != n.ContextHash {
+			return 0, false
+		}
+		return Similarity(o.Content, n.Content), true
+	})
+
+	// Pass 3: similar content
+	sameFile(func(o, n Item) (float64, bool) {
+		s := Similarity(o.Content, n.Content)
+		return s, s >= opts.Threshold
+	})
+
+	// Pass 4: moved to another file
+	var cands []candidate
+	for i, o := range old {
+		if usedOld[i] {
+			continue
+		}
+		for j, n := range new {
+			if usedNew[j] || !strings.EqualFold(o.Type, n.Type) || normalize(o.Content) != normalize(n.Content) {
+				continue
+			}
+			if o.ContextHash != "" && o.ContextHash == n.ContextHash {
+				cands = append(cands, candidate{old: i, new: j, score: 1})
+			}
+		}
+	}
+	assign(cands)
+
+	result := Result{Matches: matches}
+	sort.Slice(result.Matches, func(i, j int) bool { return result.Matches[i].New < result.Matches[j].New })
+	for j := range new {
+		if !usedNew[j] {
+			result.Added = append(result.Added, j)
+		}
+	}
+	for i := range old {
+		if !usedOld[i] {
+			result.Missing = append(result.Missing, i)
+		}
+	}
+	return result
+}
+
+// resolvePath follows a chain of renames to the latest known path
+func resolvePath(path string, renames map[string]string) string {
+	seen := make(map[string]bool)
+	for {
+		next, ok := renames[path]
+		if !ok || seen[next] {
+			return path
+		}
+		seen[path] = true
+		path = next
+	}
+}
+
+// normalize collapses whitespace and case so cosmetic edits still match
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Similarity returns a score between 0 and 1 based on the edit distance
+// between the normalized forms of a and b
+func Similarity(a, b string) float64 {
+	ra := []rune(normalize(a))
+	rb := []rune(normalize(b))
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
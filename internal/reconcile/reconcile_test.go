@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"testing"
+)
+
+func TestReconcileLineShift(t *testing.T) {
+	old := []Item{
+		{FilePath: "a.go", LineNumber: 10, Type: "TODO", Content: "handle errors", ContextHash: "c1"},
+		{FilePath: "a.go", LineNumber: 20, Type: "FIXME", Content: "race condition", ContextHash: "c2"},
+	}
+	new := []Item{
+		{FilePath: "a.go", LineNumber: 12, Type: "TODO", Content: "handle errors", ContextHash: "c1"},
+		{FilePath: "a.go", LineNumber: 22, Type: "FIXME", Content: "race condition", ContextHash: "c2"},
+	}
+
+	result := Reconcile(old, new, Options{})
+	if len(result.Matches) != 2 || len(result.Added) != 0 || len(result.Missing) != 0 {
+		t.Fatalf("expected 2 matches, got %+v", result)
+	}
+	for _, m := range result.Matches {
+		if m.Old != m.New {
+			t.Errorf("expected old %d to match new %d", m.Old, m.New)
+		}
+	}
+}
+
+func TestReconcileEditedContent(t *testing.T) {
+	old := []Item{{FilePath: "a.go", LineNumber: 5, Type: "TODO", Content: "validate user input", ContextHash: "ctx"}}
+	new := []Item{{FilePath: "a.go", LineNumber: 5, Type: "TODO", Content: "validate all user input", ContextHash: "other"}}
+
+	result := Reconcile(old, new, Options{})
+	if len(result.Matches) != 1 {
+		t.Fatalf("expected fuzzy match, got %+v", result)
+	}
+}
+
+func TestReconcileDuplicatesPreferNearest(t *testing.T) {
+	old := []Item{
+		{FilePath: "a.go", LineNumber: 10, Type: "TODO", Content: "implement"},
+		{FilePath: "a.go", LineNumber: 50, Type: "TODO", Content: "implement"},
+	}
+	new := []Item{
+		{FilePath: "a.go", LineNumber: 52, Type: "TODO", Content: "implement"},
+		{FilePath: "a.go", LineNumber: 11, Type: "TODO", Content: "implement"},
+	}
+
+	result := Reconcile(old, new, Options{})
+	if len(result.Matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", result)
+	}
+	for _, m := range result.Matches {
+		if (m.Old == 0 && m.New != 1) || (m.Old == 1 && m.New != 0) {
+			t.Errorf("unexpected pairing %+v", m)
+		}
+	}
+}
+
+func TestReconcileRename(t *testing.T) {
+	old := []Item{{FilePath: "old.go", LineNumber: 3, Type: "TODO", Content: "split this file"}}
+	new := []Item{{FilePath: "new.go", LineNumber: 3, Type: "TODO", Content: "split this file"}}
+
+	result := Reconcile(old, new, Options{})
+	if len(result.Matches) != 0 {
+		t.Fatalf("expected no match without rename or context, got %+v", result)
+	}
+
+	result = Reconcile(old, new, Options{Renames: map[string]string{"old.go": "new.go"}})
+	if len(result.Matches) != 1 {
+		t.Fatalf("expected match through rename, got %+v", result)
+	}
+}
+
+func TestReconcileAddedAndMissing(t *testing.T) {
+	old := []Item{{FilePath: "a.go", LineNumber: 1, Type: "TODO", Content: "remove me"}}
+	new := []Item{{FilePath: "a.go", LineNumber: 1, Type: "BUG", Content: "something else entirely"}}
+
+	result := Reconcile(old, new, Options{})
+	if len(result.Added) != 1 || len(result.Missing) != 1 || len(result.Matches) != 0 {
+		t.Fatalf("expected one added and one missing, got %+v", result)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := Similarity("Fix  the Bug", "fix the bug"); s != 1 {
+		t.Errorf("expected normalized strings to be identical, got %f", s)
+	}
+	if s := Similarity("abc", "xyz"); s != 0 {
+		t.Errorf("expected 0 similarity, got %f", s)
+	}
+}
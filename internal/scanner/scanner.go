@@ -0,0 +1,425 @@
+package scanner
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/git"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	"github.com/duncan-2126/ProjectManagement/internal/reconcile"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxRenameCommits bounds how far back rename detection walks history
+const maxRenameCommits = 200
+
+// maxRemovalCommits bounds the search for the commit that removed a TODO
+const maxRemovalCommits = 100
+
+// Annotation precedence values for Options.Precedence
+const (
+	PrecedenceCode     = "code"
+	PrecedenceDatabase = "database"
+)
+
+// Options controls how a scan is persisted
+type Options struct {
+	// Precedence decides whether inline annotations overwrite values
+	// edited in the database; empty means PrecedenceCode
+	Precedence string
+	// Markers holds defaults for new TODOs keyed by upper-case marker
+	Markers map[string]MarkerDefaults
+	// Blamer, if set, attributes new TODOs and TODOs without an author to
+	// the commit that last changed their line
+	Blamer *git.Blamer
+	// Scope limits the scan to these files or directories: only tracked
+	// TODOs inside them are matched or removed and git renames are not
+	// looked up, so parsed must hold every TODO found in them. Nil scans
+	// the whole root.
+	Scope []string
+	// Parser, if set, parses earlier versions of files to find the commits
+	// that removed TODOs
+	Parser *parser.Parser
+	// Failed lists files or directories that could not be read. The
+	// TODOs tracked in them are kept as they are rather than resolved.
+	Failed []string
+}
+
+// MarkerDefaults are applied to TODOs first found with a marker. An inline
+// priority annotation takes precedence over the marker's priority.
+type MarkerDefaults struct {
+	Priority string
+	Category string
+	Severity string
+}
+
+// Result summarises a scan that was persisted to the database
+type Result struct {
+	Found    int
+	New      []database.TODO
+	Existing int
+	Moved    []database.TODO
+	Removed  []database.TODO
+	// BlameErrors lists files whose authors could not be determined
+	BlameErrors []error
+}
+
+// Sync reconciles freshly parsed TODOs with the ones stored in the database.
+// Known TODOs are matched by content, surrounding context and git renames and
+// updated in place so their status, tags and history survive edits; TODOs
+// that cannot be matched are created. Tracked TODOs whose comment no longer
+// exists are resolved and stamped with the commit that removed them.
+// Inline annotations fill in assignee, priority, due date, tags and issue
+// keys according to opts.Precedence.
+func Sync(db *database.DB, root string, parsed []parser.ParsedTODO, opts Options) (*Result, error) {
+	all, err := db.GetTODOs(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODOs already found to be removed stay out of matching; if the comment
+	// comes back it is tracked as a new TODO
+	var existing []database.TODO
+	for _, t := range all {
+		if t.RemovedAt == nil && inScope(t.FilePath, opts.Scope) {
+			existing = append(existing, t)
+		}
+	}
+
+	var renames map[string]string
+	if opts.Scope == nil {
+		var since time.Time
+		if project, err := db.GetProjectByPath(root); err == nil && project.LastScanned != nil {
+			since = *project.LastScanned
+		}
+		renames, err = git.DetectRenames(root, since, maxRenameCommits)
+		if err != nil {
+			// Not a git repository or no commits yet; content matching still applies
+			renames = nil
+		}
+	}
+
+	match := reconcile.Reconcile(itemsFromTODOs(existing), itemsFromParsed(parsed), reconcile.Options{Renames: renames})
+
+	result := &Result{Found: len(parsed)}
+	now := time.Now()
+	codeWins := opts.Precedence != PrecedenceDatabase
+
+	if opts.Blamer != nil {
+		need := append([]int(nil), match.Added...)
+		for _, m := range match.Matches {
+			if existing[m.Old].Author == "" {
+				need = append(need, m.New)
+			}
+		}
+		parsed = append([]parser.ParsedTODO(nil), parsed...)
+		result.BlameErrors = attachAuthors(opts.Blamer, parsed, need)
+	}
+
+	var removed []database.TODO
+	for _, i := range match.Missing {
+		if len(opts.Failed) == 0 || !inScope(existing[i].FilePath, opts.Failed) {
+			removed = append(removed, existing[i])
+		}
+	}
+	// Looked up before the transaction, as walking history is slow
+	removedCommits := removingCommits(opts.Parser, removed)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txdb := &database.DB{DB: tx}
+
+		for _, m := range match.Matches {
+			old := existing[m.Old]
+			p := parsed[m.New]
+			result.Existing++
+
+			fields := make(map[string]interface{})
+			if old.FilePath != p.FilePath || old.LineNumber != p.LineNumber ||
+				old.Column != p.Column || old.Content != p.Content || old.ContextHash != p.ContextHash {
+				fields["file_path"] = p.FilePath
+				fields["line_number"] = p.LineNumber
+				fields["column"] = p.Column
+				fields["content"] = p.Content
+				fields["hash"] = p.Hash
+				fields["context_hash"] = p.ContextHash
+				// Moving a comment is not an update to the TODO itself
+				if old.Content != p.Content {
+					fields["updated_at"] = now
+				}
+			}
+			if old.Author == "" && p.Author != "" {
+				fields["author"] = p.Author
+				fields["email"] = p.Email
+				fields["created_at"] = p.CreatedAt
+			}
+			if codeWins {
+				if annotationFields(old, p, fields) {
+					fields["updated_at"] = now
+				}
+				if err := addTags(txdb, old.ID, p.Tags); err != nil {
+					return err
+				}
+			}
+
+			if len(fields) > 0 {
+				if err := tx.Model(&database.TODO{}).Where("id = ?", old.ID).UpdateColumns(fields).Error; err != nil {
+					return err
+				}
+			}
+
+			if old.FilePath != p.FilePath || old.LineNumber != p.LineNumber {
+				old.FilePath = p.FilePath
+				old.LineNumber = p.LineNumber
+				old.Column = p.Column
+				old.Content = p.Content
+				result.Moved = append(result.Moved, old)
+			}
+		}
+
+		for _, i := range match.Added {
+			t := todoFromParsed(parsed[i], opts.Markers[parsed[i].Type])
+			if err := tx.Create(&t).Error; err != nil {
+				return err
+			}
+			if err := addTags(txdb, t.ID, parsed[i].Tags); err != nil {
+				return err
+			}
+			result.New = append(result.New, t)
+		}
+
+		for _, t := range removed {
+			t.RemovedAt = &now
+			t.RemovedCommit = removedCommits[t.ID]
+			fields := map[string]interface{}{
+				"removed_at":     t.RemovedAt,
+				"removed_commit": t.RemovedCommit,
+			}
+			// Keep statuses that already say the work is finished
+			if !database.IsDone(t.Status) {
+				t.Status = "resolved"
+				fields["status"] = t.Status
+				fields["updated_at"] = now
+			}
+			if err := tx.Model(&database.TODO{}).Where("id = ?", t.ID).UpdateColumns(fields).Error; err != nil {
+				return err
+			}
+			result.Removed = append(result.Removed, t)
+		}
+
+		// Only a full scan brings the whole project up to date
+		if opts.Scope != nil {
+			return nil
+		}
+		return tx.Model(&database.Project{}).Where("path = ?", root).Update("last_scanned", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.RecordChange(database.ChangeScanCompleted, "", result.Summary()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Summary is the scan.completed change recorded after a scan
+type Summary struct {
+	Found   int `json:"found"`
+	New     int `json:"new"`
+	Moved   int `json:"moved"`
+	Removed int `json:"removed"`
+}
+
+// Summary counts the TODOs a scan found, created, moved and removed
+func (r *Result) Summary() Summary {
+	return Summary{Found: r.Found, New: len(r.New), Moved: len(r.Moved), Removed: len(r.Removed)}
+}
+
+// inScope reports whether path is one of scope or inside one of its
+// directories; a nil scope contains everything
+func inScope(path string, scope []string) bool {
+	if scope == nil {
+		return true
+	}
+	for _, s := range scope {
+		if path == s || strings.HasPrefix(path, s+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// removingCommits returns the commit that removed each TODO, by ID. Each
+// file's history is walked once, and its earlier versions are parsed with
+// p so a TODO is matched by its marker and content as a scan would.
+func removingCommits(p *parser.Parser, removed []database.TODO) map[string]string {
+	commits := make(map[string]string)
+	if p == nil {
+		return commits
+	}
+	byFile := make(map[string][]database.TODO)
+	var files []string
+	for _, t := range removed {
+		if _, ok := byFile[t.FilePath]; !ok {
+			files = append(files, t.FilePath)
+		}
+		byFile[t.FilePath] = append(byFile[t.FilePath], t)
+	}
+
+	key := func(todoType, content string) string { return todoType + ":" + content }
+	for _, path := range files {
+		var keys []string
+		for _, t := range byFile[path] {
+			keys = append(keys, key(t.Type, t.Content))
+		}
+		found, err := git.FindRemovingCommits(path, keys, maxRemovalCommits, func(content []byte) map[string]bool {
+			held := make(map[string]bool)
+			for _, t := range p.ParseContent(path, content) {
+				held[key(t.Type, t.Content)] = true
+			}
+			return held
+		})
+		if err != nil {
+			// Not a git repository; the removals are recorded without a commit
+			continue
+		}
+		for _, t := range byFile[path] {
+			commits[t.ID] = found[key(t.Type, t.Content)]
+		}
+	}
+	return commits
+}
+
+// attachAuthors fills in the author of the TODOs at the given indices from
+// git blame, blaming each file once
+func attachAuthors(b *git.Blamer, parsed []parser.ParsedTODO, indices []int) []error {
+	byFile := make(map[string][]int)
+	var files []string
+	for _, i := range indices {
+		path := parsed[i].FilePath
+		if _, ok := byFile[path]; !ok {
+			files = append(files, path)
+		}
+		byFile[path] = append(byFile[path], i)
+	}
+
+	var errs []error
+	for _, path := range files {
+		authors, err := b.Blame(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, i := range byFile[path] {
+			if a, ok := authors[parsed[i].LineNumber]; ok {
+				parsed[i].Author = a.Name
+				parsed[i].Email = a.Email
+				parsed[i].CreatedAt = a.Date
+			}
+		}
+	}
+	return errs
+}
+
+// annotationFields adds the columns whose annotated value in code differs
+// from the stored one and reports whether there were any
+func annotationFields(old database.TODO, p parser.ParsedTODO, fields map[string]interface{}) bool {
+	changed := false
+	if p.Assignee != "" && p.Assignee != old.Assignee {
+		fields["assignee"] = p.Assignee
+		changed = true
+	}
+	if p.Priority != "" && p.Priority != old.Priority {
+		fields["priority"] = p.Priority
+		changed = true
+	}
+	if p.DueDate != nil && (old.DueDate == nil || !p.DueDate.Equal(*old.DueDate)) {
+		fields["due_date"] = p.DueDate
+		changed = true
+	}
+	if len(p.IssueKeys) > 0 && strings.Join(p.IssueKeys, ",") != strings.Join(old.IssueKeys, ",") {
+		// Column maps bypass the serializer, so encode the list here
+		keys, _ := json.Marshal(p.IssueKeys)
+		fields["issue_keys"] = string(keys)
+		changed = true
+	}
+	return changed
+}
+
+// addTags attaches the tags named in a comment, creating them as needed.
+// Tags are only ever added, so tags attached by hand are kept.
+func addTags(db *database.DB, todoID string, names []string) error {
+	for _, name := range names {
+		tag, err := db.GetOrCreateTag(name)
+		if err != nil {
+			return err
+		}
+		if err := db.AddTagToTODO(todoID, tag.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// todoFromParsed builds a new database record for a parsed TODO
+func todoFromParsed(p parser.ParsedTODO, defaults MarkerDefaults) database.TODO {
+	t := database.TODO{
+		FilePath:    p.FilePath,
+		LineNumber:  p.LineNumber,
+		Column:      p.Column,
+		Type:        p.Type,
+		Content:     p.Content,
+		Author:      p.Author,
+		Email:       p.Email,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.CreatedAt,
+		Status:      "open",
+		Priority:    "P3",
+		Category:    defaults.Category,
+		Severity:    defaults.Severity,
+		Hash:        p.Hash,
+		ContextHash: p.ContextHash,
+		Assignee:    p.Assignee,
+		DueDate:     p.DueDate,
+		IssueKeys:   p.IssueKeys,
+	}
+	if p.Priority != "" {
+		t.Priority = p.Priority
+	} else if defaults.Priority != "" {
+		t.Priority = defaults.Priority
+	}
+	t.ID = uuid.New().String()
+	return t
+}
+
+func itemsFromTODOs(todos []database.TODO) []reconcile.Item {
+	items := make([]reconcile.Item, len(todos))
+	for i, t := range todos {
+		items[i] = reconcile.Item{
+			FilePath:    t.FilePath,
+			LineNumber:  t.LineNumber,
+			Type:        t.Type,
+			Content:     t.Content,
+			ContextHash: t.ContextHash,
+		}
+	}
+	return items
+}
+
+func itemsFromParsed(parsed []parser.ParsedTODO) []reconcile.Item {
+	items := make([]reconcile.Item, len(parsed))
+	for i, p := range parsed {
+		items[i] = reconcile.Item{
+			FilePath:    p.FilePath,
+			LineNumber:  p.LineNumber,
+			Type:        p.Type,
+			Content:     p.Content,
+			ContextHash: p.ContextHash,
+		}
+	}
+	return items
+}
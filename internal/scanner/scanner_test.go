@@ -0,0 +1,348 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duncan-2126/ProjectManagement/internal/database"
+	"github.com/duncan-2126/ProjectManagement/internal/git"
+	"github.com/duncan-2126/ProjectManagement/internal/parser"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scan(t *testing.T, db *database.DB, dir string) *Result {
+	t.Helper()
+	return scanWith(t, db, dir, Options{})
+}
+
+func scanWith(t *testing.T, db *database.DB, dir string, opts Options) *Result {
+	t.Helper()
+	todos, err := parser.New(nil, nil, nil).ParseDir(dir)
+	require.NoError(t, err)
+	result, err := Sync(db, dir, todos, opts)
+	require.NoError(t, err)
+	return result
+}
+
+func TestSyncKeepsIdentityAcrossLineMoves(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\n// TODO: handle errors\nfunc main() {}\n"), 0644))
+
+	result := scan(t, db, dir)
+	require.Len(t, result.New, 1)
+	id := result.New[0].ID
+
+	// Mark the TODO as in progress so we can check the status survives
+	todo, err := db.GetTODOByID(id)
+	require.NoError(t, err)
+	todo.Status = "in_progress"
+	require.NoError(t, db.UpdateTODO(todo))
+
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nimport \"fmt\"\n\n// TODO: handle errors\nfunc main() {}\n"), 0644))
+
+	result = scan(t, db, dir)
+	assert.Len(t, result.New, 0)
+	assert.Equal(t, 1, result.Existing)
+	assert.Len(t, result.Moved, 1)
+
+	todos, err := db.GetTODOs(nil)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, id, todos[0].ID)
+	assert.Equal(t, 5, todos[0].LineNumber)
+	assert.Equal(t, "in_progress", todos[0].Status)
+}
+
+func TestSyncFollowsMovedFile(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	content := []byte("package util\n\n// FIXME: leaks file handles\nfunc Open() {}\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.go"), content, 0644))
+	result := scan(t, db, dir)
+	require.Len(t, result.New, 1)
+
+	require.NoError(t, os.Rename(filepath.Join(dir, "old.go"), filepath.Join(dir, "new.go")))
+	result = scan(t, db, dir)
+	assert.Len(t, result.New, 0)
+	require.Len(t, result.Moved, 1)
+	assert.Equal(t, filepath.Join(dir, "new.go"), result.Moved[0].FilePath)
+}
+
+func TestSyncResolvesRemovedTODOs(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\n// TODO: first\n// HACK: second\n"), 0644))
+	result := scan(t, db, dir)
+	require.Len(t, result.New, 2)
+
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\n// TODO: first\n"), 0644))
+	result = scan(t, db, dir)
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, "HACK", result.Removed[0].Type)
+
+	removed, err := db.GetTODOByID(result.Removed[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", removed.Status)
+	assert.NotNil(t, removed.RemovedAt)
+
+	// A later scan does not report it again
+	result = scan(t, db, dir)
+	assert.Len(t, result.Removed, 0)
+	assert.Len(t, result.New, 0)
+}
+
+func TestSyncFindsRemovingCommit(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := gogit.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	commit := func(content string) string {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0644))
+		_, err := wt.Add("main.go")
+		require.NoError(t, err)
+		hash, err := wt.Commit("edit", &gogit.CommitOptions{
+			Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		return hash.String()
+	}
+
+	// A comment wrapped over two lines, whose text is left in a log message
+	commit("package main\n\n// TODO: retry failed\n// requests\nfunc main() {}\n")
+	db, err := database.New(dir)
+	require.NoError(t, err)
+	p := parser.New(nil, nil, nil)
+	require.Len(t, scanWith(t, db, dir, Options{Parser: p}).New, 1)
+
+	removal := commit("package main\n\nfunc main() { log(\"retry failed requests\") }\n")
+	commit("package main\n\nfunc main() { log(\"retry failed requests\") }\n\nfunc x() {}\n")
+	result := scanWith(t, db, dir, Options{Parser: p})
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, removal, result.Removed[0].RemovedCommit)
+}
+
+func TestSyncKeepsTODOsOfUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\n// TODO: first\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("// TODO: second\n"), 0644))
+	require.Len(t, scan(t, db, dir).New, 2)
+
+	// main.go cannot be read this time, so none of its TODOs are parsed
+	require.NoError(t, os.Chmod(file, 0))
+	todos, err := parser.New(nil, nil, nil).ParseDir(dir)
+	if os.Geteuid() == 0 {
+		// Root reads it all the same, so drop main.go, which sorts first
+		todos, err = todos[1:], parser.FileErrors{file: os.ErrPermission}
+	}
+	var failed parser.FileErrors
+	require.ErrorAs(t, err, &failed)
+	assert.Equal(t, []string{file}, failed.Paths())
+	require.Len(t, todos, 1, "other files are still parsed")
+
+	result, err := Sync(db, dir, todos, Options{Failed: failed.Paths()})
+	require.NoError(t, err)
+	assert.Empty(t, result.Removed)
+	all, err := db.GetTODOs(nil)
+	require.NoError(t, err)
+	for _, todo := range all {
+		assert.Equal(t, "open", todo.Status)
+		assert.Nil(t, todo.RemovedAt)
+	}
+}
+
+func TestSyncAppliesAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	src := "package main\n\n// TODO(alice)[P1][due:2026-12-01] #auth #42: check tokens\nfunc main() {}\n"
+	require.NoError(t, os.WriteFile(file, []byte(src), 0644))
+
+	result := scan(t, db, dir)
+	require.Len(t, result.New, 1)
+
+	todo, err := db.GetTODOByID(result.New[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "check tokens", todo.Content)
+	assert.Equal(t, "alice", todo.Assignee)
+	assert.Equal(t, "P1", todo.Priority)
+	require.NotNil(t, todo.DueDate)
+	assert.Equal(t, "2026-12-01", todo.DueDate.Format("2006-01-02"))
+	assert.Equal(t, []string{"#42"}, todo.IssueKeys)
+
+	tags, err := db.GetTagsForTODO(todo.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "auth", tags[0].Name)
+
+	// Rescanning does not duplicate tags
+	scan(t, db, dir)
+	tags, err = db.GetTagsForTODO(todo.ID)
+	require.NoError(t, err)
+	assert.Len(t, tags, 1)
+}
+
+func TestSyncAnnotationPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\n// FIXME(bob)[P2]: race\nfunc main() {}\n"), 0644))
+
+	id := scan(t, db, dir).New[0].ID
+
+	// Reassign in the database
+	todo, err := db.GetTODOByID(id)
+	require.NoError(t, err)
+	todo.Assignee = "carol"
+	todo.Priority = "P0"
+	require.NoError(t, db.UpdateTODO(todo))
+
+	scanWith(t, db, dir, Options{Precedence: PrecedenceDatabase})
+	todo, err = db.GetTODOByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "carol", todo.Assignee)
+	assert.Equal(t, "P0", todo.Priority)
+
+	scanWith(t, db, dir, Options{Precedence: PrecedenceCode})
+	todo, err = db.GetTODOByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", todo.Assignee)
+	assert.Equal(t, "P2", todo.Priority)
+
+	// Issue keys added later in code are picked up
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\n// FIXME(bob, JIRA-7)[P2]: race\nfunc main() {}\n"), 0644))
+	scan(t, db, dir)
+	todo, err = db.GetTODOByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"JIRA-7"}, todo.IssueKeys)
+}
+
+func TestSyncAppliesMarkerDefaults(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	src := "package main\n\n// SECURITY: escape output\n// SECURITY[P2]: rate limit\nfunc main() {}\n"
+	require.NoError(t, os.WriteFile(file, []byte(src), 0644))
+
+	todos, err := parser.New(nil, nil, []string{"SECURITY"}).ParseDir(dir)
+	require.NoError(t, err)
+	result, err := Sync(db, dir, todos, Options{Markers: map[string]MarkerDefaults{
+		"SECURITY": {Priority: "P0", Category: "security", Severity: "critical"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, result.New, 2)
+
+	first, err := db.GetTODOByID(result.New[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "P0", first.Priority)
+	assert.Equal(t, "security", first.Category)
+	assert.Equal(t, "critical", first.Severity)
+
+	// An inline priority beats the marker default
+	second, err := db.GetTODOByID(result.New[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "P2", second.Priority)
+}
+
+func TestSyncAttributesAuthorsWithBlamer(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := gogit.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// TODO: handle errors\n"), 0644))
+	_, err = wt.Add("main.go")
+	require.NoError(t, err)
+	_, err = wt.Commit("add main", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	// Not committed yet, so it has no author
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n\n// TODO: write tests\n"), 0644))
+
+	db, err := database.New(dir)
+	require.NoError(t, err)
+	b, err := git.NewBlamer(dir, "")
+	require.NoError(t, err)
+
+	result := scanWith(t, db, dir, Options{Blamer: b})
+	assert.Empty(t, result.BlameErrors)
+	require.Len(t, result.New, 2)
+
+	todos, err := db.GetTODOs(nil)
+	require.NoError(t, err)
+	authors := make(map[string]string)
+	for _, todo := range todos {
+		authors[filepath.Base(todo.FilePath)] = todo.Author
+	}
+	assert.Equal(t, "Alice", authors["main.go"])
+	assert.Equal(t, "", authors["new.go"])
+}
+
+func TestSyncScopeOnlyTouchesScopedFiles(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.New(dir)
+	require.NoError(t, err)
+	require.NoError(t, db.InitProject("p", dir))
+
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("package main\n\n// TODO: in a\n"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("package main\n\n// TODO: in b\n"), 0644))
+	result := scan(t, db, dir)
+	require.Len(t, result.New, 2)
+	project, err := db.GetProjectByPath(dir)
+	require.NoError(t, err)
+	require.NotNil(t, project.LastScanned)
+	scanned := *project.LastScanned
+
+	// Rename a.go to c.go; b.go is outside the scope and must survive even
+	// though its TODOs are not passed in
+	c := filepath.Join(dir, "c.go")
+	require.NoError(t, os.Rename(a, c))
+	parsed, err := parser.New(nil, nil, nil).ParseFile(c)
+	require.NoError(t, err)
+	result, err = Sync(db, dir, parsed, Options{Scope: []string{a, c}})
+	require.NoError(t, err)
+	assert.Empty(t, result.New)
+	assert.Empty(t, result.Removed)
+	require.Len(t, result.Moved, 1)
+	assert.Equal(t, c, result.Moved[0].FilePath)
+
+	// Removing a directory's worth of files resolves the TODOs inside it
+	result, err = Sync(db, dir, nil, Options{Scope: []string{dir}})
+	require.NoError(t, err)
+	assert.Len(t, result.Removed, 2)
+
+	// Scoped scans leave the time of the last full scan alone
+	project, err = db.GetProjectByPath(dir)
+	require.NoError(t, err)
+	assert.True(t, project.LastScanned.Equal(scanned))
+}
@@ -0,0 +1,226 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the watcher waits for changes to settle
+const DefaultDebounce = 300 * time.Millisecond
+
+// Batch is the set of paths that changed while events kept arriving. Paths
+// are absolute. Removed holds files and directories that no longer exist,
+// including the old side of renames; the new side is in Changed.
+type Batch struct {
+	Changed []string
+	Removed []string
+}
+
+// Options configures a Watcher
+type Options struct {
+	// Debounce is the quiet period before a batch is delivered; zero uses
+	// DefaultDebounce
+	Debounce time.Duration
+	// SkipDir reports directories that are not watched
+	SkipDir func(path string) bool
+	// OnError receives errors that do not stop watching
+	OnError func(error)
+}
+
+// Watcher watches a directory tree recursively and delivers debounced
+// batches of changed files
+type Watcher struct {
+	fsw  *fsnotify.Watcher
+	root string
+	opts Options
+	dirs map[string]bool
+}
+
+// New starts watching root and every directory below it that is not skipped
+func New(root string, opts Options) (*Watcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	w := &Watcher{fsw: fsw, root: root, opts: opts, dirs: make(map[string]bool)}
+	if _, err := w.addTree(root); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Dirs returns the number of directories being watched
+func (w *Watcher) Dirs() int {
+	return len(w.dirs)
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
+
+// Run delivers batches to handle until ctx is cancelled or the watcher is
+// closed. handle runs on the calling goroutine; events arriving meanwhile
+// are collected into the next batch.
+func (w *Watcher) Run(ctx context.Context, handle func(Batch)) error {
+	pending := make(map[string]bool)
+	var timer *time.Timer
+	var fire <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.record(ev, pending)
+			if timer == nil {
+				timer = time.NewTimer(w.opts.Debounce)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(w.opts.Debounce)
+			}
+			fire = timer.C
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.error(err)
+
+		case <-fire:
+			fire = nil
+			if batch := w.batch(pending); len(batch.Changed) > 0 || len(batch.Removed) > 0 {
+				handle(batch)
+			}
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// record notes the paths touched by an event, watching new directories
+func (w *Watcher) record(ev fsnotify.Event, pending map[string]bool) {
+	path := ev.Name
+	switch {
+	case ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename):
+		pending[path] = true
+		if w.dirs[path] {
+			w.unwatch(path)
+		}
+	case ev.Has(fsnotify.Create):
+		info, err := os.Lstat(path)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			// Files created before the watch was added send no events
+			files, err := w.addTree(path)
+			if err != nil {
+				w.error(err)
+			}
+			for _, f := range files {
+				pending[f] = true
+			}
+			return
+		}
+		pending[path] = true
+	case ev.Has(fsnotify.Write):
+		pending[path] = true
+	}
+}
+
+// batch sorts pending paths by whether they still exist as files
+func (w *Watcher) batch(pending map[string]bool) Batch {
+	var b Batch
+	for path := range pending {
+		info, err := os.Lstat(path)
+		switch {
+		case err != nil:
+			b.Removed = append(b.Removed, path)
+		case info.Mode().IsRegular():
+			b.Changed = append(b.Changed, path)
+		}
+	}
+	sort.Strings(b.Changed)
+	sort.Strings(b.Removed)
+	return b
+}
+
+// addTree watches dir and the directories below it and returns the files
+// found in them
+func (w *Watcher) addTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories may vanish while being walked
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		}
+		if path != w.root && w.opts.SkipDir != nil && w.opts.SkipDir(path) {
+			return filepath.SkipDir
+		}
+		if w.dirs[path] {
+			return nil
+		}
+		if err := w.fsw.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		w.dirs[path] = true
+		return nil
+	})
+	return files, err
+}
+
+// unwatch forgets a removed or renamed directory and those below it
+func (w *Watcher) unwatch(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			// The watch is usually gone already
+			_ = w.fsw.Remove(path)
+			delete(w.dirs, path)
+		}
+	}
+}
+
+func (w *Watcher) error(err error) {
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+	}
+}
@@ -0,0 +1,73 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// next waits for the next batch delivered by w
+func next(t *testing.T, batches <-chan Batch) Batch {
+	t.Helper()
+	select {
+	case b := <-batches:
+		return b
+	case <-time.After(5 * time.Second):
+		t.Fatal("no batch delivered")
+		return Batch{}
+	}
+}
+
+func TestWatcherBatches(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "skipped"), 0755))
+
+	w, err := New(dir, Options{
+		Debounce: 50 * time.Millisecond,
+		SkipDir:  func(path string) bool { return filepath.Base(path) == "skipped" },
+	})
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, 1, w.Dirs())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan Batch, 10)
+	go w.Run(ctx, func(b Batch) { batches <- b })
+
+	// Several writes to one file arrive as one change
+	a := filepath.Join(dir, "a.go")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, os.WriteFile(a, []byte("package a\n"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "skipped", "x.go"), []byte("package x\n"), 0644))
+	b := next(t, batches)
+	assert.Equal(t, []string{a}, b.Changed)
+	assert.Empty(t, b.Removed)
+
+	// Files in new directories are picked up
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "b.go"), []byte("package b\n"), 0644))
+	b = next(t, batches)
+	assert.Equal(t, []string{filepath.Join(sub, "b.go")}, b.Changed)
+
+	// A rename reports both sides
+	c := filepath.Join(sub, "c.go")
+	require.NoError(t, os.Rename(a, c))
+	b = next(t, batches)
+	assert.Equal(t, []string{c}, b.Changed)
+	assert.Equal(t, []string{a}, b.Removed)
+
+	// Removing a directory reports the directory
+	require.NoError(t, os.RemoveAll(sub))
+	b = next(t, batches)
+	assert.Contains(t, b.Removed, sub)
+	assert.Empty(t, b.Changed)
+}
@@ -0,0 +1,271 @@
+// Package webui serves the web UI, either embedded in the binary or from a
+// directory during development
+package webui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// ErrNotBuilt is returned by Assets when the binary was built without the UI
+var ErrNotBuilt = errors.New("web UI not built into this binary")
+
+// minCompressSize is the smallest asset worth compressing
+const minCompressSize = 1024
+
+// Cache-Control values. Vite puts content-hashed files under assets/, so
+// they never change; index.html must be revalidated to pick up new builds.
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+	cacheDefault    = "public, max-age=3600"
+)
+
+// Assets returns the UI files in dir, or those embedded in the binary when
+// dir is empty
+func Assets(dir string) (fs.FS, error) {
+	var fsys fs.FS
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(dist, "dist/app")
+		if err != nil {
+			return nil, ErrNotBuilt
+		}
+		fsys = sub
+	}
+	if _, err := fs.Stat(fsys, "index.html"); err != nil {
+		if dir == "" {
+			return nil, ErrNotBuilt
+		}
+		return nil, fmt.Errorf("no index.html in %s", dir)
+	}
+	return fsys, nil
+}
+
+// NotBuilt answers every request with a page explaining how to build the UI
+var NotBuilt = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprint(w, `<!doctype html><title>TODO Tracker</title>
+<p>This binary was built without the web UI. Run <code>npm run build</code>
+in <code>web/</code> and rebuild <code>todo</code>, or point
+<code>todo serve --assets-dir</code> at a built UI. The REST API is
+available at <a href="/api/v1/openapi.json">/api/v1</a>.</p>
+`)
+})
+
+// Handler serves a single-page app from fsys. Paths without a file
+// extension that match no file get index.html so client-side routes work.
+// Responses carry cache headers and ETags and are compressed with brotli or
+// gzip when the client accepts it.
+func Handler(fsys fs.FS) http.Handler {
+	return &handler{fsys: fsys, cache: make(map[string]*asset)}
+}
+
+type handler struct {
+	fsys fs.FS
+
+	mu    sync.Mutex
+	cache map[string]*asset
+}
+
+// asset is a file read into memory with its compressed forms
+type asset struct {
+	modTime time.Time
+	size    int64
+	content []byte
+	etag    string
+
+	mu      sync.Mutex
+	encoded map[string][]byte
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := assetName(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	a, err := h.load(name)
+	if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+		name = "index.html"
+		a, err = h.load(name)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case name == "index.html":
+		w.Header().Set("Cache-Control", cacheRevalidate)
+	case strings.HasPrefix(name, "assets/"):
+		w.Header().Set("Cache-Control", cacheImmutable)
+	default:
+		w.Header().Set("Cache-Control", cacheDefault)
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(a.content)
+	}
+	w.Header().Set("Content-Type", contentType)
+
+	body, etag := a.content, a.etag
+	if compressible(contentType) && len(a.content) >= minCompressSize {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if encoding := negotiate(r.Header.Get("Accept-Encoding")); encoding != "" {
+			encoded, err := a.encode(encoding)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Encoding", encoding)
+			body = encoded
+			etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+		}
+	}
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, a.modTime, bytes.NewReader(body))
+}
+
+// assetName turns a URL path into a file name inside the assets. Paths are
+// cleaned so they cannot leave the assets, and hidden files are refused.
+func assetName(urlPath string) (string, bool) {
+	if strings.ContainsAny(urlPath, "\\\x00") {
+		return "", false
+	}
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return "index.html", true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+	return name, fs.ValidPath(name)
+}
+
+// load returns a regular file, reading it again when it changed on disk
+func (h *handler) load(name string) (*asset, error) {
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fs.ErrNotExist
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if a, ok := h.cache[name]; ok && a.modTime.Equal(info.ModTime()) && a.size == info.Size() {
+		return a, nil
+	}
+	content, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	a := &asset{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		content: content,
+		etag:    fmt.Sprintf(`"%x"`, sum[:12]),
+		encoded: make(map[string][]byte),
+	}
+	h.cache[name] = a
+	return a, nil
+}
+
+// encode returns the asset compressed with encoding, compressing it once
+func (a *asset) encode(encoding string) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if encoded, ok := a.encoded[encoding]; ok {
+		return encoded, nil
+	}
+
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch encoding {
+	case "br":
+		zw = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	case "gzip":
+		zw, _ = gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	if _, err := zw.Write(a.content); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	a.encoded[encoding] = buf.Bytes()
+	return a.encoded[encoding], nil
+}
+
+// negotiate picks brotli or gzip from an Accept-Encoding header, preferring
+// brotli, or returns "" for an uncompressed response
+func negotiate(accept string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(coding))] = true
+	}
+	for _, encoding := range []string{"br", "gzip"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressible reports content types that shrink when compressed
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/javascript", mediaType == "application/json",
+		mediaType == "application/manifest+json", mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
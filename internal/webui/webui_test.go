@@ -0,0 +1,125 @@
+package webui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var script = strings.Repeat("console.log('todo');\n", 100)
+
+func testAssets() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":           {Data: []byte("<!doctype html><div id=root></div>")},
+		"assets/index-abc.js":  {Data: []byte(script)},
+		"assets/logo-abc.png":  {Data: bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 400)},
+		"vite.svg":             {Data: []byte("<svg></svg>")},
+		".env":                 {Data: []byte("SECRET=1")},
+		"assets/.hidden/x.txt": {Data: []byte("hidden")},
+	}
+}
+
+func get(h http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.URL.Path = path
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(testAssets())
+
+	rec := get(h, "/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "id=root")
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	// Client-side routes get the app, missing files do not
+	rec = get(h, "/kanban")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "id=root")
+	assert.Equal(t, http.StatusNotFound, get(h, "/assets/missing.js").Code)
+
+	rec = get(h, "/assets/index-abc.js")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+	assert.Equal(t, script, rec.Body.String())
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rec = get(h, "/assets/index-abc.js", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = get(h, "/vite.svg")
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+
+	req := httptest.NewRequest("POST", "/", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestCompression(t *testing.T) {
+	h := Handler(testAssets())
+
+	rec := get(h, "/assets/index-abc.js", "Accept-Encoding", "gzip, deflate, br")
+	require.Equal(t, "br", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Less(t, rec.Body.Len(), len(script))
+	body, err := io.ReadAll(brotli.NewReader(rec.Body))
+	require.NoError(t, err)
+	assert.Equal(t, script, string(body))
+	brETag := rec.Header().Get("ETag")
+
+	rec = get(h, "/assets/index-abc.js", "Accept-Encoding", "gzip, br;q=0")
+	require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.NotEqual(t, brETag, rec.Header().Get("ETag"))
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, script, string(body))
+
+	// Images and small files are sent as they are
+	rec = get(h, "/assets/logo-abc.png", "Accept-Encoding", "br")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	rec = get(h, "/", "Accept-Encoding", "br")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	assets := filepath.Join(dir, "app")
+	require.NoError(t, os.Mkdir(assets, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(assets, "index.html"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644))
+
+	fsys, err := Assets(assets)
+	require.NoError(t, err)
+	for _, h := range []http.Handler{Handler(fsys), Handler(testAssets())} {
+		for _, path := range []string{"/../secret.txt", "/assets/../../secret.txt", "/..\\secret.txt", "/.env", "/assets/.hidden/x.txt"} {
+			rec := get(h, path)
+			assert.NotContains(t, rec.Body.String(), "secret", path)
+			assert.NotContains(t, rec.Body.String(), "hidden", path)
+		}
+	}
+
+	_, err = Assets(dir)
+	assert.Error(t, err)
+}
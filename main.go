@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/duncan-2126/ProjectManagement/cmd"
@@ -9,7 +10,11 @@ import (
 
 func main() {
 	// Initialize configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Execute root command
 	if err := cmd.Execute(cfg); err != nil {
@@ -1,6 +1,7 @@
 import { useState, useEffect } from 'react';
 import type { TODO, Stats, FilterOptions } from '../types';
 import { api } from '../services/api';
+import { subscribeToChanges } from '../services/events';
 import { Layout } from '../components/Layout';
 import { FilterBar } from '../components/FilterBar';
 import { TODOCard } from '../components/TODOCard';
@@ -15,6 +16,7 @@ export function Dashboard() {
 
   useEffect(() => {
     loadData();
+    return subscribeToChanges(loadData);
   }, [filters]);
 
   const loadData = async () => {
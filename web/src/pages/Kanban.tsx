@@ -1,6 +1,7 @@
 import { useState, useEffect } from 'react';
 import type { TODO, TODOStatus } from '../types';
 import { api } from '../services/api';
+import { subscribeToChanges } from '../services/events';
 import { Layout } from '../components/Layout';
 import { TODOCard } from '../components/TODOCard';
 import { Loader2 } from 'lucide-react';
@@ -107,11 +108,12 @@ export function Kanban() {
 
   useEffect(() => {
     loadData();
+    return subscribeToChanges(() => loadData(false));
   }, []);
 
-  const loadData = async () => {
+  const loadData = async (showSpinner = true) => {
     try {
-      setLoading(true);
+      if (showSpinner) setLoading(true);
       const data = await api.getTODOs();
       setTodos(data);
     } catch (err) {
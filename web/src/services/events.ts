@@ -0,0 +1,34 @@
+const EVENTS_URL = '/api/v1/events';
+
+// Event types sent by the server; reset means too many changes were missed
+// to replay and everything should be reloaded
+const EVENT_TYPES = [
+  'todo.created',
+  'todo.updated',
+  'todo.deleted',
+  'scan.completed',
+  'timer.started',
+  'timer.stopped',
+  'reset',
+];
+
+// subscribeToChanges calls onChange, at most once per delay, whenever the
+// project changes. It returns a function that closes the stream.
+export function subscribeToChanges(onChange: () => void, delay = 300): () => void {
+  const source = new EventSource(EVENTS_URL);
+  let timer: ReturnType<typeof setTimeout> | undefined;
+
+  const schedule = () => {
+    if (timer) return;
+    timer = setTimeout(() => {
+      timer = undefined;
+      onChange();
+    }, delay);
+  };
+  EVENT_TYPES.forEach(type => source.addEventListener(type, schedule));
+
+  return () => {
+    if (timer) clearTimeout(timer);
+    source.close();
+  };
+}
@@ -5,6 +5,11 @@ import tailwindcss from '@tailwindcss/vite'
 // https://vite.dev/config/
 export default defineConfig({
   plugins: [react(), tailwindcss()],
+  // Built into the Go tree so `todo` embeds the UI; see internal/webui
+  build: {
+    outDir: '../internal/webui/dist/app',
+    emptyOutDir: true,
+  },
   server: {
     proxy: {
       '/api': {